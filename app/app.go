@@ -0,0 +1,221 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Micah-Shallom/modules/config"
+	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/database"
+	"github.com/Micah-Shallom/modules/helpers"
+	"github.com/Micah-Shallom/modules/kds"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/notifications"
+	"github.com/Micah-Shallom/modules/payments"
+	"github.com/Micah-Shallom/modules/printing"
+	"github.com/Micah-Shallom/modules/receipts"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/Micah-Shallom/modules/routes"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type App struct {
+	config config.Config
+	client *mongo.Client
+	server *http.Server
+	// printer sends print jobs; fakePrinters stand in for the printers
+	// configured as fake
+	printer      *printing.Queue
+	fakePrinters []*printing.FakePrinter
+	// kitchen pushes ticket changes to the kitchen displays
+	kitchen *kds.Hub
+}
+
+// New connects the configured storage backend and wires every controller
+// into a ready-to-serve http.Server.
+func New(ctx context.Context, cfg config.Config) (*App, error) {
+	a := &App{config: cfg}
+
+	var repos *repository.Repositories
+	switch cfg.Database.Driver {
+	case "memory":
+		repos = repository.NewMemoryRepositories()
+	case "mongo":
+		client, err := database.Connect(ctx, cfg.Database.URL, cfg.Database.ConnectAttempts, cfg.Database.ConnectBackoff)
+		if err != nil {
+			return nil, err
+		}
+		a.client = client
+		repos = repository.NewMongoRepositories(database.OpenDatabase(client, cfg.Database.Name))
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Database.Driver)
+	}
+
+	keys, err := helpers.NewKeySet(cfg.Auth.SigningAlgorithm, cfg.Auth.SecretKey, cfg.Auth.SigningKeyFile, cfg.Auth.VerificationKeyFiles)
+	if err != nil {
+		a.close()
+		return nil, fmt.Errorf("loading signing keys: %w", err)
+	}
+	tokens := helpers.NewTokenMaker(keys, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
+
+	if err := a.startPrinters(); err != nil {
+		a.close()
+		return nil, fmt.Errorf("starting fake printers: %w", err)
+	}
+
+	a.kitchen = kds.NewHub()
+	a.server = &http.Server{
+		Addr:    ":" + cfg.Server.Port,
+		Handler: newRouter(cfg, repos, tokens, a.printer, a.kitchen),
+	}
+	// open display streams would otherwise hold up the shutdown
+	a.server.RegisterOnShutdown(a.kitchen.Close)
+	return a, nil
+}
+
+// startPrinters resolves the configured printer addresses, leaving out those
+// set to none and starting a fake printer for each one set to fake, and
+// starts the queue that feeds them.
+func (a *App) startPrinters() error {
+	printers := map[string]string{
+		printing.PrinterKitchen: a.config.Printing.KitchenPrinter,
+		printing.PrinterReceipt: a.config.Printing.ReceiptPrinter,
+	}
+	for name, addr := range printers {
+		if addr == "none" {
+			delete(printers, name)
+			continue
+		}
+		if addr != "fake" {
+			continue
+		}
+		name := name
+		fake, err := printing.NewFakePrinter(func(document []byte) {
+			log.Printf("fake %s printer printed:\n%s", name, printing.Plain(document))
+		})
+		if err != nil {
+			return err
+		}
+		a.fakePrinters = append(a.fakePrinters, fake)
+		printers[name] = fake.Addr()
+	}
+	a.printer = printing.NewQueue(printers)
+	return nil
+}
+
+func newRouter(cfg config.Config, repos *repository.Repositories, tokens *helpers.TokenMaker, printer *printing.Queue, kitchen *kds.Hub) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Logger())
+	router.Use(middleware.RequestTimeout(cfg.Server.RequestTimeout, routes.StationStreamPath))
+	// every route but sign-up, login and refresh requires a valid token and
+	// declares the roles allowed to call it
+	protected := router.Group("/", middleware.Authenticate(tokens, repos.Sessions))
+
+	var gateway payments.Gateway
+	if cfg.Payments.Gateway == "fake" {
+		gateway = payments.NewFakeGateway()
+	}
+	var notifier notifications.Notifier
+	if cfg.Notifications.Notifier == "log" {
+		notifier = notifications.NewLogNotifier()
+	}
+	// validated with the rest of the configuration
+	timeZone, _ := time.LoadLocation(cfg.Invoicing.TimeZone)
+	numbering := helpers.InvoiceNumbering{
+		Location:        cfg.Invoicing.Location,
+		Format:          cfg.Invoicing.NumberFormat,
+		FiscalYearStart: cfg.Invoicing.FiscalYearStart,
+		TimeZone:        timeZone,
+	}
+	header := receipts.Header{
+		Name:    cfg.Restaurant.Name,
+		Address: cfg.Restaurant.Address,
+		Phone:   cfg.Restaurant.Phone,
+		TaxID:   cfg.Restaurant.TaxID,
+	}
+	pricing := helpers.Pricing{
+		Currency:          cfg.Pricing.Currency,
+		TaxRate:           cfg.Pricing.TaxRate,
+		ServiceChargeRate: cfg.Pricing.ServiceChargeRate,
+	}
+	booking := helpers.Booking{
+		Opens:        cfg.Reservations.Opens,
+		Closes:       cfg.Reservations.Closes,
+		SlotInterval: cfg.Reservations.SlotInterval,
+		NoShowAfter:  cfg.Reservations.NoShowAfter,
+		TimeZone:     timeZone,
+	}
+	for _, turn := range cfg.Reservations.TurnTimes {
+		booking.TurnTimes = append(booking.TurnTimes, helpers.TurnTime{PartySize: turn.PartySize, Duration: turn.Duration})
+	}
+	schedule := helpers.MenuSchedule{TimeZone: timeZone}
+	routes.AuthRoutes(router, protected, controllers.NewAuthController(repos.Users, repos.Sessions, tokens))
+	routes.UserRoutes(protected, controllers.NewUserController(repos.Users))
+	routes.FoodRoutes(protected, controllers.NewFoodController(repos.Foods, repos.Menus, pricing, schedule))
+	routes.InvoiceRoutes(protected, controllers.NewInvoiceController(repos.Invoices, repos.Orders, repos.OrderItems, repos.Tables, repos.Payments, repos.CreditNotes, repos.Counters, repos.Transactions, gateway, pricing, numbering, header, printer))
+	routes.MenuRoutes(protected, controllers.NewMenuController(repos.Menus, schedule))
+	routes.OrderRoutes(protected, controllers.NewOrderController(repos.Orders, repos.OrderItems, repos.Foods, repos.Menus, repos.Tables, repos.Invoices, repos.TaxRules, repos.Stations, repos.Tickets, repos.Transactions, pricing, schedule, kitchen))
+	routes.OrderItemsRoutes(protected, controllers.NewOrderItemController(repos.OrderItems, repos.Orders, repos.Foods, repos.Menus, repos.Tables, repos.Invoices, repos.TaxRules, repos.Stations, repos.Tickets, repos.Transactions, pricing, schedule, kitchen))
+	routes.TableRoutes(protected, controllers.NewTableController(repos.Tables, repos.Orders, repos.Areas, repos.Sections, repos.Shifts, repos.Transactions))
+	routes.FloorRoutes(protected, controllers.NewFloorController(repos.Areas, repos.Sections, repos.Shifts, repos.Tables, repos.Users, repos.Counters, repos.Transactions, timeZone))
+	routes.ReservationRoutes(protected, controllers.NewReservationController(repos.Reservations, repos.Tables, repos.Counters, repos.Transactions, booking))
+	routes.WaitlistRoutes(protected, controllers.NewWaitlistController(repos.Waitlist, repos.Tables, repos.Reservations, repos.Transactions, booking, notifier, cfg.Restaurant.Name))
+	routes.TaxRuleRoutes(protected, controllers.NewTaxRuleController(repos.TaxRules))
+	routes.KitchenRoutes(protected, controllers.NewKitchenController(repos.Stations, repos.Tickets, repos.Transactions, kitchen))
+	routes.PrintRoutes(protected, controllers.NewPrintController(repos.Orders, repos.OrderItems, printer, timeZone))
+	return router
+}
+
+// Run serves HTTP until ctx is cancelled, then stops accepting connections,
+// waits for in-flight requests to finish and disconnects from MongoDB.
+func (a *App) Run(ctx context.Context) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s (%s profile)", a.server.Addr, a.config.Env)
+		if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	var runErr error
+	select {
+	case runErr = <-serveErr:
+	case <-ctx.Done():
+		log.Println("shutting down")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.config.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := a.server.Shutdown(shutdownCtx); err != nil {
+		runErr = errors.Join(runErr, fmt.Errorf("shutting down http server: %w", err))
+	}
+	if err := a.printer.Close(shutdownCtx); err != nil {
+		runErr = errors.Join(runErr, err)
+	}
+	for _, fake := range a.fakePrinters {
+		_ = fake.Close()
+	}
+	if a.client != nil {
+		if err := a.client.Disconnect(shutdownCtx); err != nil {
+			runErr = errors.Join(runErr, fmt.Errorf("disconnecting from MongoDB: %w", err))
+		}
+	}
+	return runErr
+}
+
+// close releases what New acquired when wiring fails halfway.
+func (a *App) close() {
+	for _, fake := range a.fakePrinters {
+		_ = fake.Close()
+	}
+	if a.client != nil {
+		_ = a.client.Disconnect(context.Background())
+	}
+}
@@ -0,0 +1,444 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EnvDev  = "dev"
+	EnvTest = "test"
+	EnvProd = "prod"
+)
+
+type Config struct {
+	Env           string
+	Server        ServerConfig
+	Database      DatabaseConfig
+	Auth          AuthConfig
+	Pricing       PricingConfig
+	Payments      PaymentsConfig
+	Invoicing     InvoicingConfig
+	Restaurant    RestaurantConfig
+	Printing      PrintingConfig
+	Reservations  ReservationsConfig
+	Notifications NotificationsConfig
+}
+
+type ServerConfig struct {
+	Port            string
+	RequestTimeout  time.Duration
+	ShutdownTimeout time.Duration
+}
+
+type DatabaseConfig struct {
+	Driver          string
+	URL             string
+	Name            string
+	ConnectAttempts int
+	ConnectBackoff  time.Duration
+}
+
+type AuthConfig struct {
+	SecretKey            string
+	SigningAlgorithm     string
+	SigningKeyFile       string
+	VerificationKeyFiles []string
+	AccessTokenTTL       time.Duration
+	RefreshTokenTTL      time.Duration
+}
+
+type PricingConfig struct {
+	Currency          string
+	TaxRate           float64
+	ServiceChargeRate float64
+}
+
+type PaymentsConfig struct {
+	// Gateway names the card payment provider; none accepts cash only.
+	Gateway string
+}
+
+type InvoicingConfig struct {
+	// Location is the code of the restaurant in invoice numbers, e.g. LAG.
+	Location string
+	// NumberFormat lays out invoice numbers from {location}, {year} and
+	// {seq}, optionally zero-padded as in {seq:6}.
+	NumberFormat    string
+	FiscalYearStart time.Month
+	TimeZone        string
+}
+
+// RestaurantConfig is the header printed on receipts.
+type RestaurantConfig struct {
+	Name    string
+	Address string
+	Phone   string
+	TaxID   string
+}
+
+// PrintingConfig holds the host:port of the raw TCP (ESC/POS) printers. none
+// disables a printer and fake starts a local fake printer that logs what it
+// receives.
+type PrintingConfig struct {
+	KitchenPrinter string
+	ReceiptPrinter string
+}
+
+// ReservationsConfig sets when tables can be booked and for how long.
+type ReservationsConfig struct {
+	// Opens and Closes bound the local times bookings may start at, as
+	// offsets from midnight, written 11:00-22:00.
+	Opens        time.Duration
+	Closes       time.Duration
+	SlotInterval time.Duration
+	// TurnTimes is how long a party keeps its table, by the largest party
+	// size each applies to, written 2:75m,4:90m,6:2h. Larger parties get
+	// the last one.
+	TurnTimes []TurnTime
+	// NoShowAfter is how late a party may be before it can be marked as a
+	// no-show.
+	NoShowAfter time.Duration
+}
+
+type TurnTime struct {
+	PartySize int
+	Duration  time.Duration
+}
+
+type NotificationsConfig struct {
+	// Notifier names the provider guests are texted through; log only
+	// writes the messages to the log and none sends nothing.
+	Notifier string
+}
+
+// setting maps a dotted key, as written in the YAML/TOML config file, to the
+// environment variable that overrides it.
+type setting struct {
+	key string
+	env string
+}
+
+var settings = []setting{
+	{"server.port", "PORT"},
+	{"server.request_timeout", "REQUEST_TIMEOUT"},
+	{"server.shutdown_timeout", "SHUTDOWN_TIMEOUT"},
+	{"database.driver", "DATABASE_DRIVER"},
+	{"database.url", "MONGO_URL"},
+	{"database.name", "DATABASE_NAME"},
+	{"database.connect_attempts", "DATABASE_CONNECT_ATTEMPTS"},
+	{"database.connect_backoff", "DATABASE_CONNECT_BACKOFF"},
+	{"auth.secret_key", "SECRET_KEY"},
+	{"auth.signing_algorithm", "JWT_SIGNING_ALGORITHM"},
+	{"auth.signing_key_file", "JWT_SIGNING_KEY_FILE"},
+	{"auth.verification_key_files", "JWT_VERIFICATION_KEY_FILES"},
+	{"auth.access_token_ttl", "ACCESS_TOKEN_TTL"},
+	{"auth.refresh_token_ttl", "REFRESH_TOKEN_TTL"},
+	{"pricing.currency", "CURRENCY"},
+	{"pricing.tax_rate", "TAX_RATE"},
+	{"pricing.service_charge_rate", "SERVICE_CHARGE_RATE"},
+	{"payments.gateway", "PAYMENT_GATEWAY"},
+	{"invoicing.location", "INVOICE_LOCATION"},
+	{"invoicing.number_format", "INVOICE_NUMBER_FORMAT"},
+	{"invoicing.fiscal_year_start", "FISCAL_YEAR_START_MONTH"},
+	{"invoicing.time_zone", "INVOICE_TIME_ZONE"},
+	{"restaurant.name", "RESTAURANT_NAME"},
+	{"restaurant.address", "RESTAURANT_ADDRESS"},
+	{"restaurant.phone", "RESTAURANT_PHONE"},
+	{"restaurant.tax_id", "RESTAURANT_TAX_ID"},
+	{"printing.kitchen_printer", "KITCHEN_PRINTER"},
+	{"printing.receipt_printer", "RECEIPT_PRINTER"},
+	{"reservations.hours", "RESERVATION_HOURS"},
+	{"reservations.slot_interval", "RESERVATION_SLOT_INTERVAL"},
+	{"reservations.turn_times", "RESERVATION_TURN_TIMES"},
+	{"reservations.no_show_after", "RESERVATION_NO_SHOW_AFTER"},
+	{"notifications.notifier", "NOTIFIER"},
+}
+
+// build converts the merged key/value settings into a typed Config and
+// validates it, reporting every problem at once.
+func build(env string, values map[string]string) (Config, error) {
+	p := parser{values: values}
+	cfg := Config{
+		Env: env,
+		Server: ServerConfig{
+			Port:            p.str("server.port"),
+			RequestTimeout:  p.duration("server.request_timeout"),
+			ShutdownTimeout: p.duration("server.shutdown_timeout"),
+		},
+		Database: DatabaseConfig{
+			Driver:          p.str("database.driver"),
+			URL:             p.str("database.url"),
+			Name:            p.str("database.name"),
+			ConnectAttempts: p.integer("database.connect_attempts"),
+			ConnectBackoff:  p.duration("database.connect_backoff"),
+		},
+		Auth: AuthConfig{
+			SecretKey:            p.str("auth.secret_key"),
+			SigningAlgorithm:     p.str("auth.signing_algorithm"),
+			SigningKeyFile:       p.str("auth.signing_key_file"),
+			VerificationKeyFiles: p.list("auth.verification_key_files"),
+			AccessTokenTTL:       p.duration("auth.access_token_ttl"),
+			RefreshTokenTTL:      p.duration("auth.refresh_token_ttl"),
+		},
+		Pricing: PricingConfig{
+			Currency:          strings.ToUpper(p.str("pricing.currency")),
+			TaxRate:           p.rate("pricing.tax_rate"),
+			ServiceChargeRate: p.rate("pricing.service_charge_rate"),
+		},
+		Payments: PaymentsConfig{
+			Gateway: p.str("payments.gateway"),
+		},
+		Invoicing: InvoicingConfig{
+			Location:        strings.ToUpper(p.str("invoicing.location")),
+			NumberFormat:    p.str("invoicing.number_format"),
+			FiscalYearStart: time.Month(p.integer("invoicing.fiscal_year_start")),
+			TimeZone:        p.str("invoicing.time_zone"),
+		},
+		Restaurant: RestaurantConfig{
+			Name:    p.str("restaurant.name"),
+			Address: p.str("restaurant.address"),
+			Phone:   p.str("restaurant.phone"),
+			TaxID:   p.str("restaurant.tax_id"),
+		},
+		Printing: PrintingConfig{
+			KitchenPrinter: p.str("printing.kitchen_printer"),
+			ReceiptPrinter: p.str("printing.receipt_printer"),
+		},
+		Reservations: ReservationsConfig{
+			SlotInterval: p.duration("reservations.slot_interval"),
+			TurnTimes:    p.turnTimes("reservations.turn_times"),
+			NoShowAfter:  p.duration("reservations.no_show_after"),
+		},
+		Notifications: NotificationsConfig{
+			Notifier: p.str("notifications.notifier"),
+		},
+	}
+	cfg.Reservations.Opens, cfg.Reservations.Closes = p.hours("reservations.hours")
+	return cfg, errors.Join(append(p.errs, cfg.validate()...)...)
+}
+
+func (cfg Config) validate() []error {
+	var errs []error
+	if port, err := strconv.Atoi(cfg.Server.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port (PORT) must be a valid TCP port, got %q", cfg.Server.Port))
+	}
+	if cfg.Server.RequestTimeout <= 0 {
+		errs = append(errs, errors.New("server.request_timeout (REQUEST_TIMEOUT) must be positive"))
+	}
+	if cfg.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server.shutdown_timeout (SHUTDOWN_TIMEOUT) must be positive"))
+	}
+
+	switch cfg.Database.Driver {
+	case "mongo":
+		if cfg.Database.URL == "" {
+			errs = append(errs, errors.New("database.url (MONGO_URL) is required when the mongo driver is used"))
+		}
+		if cfg.Database.Name == "" {
+			errs = append(errs, errors.New("database.name (DATABASE_NAME) is required when the mongo driver is used"))
+		}
+	case "memory":
+		if cfg.Env == EnvProd {
+			errs = append(errs, errors.New("database.driver (DATABASE_DRIVER) cannot be memory in prod"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.driver (DATABASE_DRIVER) must be mongo or memory, got %q", cfg.Database.Driver))
+	}
+	if cfg.Database.ConnectAttempts < 1 {
+		errs = append(errs, errors.New("database.connect_attempts (DATABASE_CONNECT_ATTEMPTS) must be at least 1"))
+	}
+
+	switch cfg.Auth.SigningAlgorithm {
+	case "HS256":
+		if strings.TrimSpace(cfg.Auth.SecretKey) == "" {
+			errs = append(errs, errors.New("auth.secret_key (SECRET_KEY) is required"))
+		}
+	case "RS256", "EdDSA":
+		if cfg.Auth.SigningKeyFile == "" {
+			errs = append(errs, fmt.Errorf("auth.signing_key_file (JWT_SIGNING_KEY_FILE) is required for %s", cfg.Auth.SigningAlgorithm))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("auth.signing_algorithm (JWT_SIGNING_ALGORITHM) must be HS256, RS256 or EdDSA, got %q", cfg.Auth.SigningAlgorithm))
+	}
+	if cfg.Env == EnvProd && cfg.Auth.SecretKey != "" && len(cfg.Auth.SecretKey) < 32 {
+		errs = append(errs, errors.New("auth.secret_key (SECRET_KEY) must be at least 32 characters in prod"))
+	}
+	if cfg.Auth.AccessTokenTTL <= 0 || cfg.Auth.RefreshTokenTTL <= 0 {
+		errs = append(errs, errors.New("auth token lifetimes (ACCESS_TOKEN_TTL, REFRESH_TOKEN_TTL) must be positive"))
+	}
+	if len(cfg.Pricing.Currency) != 3 || strings.Trim(cfg.Pricing.Currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		errs = append(errs, fmt.Errorf("pricing.currency (CURRENCY) must be an ISO 4217 code such as NGN, got %q", cfg.Pricing.Currency))
+	}
+	switch cfg.Payments.Gateway {
+	case "none":
+	case "fake":
+		if cfg.Env == EnvProd {
+			errs = append(errs, errors.New("payments.gateway (PAYMENT_GATEWAY) cannot be fake in prod"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("payments.gateway (PAYMENT_GATEWAY) must be fake or none, got %q", cfg.Payments.Gateway))
+	}
+	switch cfg.Notifications.Notifier {
+	case "none":
+	case "log":
+		if cfg.Env == EnvProd {
+			errs = append(errs, errors.New("notifications.notifier (NOTIFIER) cannot be log in prod"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("notifications.notifier (NOTIFIER) must be log or none, got %q", cfg.Notifications.Notifier))
+	}
+
+	if cfg.Invoicing.Location == "" || strings.Trim(cfg.Invoicing.Location, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789") != "" {
+		errs = append(errs, fmt.Errorf("invoicing.location (INVOICE_LOCATION) must be letters and digits, got %q", cfg.Invoicing.Location))
+	}
+	// the counter behind {seq} is kept per location and fiscal year, so
+	// numbers are only unique when they show both
+	for _, token := range []string{"{location}", "{year}", "{seq"} {
+		if !strings.Contains(cfg.Invoicing.NumberFormat, token) {
+			errs = append(errs, fmt.Errorf("invoicing.number_format (INVOICE_NUMBER_FORMAT) must contain %s}, got %q", strings.TrimSuffix(token, "}"), cfg.Invoicing.NumberFormat))
+		}
+	}
+	if cfg.Invoicing.FiscalYearStart < time.January || cfg.Invoicing.FiscalYearStart > time.December {
+		errs = append(errs, fmt.Errorf("invoicing.fiscal_year_start (FISCAL_YEAR_START_MONTH) must be a month from 1 to 12, got %d", cfg.Invoicing.FiscalYearStart))
+	}
+	if _, err := time.LoadLocation(cfg.Invoicing.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("invoicing.time_zone (INVOICE_TIME_ZONE): %w", err))
+	}
+	if cfg.Restaurant.Name == "" {
+		errs = append(errs, errors.New("restaurant.name (RESTAURANT_NAME) is required"))
+	}
+	for _, printer := range []struct{ key, env, addr string }{
+		{"printing.kitchen_printer", "KITCHEN_PRINTER", cfg.Printing.KitchenPrinter},
+		{"printing.receipt_printer", "RECEIPT_PRINTER", cfg.Printing.ReceiptPrinter},
+	} {
+		switch printer.addr {
+		case "none":
+		case "fake":
+			if cfg.Env == EnvProd {
+				errs = append(errs, fmt.Errorf("%s (%s) cannot be fake in prod", printer.key, printer.env))
+			}
+		default:
+			if _, port, err := net.SplitHostPort(printer.addr); err != nil || port == "" {
+				errs = append(errs, fmt.Errorf("%s (%s) must be none, fake or a host:port address such as 192.168.1.50:9100, got %q", printer.key, printer.env, printer.addr))
+			}
+		}
+	}
+	if cfg.Reservations.Opens >= cfg.Reservations.Closes {
+		errs = append(errs, errors.New("reservations.hours (RESERVATION_HOURS) must close after it opens"))
+	}
+	if cfg.Reservations.SlotInterval < time.Minute {
+		errs = append(errs, errors.New("reservations.slot_interval (RESERVATION_SLOT_INTERVAL) must be at least 1m"))
+	}
+	if len(cfg.Reservations.TurnTimes) == 0 {
+		errs = append(errs, errors.New("reservations.turn_times (RESERVATION_TURN_TIMES) needs at least one party size"))
+	}
+	for i, turn := range cfg.Reservations.TurnTimes {
+		if turn.PartySize < 1 || turn.Duration <= 0 || (i > 0 && turn.PartySize <= cfg.Reservations.TurnTimes[i-1].PartySize) {
+			errs = append(errs, errors.New("reservations.turn_times (RESERVATION_TURN_TIMES) must list positive durations by increasing party size, e.g. 2:75m,4:90m"))
+			break
+		}
+	}
+	if cfg.Reservations.NoShowAfter < 0 {
+		errs = append(errs, errors.New("reservations.no_show_after (RESERVATION_NO_SHOW_AFTER) cannot be negative"))
+	}
+	return errs
+}
+
+type parser struct {
+	values map[string]string
+	errs   []error
+}
+
+func (p *parser) str(key string) string {
+	return strings.TrimSpace(p.values[key])
+}
+
+// list splits a comma separated value, dropping empty entries.
+func (p *parser) list(key string) []string {
+	var items []string
+	for _, item := range strings.Split(p.str(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (p *parser) duration(key string) time.Duration {
+	d, err := time.ParseDuration(p.str(key))
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s: %w", key, err))
+	}
+	return d
+}
+
+func (p *parser) integer(key string) int {
+	n, err := strconv.Atoi(p.str(key))
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s: %w", key, err))
+	}
+	return n
+}
+
+// hours parses a window of local times such as 11:00-22:00 into offsets from
+// midnight.
+func (p *parser) hours(key string) (time.Duration, time.Duration) {
+	var opens, closes time.Duration
+	from, to, ok := strings.Cut(p.str(key), "-")
+	if ok {
+		opens, ok = clock(from)
+	}
+	if ok {
+		closes, ok = clock(to)
+	}
+	if !ok {
+		p.errs = append(p.errs, fmt.Errorf("%s must be a window such as 11:00-22:00, got %q", key, p.str(key)))
+	}
+	return opens, closes
+}
+
+func clock(s string) (time.Duration, bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, true
+}
+
+// turnTimes parses a list such as 2:75m,4:90m of party sizes and durations.
+func (p *parser) turnTimes(key string) []TurnTime {
+	var turns []TurnTime
+	for _, item := range p.list(key) {
+		size, duration, _ := strings.Cut(item, ":")
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil {
+			p.errs = append(p.errs, fmt.Errorf("%s: party size %q: %w", key, size, err))
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil {
+			p.errs = append(p.errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		turns = append(turns, TurnTime{PartySize: n, Duration: d})
+	}
+	return turns
+}
+
+// rate parses a fraction between 0 and 1, such as 0.075 for 7.5%.
+func (p *parser) rate(key string) float64 {
+	r, err := strconv.ParseFloat(p.str(key), 64)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s: %w", key, err))
+		return 0
+	}
+	if r < 0 || r > 1 {
+		p.errs = append(p.errs, fmt.Errorf("%s must be a fraction between 0 and 1, got %v", key, r))
+	}
+	return r
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+var defaultFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// Load builds the configuration of the running process. Values are merged
+// from lowest to highest precedence:
+//
+//  1. the defaults of the profile selected by APP_ENV (dev when unset)
+//  2. the config file named by CONFIG_FILE, or the first of config.yaml,
+//     config.yml and config.toml found in the working directory
+//  3. the profile overlay next to it, e.g. config.prod.yaml
+//  4. an optional .env file in the working directory
+//  5. the process environment
+func Load() (Config, error) {
+	dotenv, err := godotenv.Read(".env")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Config{}, fmt.Errorf("reading .env: %w", err)
+	}
+	lookup := func(name string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		return dotenv[name]
+	}
+
+	env := strings.ToLower(strings.TrimSpace(lookup("APP_ENV")))
+	if env == "" {
+		env = EnvDev
+	}
+	defaults, ok := profiles[env]
+	if !ok {
+		return Config{}, fmt.Errorf("unknown APP_ENV %q, expected one of dev, test, prod", env)
+	}
+
+	values := map[string]string{}
+	for key, value := range defaults {
+		values[key] = value
+	}
+
+	path, required := lookup("CONFIG_FILE"), true
+	if path == "" {
+		path, required = findDefaultFile(), false
+	}
+	if path != "" {
+		if err := mergeFile(values, path, required); err != nil {
+			return Config{}, err
+		}
+		if err := mergeFile(values, profileFile(path, env), false); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		if value := lookup(s.env); value != "" {
+			values[s.key] = value
+		}
+	}
+
+	return build(env, values)
+}
+
+func findDefaultFile() string {
+	for _, name := range defaultFiles {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// profileFile turns config.yaml into config.prod.yaml for the prod profile.
+func profileFile(path, env string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + env + ext
+}
+
+func mergeFile(values map[string]string, path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	raw := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	flat := map[string]string{}
+	flatten("", raw, flat)
+
+	known := map[string]bool{}
+	for _, s := range settings {
+		known[s.key] = true
+	}
+	var unknown []string
+	for key, value := range flat {
+		if !known[key] {
+			unknown = append(unknown, key)
+			continue
+		}
+		values[key] = value
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("config file %s: unknown settings %s", path, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func flatten(prefix string, raw map[string]interface{}, out map[string]string) {
+	for key, value := range raw {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(key, v, out)
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			out[key] = strings.Join(items, ",")
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+// profiles hold the defaults of each environment. They sit at the bottom of
+// the precedence chain and are overridden by config files, .env and the
+// process environment.
+var profiles = map[string]map[string]string{
+	EnvDev: {
+		"server.port":                 "8000",
+		"server.request_timeout":      "100s",
+		"server.shutdown_timeout":     "15s",
+		"database.driver":             "mongo",
+		"database.name":               "restaurant",
+		"database.connect_attempts":   "5",
+		"database.connect_backoff":    "2s",
+		"auth.signing_algorithm":      "HS256",
+		"auth.access_token_ttl":       "24h",
+		"auth.refresh_token_ttl":      "168h",
+		"pricing.currency":            "NGN",
+		"pricing.tax_rate":            "0",
+		"pricing.service_charge_rate": "0",
+		"payments.gateway":            "fake",
+		"invoicing.location":          "MAIN",
+		"invoicing.number_format":     "{location}-{year}-{seq:6}",
+		"invoicing.fiscal_year_start": "1",
+		"invoicing.time_zone":         "UTC",
+		"restaurant.name":             "Restaurant",
+		"printing.kitchen_printer":    "fake",
+		"printing.receipt_printer":    "fake",
+		"reservations.hours":          "11:00-22:00",
+		"reservations.slot_interval":  "15m",
+		"reservations.turn_times":     "2:75m,4:90m,6:2h,8:150m",
+		"reservations.no_show_after":  "15m",
+		"notifications.notifier":      "log",
+	},
+	EnvTest: {
+		"server.port":                 "8000",
+		"server.request_timeout":      "5s",
+		"server.shutdown_timeout":     "1s",
+		"database.driver":             "memory",
+		"database.name":               "restaurant_test",
+		"database.connect_attempts":   "1",
+		"database.connect_backoff":    "0s",
+		"auth.secret_key":             "test-secret-key",
+		"auth.signing_algorithm":      "HS256",
+		"auth.access_token_ttl":       "1h",
+		"auth.refresh_token_ttl":      "24h",
+		"pricing.currency":            "NGN",
+		"pricing.tax_rate":            "0",
+		"pricing.service_charge_rate": "0",
+		"payments.gateway":            "fake",
+		"invoicing.location":          "MAIN",
+		"invoicing.number_format":     "{location}-{year}-{seq:6}",
+		"invoicing.fiscal_year_start": "1",
+		"invoicing.time_zone":         "UTC",
+		"restaurant.name":             "Restaurant",
+		"printing.kitchen_printer":    "fake",
+		"printing.receipt_printer":    "fake",
+		"reservations.hours":          "11:00-22:00",
+		"reservations.slot_interval":  "15m",
+		"reservations.turn_times":     "2:75m,4:90m,6:2h,8:150m",
+		"reservations.no_show_after":  "15m",
+		"notifications.notifier":      "log",
+	},
+	EnvProd: {
+		"server.port":                 "8000",
+		"server.request_timeout":      "30s",
+		"server.shutdown_timeout":     "30s",
+		"database.driver":             "mongo",
+		"database.name":               "restaurant",
+		"database.connect_attempts":   "10",
+		"database.connect_backoff":    "3s",
+		"auth.signing_algorithm":      "HS256",
+		"auth.access_token_ttl":       "15m",
+		"auth.refresh_token_ttl":      "168h",
+		"pricing.currency":            "NGN",
+		"pricing.tax_rate":            "0",
+		"pricing.service_charge_rate": "0",
+		"payments.gateway":            "none",
+		"invoicing.location":          "MAIN",
+		"invoicing.number_format":     "{location}-{year}-{seq:6}",
+		"invoicing.fiscal_year_start": "1",
+		"invoicing.time_zone":         "UTC",
+		"restaurant.name":             "Restaurant",
+		"printing.kitchen_printer":    "none",
+		"printing.receipt_printer":    "none",
+		"reservations.hours":          "11:00-22:00",
+		"reservations.slot_interval":  "15m",
+		"reservations.turn_times":     "2:75m,4:90m,6:2h,8:150m",
+		"reservations.no_show_after":  "15m",
+		"notifications.notifier":      "none",
+	},
+}
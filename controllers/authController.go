@@ -2,121 +2,272 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"time"
-	"github.com/Micah-Shallom/modules/database"
+
 	"github.com/Micah-Shallom/modules/helpers"
 	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
 var validate = validator.New()
 
-func SignUp() gin.HandlerFunc {
+type AuthController struct {
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+	tokens   *helpers.TokenMaker
+}
+
+func NewAuthController(users repository.UserRepository, sessions repository.SessionRepository, tokens *helpers.TokenMaker) *AuthController {
+	return &AuthController{users: users, sessions: sessions, tokens: tokens}
+}
+
+func (ac *AuthController) SignUp() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx := c.Request.Context()
 		var user models.User
 
-		if err := c.ShouldBindJSON(&user); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if !bindNewUser(c, ac.users, &user) {
 			return
 		}
 
-		validationErr := validate.Struct(user)
-		if validationErr != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+		// Staff accounts are created by admins through POST /users/. The only
+		// exception is the very first account, which may bootstrap an admin.
+		if *user.UserType != models.RoleUser {
+			_, total, err := ac.users.List(ctx, 0, 1)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking existing users"})
+				return
+			}
+			if total > 0 || *user.UserType != models.RoleAdmin {
+				c.JSON(http.StatusForbidden, gin.H{"error": "only USER accounts can sign up, staff accounts are created by an admin"})
+				return
+			}
+		}
+
+		if err := ac.users.Create(ctx, &user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "User item was not created"})
 			return
 		}
 
-		count, err := userCollection.CountDocuments(ctx, bson.M{"email": user.Email})
-		defer cancel()
-		if err != nil {
-			log.Panic(err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the email"})
+		if _, _, err := ac.startSession(ctx, user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating tokens"})
+			return
 		}
+		c.JSON(http.StatusOK, gin.H{"InsertedID": user.ID})
+	}
+}
 
-		password := HashPassword(*user.Password)
-		user.Password = &password
+func (ac *AuthController) Login() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var user models.User
 
-		count, err = userCollection.CountDocuments(ctx, bson.M{"phone": user.Phone})
-		defer cancel()
-		if err != nil {
-			log.Panic(err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the phone"})
+		if err := c.ShouldBindJSON(&user); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
-
-		if count > 0 {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "this email or phone already exist"})
+		if user.Email == nil || user.Password == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "email and password are required"})
 			return
 		}
 
-		user.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		user.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		user.ID = primitive.NewObjectID()
-		user.UserID = user.ID.Hex()
-		token, refreshToken, _ := helpers.GenerateAllTokens(*user.Email, *user.FirstName, *user.LastName, *user.UserType, user.UserID)
-		user.Token = &token
-		user.RefreshToken = &refreshToken
+		foundUser, err := ac.users.FindByEmail(ctx, *user.Email)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "email or password is incorrect"})
+			return
+		}
 
-		resultInsertionNumber, insertErr := userCollection.InsertOne(ctx, user)
-		if insertErr != nil {
-			msg := fmt.Sprintf("User item was not created")
+		passwordIsValid, msg := VerifyPassword(*user.Password, *foundUser.Password)
+		if !passwordIsValid {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 			return
 		}
-		defer cancel()
-		c.JSON(http.StatusOK, resultInsertionNumber)
+
+		if _, _, err := ac.startSession(ctx, foundUser); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating tokens"})
+			return
+		}
+		foundUser, err = ac.users.FindByID(ctx, foundUser.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, foundUser)
 	}
 }
 
-func Login() gin.HandlerFunc {
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// Refresh exchanges a refresh token for a new access/refresh token pair. Each
+// refresh token can be used once: presenting one that was already rotated
+// revokes the whole session, logging out both the thief and the owner.
+func (ac *AuthController) Refresh() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		var user models.User
-		var foundUser models.User
+		ctx := c.Request.Context()
+		var request refreshRequest
 
-		if err := c.ShouldBindJSON(&user); err != nil {
+		if err := c.ShouldBindJSON(&request); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if validationErr := validate.Struct(request); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
 
-		err := userCollection.FindOne(ctx, bson.M{"email": user.Email}).Decode(&foundUser)
-		defer cancel()
+		claims, msg := ac.tokens.ValidateRefreshToken(request.RefreshToken)
+		if msg != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+			return
+		}
+
+		session, err := ac.sessions.FindByID(ctx, claims.SessionID)
+		if err != nil || session.UserID != claims.Uid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "session was not found"})
+			return
+		}
+		if session.Revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked"})
+			return
+		}
+		if session.CurrentTokenID != claims.Id {
+			ac.revokeReusedSession(c, session.SessionID)
+			return
+		}
+
+		user, err := ac.users.FindByID(ctx, claims.Uid)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user was not found"})
 			return
 		}
 
-		passwordIsValid, msg := VerifyPassword(*user.Password, *foundUser.Password)
-		defer cancel()
-		if passwordIsValid != true {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		nextTokenID := helpers.NewTokenID()
+		token, refreshToken, err := ac.tokens.GenerateAllTokens(*user.Email, *user.FirstName, *user.LastName, *user.UserType, user.UserID, session.SessionID, nextTokenID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating tokens"})
 			return
 		}
 
-		if foundUser.Email == nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		err = ac.sessions.Rotate(ctx, session.SessionID, claims.Id, nextTokenID, ac.tokens.RefreshTokenExpiry())
+		if errors.Is(err, repository.ErrNotFound) {
+			// another request rotated the same token first
+			ac.revokeReusedSession(c, session.SessionID)
 			return
 		}
-		token, refreshToken, _ := helpers.GenerateAllTokens(*foundUser.Email, *foundUser.FirstName, *foundUser.LastName, *foundUser.UserType, foundUser.UserID)
-		helpers.UpdateAllTokens(token, refreshToken, foundUser.UserID)
-		err = userCollection.FindOne(ctx, bson.M{"userid": foundUser.UserID}).Decode(&foundUser)
 		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while rotating tokens"})
+			return
+		}
+		if err := ac.users.UpdateTokens(ctx, user.UserID, token, refreshToken); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, foundUser)
+		c.JSON(http.StatusOK, gin.H{"token": token, "refresh_token": refreshToken})
+	}
+}
+
+// Logout revokes the session of the access token used for the request so that
+// neither its access token nor its refresh token is accepted again.
+func (ac *AuthController) Logout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := ac.sessions.Revoke(c.Request.Context(), c.GetString("sid"), "logout")
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while ending the session"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+	}
+}
+
+// JWKS publishes the public keys access tokens are signed with so that other
+// services can verify them without sharing a secret.
+func (ac *AuthController) JWKS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, ac.tokens.JWKS())
 	}
 }
 
+func (ac *AuthController) revokeReusedSession(c *gin.Context, sessionID string) {
+	if err := ac.sessions.Revoke(c.Request.Context(), sessionID, "refresh token reuse"); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while revoking the session"})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token has already been used, the session has been revoked"})
+}
+
+// startSession opens a new session for user, issues its first token pair and
+// stores the pair on the user record.
+func (ac *AuthController) startSession(ctx context.Context, user models.User) (token string, refreshToken string, err error) {
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	session := models.Session{
+		ID:             primitive.NewObjectID(),
+		UserID:         user.UserID,
+		CurrentTokenID: helpers.NewTokenID(),
+		ExpiresAt:      ac.tokens.RefreshTokenExpiry(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	session.SessionID = session.ID.Hex()
+
+	token, refreshToken, err = ac.tokens.GenerateAllTokens(*user.Email, *user.FirstName, *user.LastName, *user.UserType, user.UserID, session.SessionID, session.CurrentTokenID)
+	if err != nil {
+		return "", "", err
+	}
+	if err := ac.sessions.Create(ctx, &session); err != nil {
+		return "", "", err
+	}
+	if err := ac.users.UpdateTokens(ctx, user.UserID, token, refreshToken); err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
+// bindNewUser binds and validates a registration payload, rejects duplicate
+// emails and phones, hashes the password and assigns the generated fields. It
+// writes the error response itself and reports whether the caller may go on.
+func bindNewUser(c *gin.Context, users repository.UserRepository, user *models.User) bool {
+	if err := c.ShouldBindJSON(user); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	validationErr := validate.Struct(user)
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+		return false
+	}
+
+	exists, err := users.ExistsByEmailOrPhone(c.Request.Context(), *user.Email, *user.Phone)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the email or phone"})
+		return false
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "this email or phone already exist"})
+		return false
+	}
+
+	password := HashPassword(*user.Password)
+	user.Password = &password
+
+	user.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	user.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	user.ID = primitive.NewObjectID()
+	user.UserID = user.ID.Hex()
+	return true
+}
+
 func HashPassword(password string) string {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	if err != nil {
@@ -0,0 +1,476 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// longestShift is the longest a waiter can be put on a section in one go.
+const longestShift = 24 * time.Hour
+
+var (
+	// errInvalidSection marks sections listing tables that do not exist or
+	// already belong to another section.
+	errInvalidSection = errors.New("invalid section")
+	// errInvalidShift marks shifts that cannot be worked as asked.
+	errInvalidShift = errors.New("invalid shift")
+	// errShiftTaken marks a shift on a section that another waiter already
+	// covers for part of the time.
+	errShiftTaken = errors.New("section is already covered")
+	// errShiftCancelled marks a shift cancelled twice.
+	errShiftCancelled = errors.New("shift was cancelled")
+)
+
+// FloorController manages the areas of the floor, the sections tables are
+// grouped in and the shifts waiters work them in.
+type FloorController struct {
+	areas        repository.AreaRepository
+	sections     repository.SectionRepository
+	shifts       repository.ShiftRepository
+	tables       repository.TableRepository
+	users        repository.UserRepository
+	counters     repository.CounterRepository
+	transactions repository.Transactor
+	timeZone     *time.Location
+}
+
+func NewFloorController(areas repository.AreaRepository, sections repository.SectionRepository, shifts repository.ShiftRepository, tables repository.TableRepository, users repository.UserRepository, counters repository.CounterRepository, transactions repository.Transactor, timeZone *time.Location) *FloorController {
+	return &FloorController{areas: areas, sections: sections, shifts: shifts, tables: tables, users: users, counters: counters, transactions: transactions, timeZone: timeZone}
+}
+
+// areaUpdate is the body of PATCH /areas/:area_id; fields left out are not
+// changed.
+type areaUpdate struct {
+	Name   *string  `json:"name" validate:"omitempty,min=2,max=50"`
+	Width  *float64 `json:"width" validate:"omitempty,gt=0"`
+	Height *float64 `json:"height" validate:"omitempty,gt=0"`
+}
+
+// sectionUpdate is the body of PATCH /sections/:section_id; fields left out
+// are not changed.
+type sectionUpdate struct {
+	Name     *string   `json:"name" validate:"omitempty,min=2,max=50"`
+	TableIDs *[]string `json:"table_ids" validate:"omitempty,dive,required"`
+}
+
+// shiftRequest is the body of POST /shifts.
+type shiftRequest struct {
+	SectionID string    `json:"section_id" validate:"required"`
+	WaiterID  string    `json:"waiter_id" validate:"required"`
+	StartsAt  time.Time `json:"starts_at" validate:"required"`
+	EndsAt    time.Time `json:"ends_at" validate:"required"`
+}
+
+func (fc *FloorController) GetAreas() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		areas, err := fc.areas.List(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing areas"})
+			return
+		}
+		c.JSON(http.StatusOK, areas)
+	}
+}
+
+func (fc *FloorController) GetArea() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		area, ok := fc.findArea(c)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, area)
+	}
+}
+
+// CreateArea adds a part of the floor tables can be placed in.
+func (fc *FloorController) CreateArea() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var area models.Area
+
+		if err := c.ShouldBindJSON(&area); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(area); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		area.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		area.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		area.ID = primitive.NewObjectID()
+		area.AreaID = area.ID.Hex()
+
+		if err := fc.areas.Create(ctx, &area); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "area was not created"})
+			return
+		}
+		c.JSON(http.StatusOK, area)
+	}
+}
+
+// UpdateArea renames an area or resizes its plan. A plan cannot shrink past
+// the tables placed on it.
+func (fc *FloorController) UpdateArea() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var update areaUpdate
+
+		if err := c.ShouldBindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		area, ok := fc.findArea(c)
+		if !ok {
+			return
+		}
+		if update.Name != nil {
+			area.Name = *update.Name
+		}
+		if update.Width != nil {
+			area.Width = *update.Width
+		}
+		if update.Height != nil {
+			area.Height = *update.Height
+		}
+		tables, err := fc.tables.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing tables"})
+			return
+		}
+		for _, table := range tables {
+			if table.AreaID != nil && *table.AreaID == area.AreaID && table.Layout != nil && !table.Layout.Fits(area) {
+				c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("table %s would lie outside the plan", tableLabel(table))})
+				return
+			}
+		}
+		area.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := fc.areas.Update(ctx, &area); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "area update failed"})
+			return
+		}
+		c.JSON(http.StatusOK, area)
+	}
+}
+
+func (fc *FloorController) GetSections() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sections, err := fc.sections.List(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing sections"})
+			return
+		}
+		c.JSON(http.StatusOK, sections)
+	}
+}
+
+func (fc *FloorController) GetSection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		section, err := fc.sections.FindByID(c.Request.Context(), c.Param("section_id"))
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "section was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the section"})
+			return
+		}
+		c.JSON(http.StatusOK, section)
+	}
+}
+
+// CreateSection groups tables for one waiter to look after.
+func (fc *FloorController) CreateSection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var section models.Section
+
+		if err := c.ShouldBindJSON(&section); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if section.TableIDs == nil {
+			section.TableIDs = []string{}
+		}
+		if err := validate.Struct(section); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		section.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		section.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		section.ID = primitive.NewObjectID()
+		section.SectionID = section.ID.Hex()
+
+		err := fc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := fc.checkSection(ctx, section); err != nil {
+				return err
+			}
+			return fc.sections.Create(ctx, &section)
+		})
+		if err != nil {
+			respondSectionError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, section)
+	}
+}
+
+// UpdateSection renames a section or changes its tables.
+func (fc *FloorController) UpdateSection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var update sectionUpdate
+
+		if err := c.ShouldBindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var section models.Section
+		err := fc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			section, err = fc.sections.FindByID(ctx, c.Param("section_id"))
+			if err != nil {
+				return err
+			}
+			if update.Name != nil {
+				section.Name = *update.Name
+			}
+			if update.TableIDs != nil {
+				section.TableIDs = *update.TableIDs
+			}
+			if err := fc.checkSection(ctx, section); err != nil {
+				return err
+			}
+			section.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			return fc.sections.Update(ctx, &section)
+		})
+		if err != nil {
+			respondSectionError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, section)
+	}
+}
+
+// checkSection checks that the tables of section exist and belong to no other
+// section.
+func (fc *FloorController) checkSection(ctx context.Context, section models.Section) error {
+	tables, err := fc.tables.List(ctx)
+	if err != nil {
+		return err
+	}
+	sections, err := fc.sections.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tableID := range section.TableIDs {
+		i := slices.IndexFunc(tables, func(table models.Table) bool { return table.TableID == tableID })
+		if i < 0 {
+			return fmt.Errorf("%w: table %s was not found", errInvalidSection, tableID)
+		}
+		for _, other := range sections {
+			if other.SectionID != section.SectionID && slices.Contains(other.TableIDs, tableID) {
+				return fmt.Errorf("%w: table %s is in section %s", errInvalidSection, tableLabel(tables[i]), other.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func respondSectionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "section was not found"})
+	case errors.Is(err, errInvalidSection):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while saving the section"})
+	}
+}
+
+// GetShifts lists the shifts that start on ?date=, today by default, in the
+// time zone of the restaurant.
+func (fc *FloorController) GetShifts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		day, err := time.ParseInLocation(time.DateOnly, c.DefaultQuery("date", time.Now().In(fc.timeZone).Format(time.DateOnly)), fc.timeZone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be a day such as 2026-10-20"})
+			return
+		}
+		next := day.AddDate(0, 0, 1)
+		shifts, err := fc.shifts.ListBetween(ctx, day, next)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing shifts"})
+			return
+		}
+		starting := []models.Shift{}
+		for _, shift := range shifts {
+			if !shift.StartsAt.Before(day) && shift.StartsAt.Before(next) {
+				starting = append(starting, shift)
+			}
+		}
+		sort.SliceStable(starting, func(i, j int) bool { return starting[i].StartsAt.Before(starting[j].StartsAt) })
+		c.JSON(http.StatusOK, starting)
+	}
+}
+
+// CreateShift puts a waiter on a section. Only one waiter covers a section at
+// a time.
+func (fc *FloorController) CreateShift() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var request shiftRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		shift := models.Shift{
+			ID:        primitive.NewObjectID(),
+			SectionID: request.SectionID,
+			WaiterID:  request.WaiterID,
+			StartsAt:  request.StartsAt,
+			EndsAt:    request.EndsAt,
+			CreatedBy: c.GetString("uid"),
+			CreatedAt: now,
+		}
+		shift.ShiftID = shift.ID.Hex()
+		err := fc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := fc.checkShift(ctx, shift); err != nil {
+				return err
+			}
+			return fc.shifts.Create(ctx, &shift)
+		})
+		if err != nil {
+			respondShiftError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, shift)
+	}
+}
+
+// CancelShift takes a waiter off a section. The shift is kept for the record.
+func (fc *FloorController) CancelShift() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var shift models.Shift
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		err := fc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			shift, err = fc.shifts.FindByID(ctx, c.Param("shift_id"))
+			if err != nil {
+				return err
+			}
+			if shift.CancelledAt != nil {
+				return errShiftCancelled
+			}
+			shift.CancelledAt = &now
+			return fc.shifts.Update(ctx, &shift)
+		})
+		if err != nil {
+			respondShiftError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, shift)
+	}
+}
+
+// checkShift checks that shift puts a waiter on an existing section for a
+// sensible time that no other shift covers. Bumping the counter of the section
+// keeps two shifts for it from being booked at once.
+func (fc *FloorController) checkShift(ctx context.Context, shift models.Shift) error {
+	if !shift.StartsAt.Before(shift.EndsAt) {
+		return fmt.Errorf("%w: the shift must end after it starts", errInvalidShift)
+	}
+	if shift.EndsAt.Sub(shift.StartsAt) > longestShift {
+		return fmt.Errorf("%w: a shift lasts %s at most", errInvalidShift, longestShift)
+	}
+	if _, err := fc.counters.Next(ctx, "shifts:"+shift.SectionID); err != nil {
+		return err
+	}
+	if _, err := fc.sections.FindByID(ctx, shift.SectionID); errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: section %s was not found", errInvalidShift, shift.SectionID)
+	} else if err != nil {
+		return err
+	}
+	waiter, err := fc.users.FindByID(ctx, shift.WaiterID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: user %s was not found", errInvalidShift, shift.WaiterID)
+	}
+	if err != nil {
+		return err
+	}
+	if waiter.UserType == nil || *waiter.UserType != models.RoleWaiter {
+		return fmt.Errorf("%w: user %s is not a waiter", errInvalidShift, shift.WaiterID)
+	}
+
+	shifts, err := fc.shifts.ListBetween(ctx, shift.StartsAt, shift.EndsAt)
+	if err != nil {
+		return err
+	}
+	for _, other := range shifts {
+		if other.SectionID == shift.SectionID && other.Overlaps(shift.StartsAt, shift.EndsAt) {
+			return fmt.Errorf("%w: shift %s covers it until %s", errShiftTaken, other.ShiftID, other.EndsAt.In(fc.timeZone).Format(time.Kitchen))
+		}
+	}
+	return nil
+}
+
+func respondShiftError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "shift was not found"})
+	case errors.Is(err, errInvalidShift):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errShiftTaken), errors.Is(err, errShiftCancelled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while saving the shift"})
+	}
+}
+
+// findArea loads the area of the request, answering it when there is none.
+func (fc *FloorController) findArea(c *gin.Context) (models.Area, bool) {
+	area, err := fc.areas.FindByID(c.Request.Context(), c.Param("area_id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "area was not found"})
+		return area, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the area"})
+		return area, false
+	}
+	return area, true
+}
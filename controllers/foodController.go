@@ -1,28 +1,38 @@
 package controllers
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
-	"log"
 
-	"github.com/Micah-Shallom/modules/database"
 	"github.com/Micah-Shallom/modules/helpers"
 	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var foodCollection *mongo.Collection = database.OpenCollection(database.Client, "food")
+type FoodController struct {
+	foods    repository.FoodRepository
+	menus    repository.MenuRepository
+	pricing  helpers.Pricing
+	schedule helpers.MenuSchedule
+}
+
+func NewFoodController(foods repository.FoodRepository, menus repository.MenuRepository, pricing helpers.Pricing, schedule helpers.MenuSchedule) *FoodController {
+	return &FoodController{foods: foods, menus: menus, pricing: pricing, schedule: schedule}
+}
 
-func GetFoods() gin.HandlerFunc{
+// GetFoods pages through the foods, those of one menu with ?menu_id= or those
+// that can be ordered now with ?available=true: the foods of the menus served
+// now and the foods on no menu.
+func (fc *FoodController) GetFoods() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		ctx := c.Request.Context()
+
 		recordPerPage, err := strconv.Atoi(c.Query("recordPerPage"))
 		if err != nil || recordPerPage < 1 {
 			recordPerPage = 10
@@ -32,54 +42,67 @@ func GetFoods() gin.HandlerFunc{
 		if err != nil || page < 1 {
 			page = 1
 		}
-		startIndex := (page-1) * recordPerPage
-		startIndex, err = strconv.Atoi(c.Query("startIndex"))
-
-		matchStage := bson.D{{"$match", bson.D{{}}}}
-		groupStage := bson.D{{"$group", bson.D{{"_id", bson.D{{"_id", "null"}}}, {"total_count", bson.D{{"$sum", 1}}}, {"data", bson.D{{"$push", "$$ROOT"}}}}}}
-		projectStage := bson.D{
-			{
-				"$project", bson.D{
-					{"_id", 0},
-					{"total_count", 1},
-					{"food_items", bson.D{{"$slice", []interface{}{"$data", startIndex, recordPerPage}}}},
-				}}}
-
-		result, err := foodCollection.Aggregate(ctx, mongo.Pipeline{
-			matchStage, groupStage, projectStage})
-		defer cancel()
+		startIndex := (page - 1) * recordPerPage
+		if index, err := strconv.Atoi(c.Query("startIndex")); err == nil && index >= 0 {
+			startIndex = index
+		}
+
+		var menuIDs []string
+		var withoutMenu bool
+		if available, _ := strconv.ParseBool(c.Query("available")); available {
+			menus, err := fc.menus.List(ctx)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing menus"})
+				return
+			}
+			now := time.Now()
+			menuIDs, withoutMenu = []string{}, true
+			for _, menu := range menus {
+				if fc.schedule.Served(menu, now) {
+					menuIDs = append(menuIDs, menu.MenuID)
+				}
+			}
+		}
+		if menuID := c.Query("menu_id"); menuID != "" {
+			if menuIDs != nil && !slices.Contains(menuIDs, menuID) {
+				menuIDs = []string{}
+			} else {
+				menuIDs = []string{menuID}
+			}
+			withoutMenu = false
+		}
+
+		foods, total, err := fc.foods.List(ctx, menuIDs, withoutMenu, startIndex, recordPerPage)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing food items"})
+			return
 		}
-		var allFoods []bson.M
-		if err = result.All(ctx, &allFoods); err != nil {
-			log.Fatal(err)
-		}
-		c.JSON(http.StatusOK, allFoods[0])
+		c.JSON(http.StatusOK, gin.H{"total_count": total, "food_items": foods})
 	}
 }
 
-func GetFood() gin.HandlerFunc{
+func (fc *FoodController) GetFood() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		ctx := c.Request.Context()
 		foodID := c.Param("food_id")
-		var food models.Food
 
-		err := foodCollection.FindOne(ctx, bson.M{"food_id": foodID}).Decode(&food)
-		defer cancel()
+		food, err := fc.foods.FindByID(ctx, foodID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":"error occured while fetching the food collection from database"} )
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the food collection from database"})
 			return
 		}
 		c.JSON(http.StatusOK, food)
 	}
 }
 
-func CreateFood() gin.HandlerFunc{
+func (fc *FoodController) CreateFood() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		ctx := c.Request.Context()
 		var food models.Food
-		var menu models.Menu
 
 		if err := c.ShouldBindJSON(&food); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -92,37 +115,42 @@ func CreateFood() gin.HandlerFunc{
 			return
 		}
 
-		err := menuCollection.FindOne(ctx, bson.M{"menu_id": food.MenuID}).Decode(&menu)
-		defer cancel()
+		price, err := fc.price(*food.Price)
 		if err != nil {
-			msg := fmt.Sprintf("menu was not found")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		food.Price = &price
+		if food.Modifiers, err = fc.modifiers(food.Modifiers); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if food.MenuID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "menu_id is required"})
+			return
+		}
+		if _, err := fc.menus.FindByID(ctx, *food.MenuID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "menu was not found"})
 			return
 		}
 		food.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		food.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		food.ID = primitive.NewObjectID()
 		food.FoodID = food.ID.Hex()
-		num := helpers.ToFixed(*food.Price, 2)
-		food.Price = &num
-		
-		result, insertErr := foodCollection.InsertOne(ctx, food)
-		defer cancel()
-		if insertErr != nil {
-			msg := fmt.Sprintf("Food item was not created")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+
+		if err := fc.foods.Create(ctx, &food); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Food item was not created"})
 			return
 		}
-		c.JSON(http.StatusOK, result)
+		c.JSON(http.StatusOK, food)
 	}
 }
 
-
-func UpdateFood() gin.HandlerFunc{
-	return func(c *gin.Context){
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+func (fc *FoodController) UpdateFood() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
 		var food models.Food
-		var menu models.Menu
 
 		foodID := c.Param("food_id")
 
@@ -131,51 +159,95 @@ func UpdateFood() gin.HandlerFunc{
 			return
 		}
 
-		var updateObj primitive.D
+		existing, err := fc.foods.FindByID(ctx, foodID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "food item update failed"})
+			return
+		}
 
 		if food.Name != nil {
-			updateObj = append(updateObj, bson.E{Key:"name", Value:food.Name})
+			existing.Name = food.Name
 		}
 		if food.Price != nil {
-			updateObj = append(updateObj, bson.E{Key:"price", Value:food.Price})
+			price, err := fc.price(*food.Price)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			existing.Price = &price
 		}
 		if food.FoodImage != nil {
-			updateObj = append(updateObj, bson.E{Key:"food_image", Value:food.FoodImage})
+			existing.FoodImage = food.FoodImage
 		}
-		if food.MenuID != nil {
-			err := menuCollection.FindOne(ctx, bson.M{"menu_id": food.MenuID}).Decode(&menu)
-			defer cancel()
+		// modifier groups are replaced as a whole; groups and options sent
+		// with their IDs keep them
+		if food.Modifiers != nil {
+			if err := validate.Var(food.Modifiers, "dive"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			modifiers, err := fc.modifiers(food.Modifiers)
 			if err != nil {
-				msg := fmt.Sprintf("message: Menu was not found")
-				c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			updateObj = append(updateObj, bson.E{Key:"menu", Value:food.Price}) //this doesnt make sense for now
+			existing.Modifiers = modifiers
 		}
-		food.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{Key:"updated_at", Value:food.UpdatedAt})
-
-		upsert := true
-		filter := bson.M{"food_id": foodID}
+		if food.MenuID != nil {
+			if _, err := fc.menus.FindByID(ctx, *food.MenuID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "message: Menu was not found"})
+				return
+			}
+			existing.MenuID = food.MenuID
+		}
+		existing.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		if err := fc.foods.Update(ctx, &existing); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "food item update failed"})
+			return
 		}
+		c.JSON(http.StatusOK, existing)
+	}
+}
 
-		result, err := foodCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{Key:"$set", Value:updateObj},
-			},
-			&opt,
-		)
+// price checks that a food price is positive and in the trading currency.
+func (fc *FoodController) price(amount models.Money) (models.Money, error) {
+	amount, err := fc.pricing.Price(amount)
+	if err != nil {
+		return amount, err
+	}
+	if amount.IsNegative() || amount.IsZero() {
+		return amount, errors.New("price must be positive")
+	}
+	return amount, nil
+}
 
-		if err != nil {
-			msg := fmt.Sprint("food item update failed")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-			return
+// modifiers gives new modifier groups and options their IDs, puts their price
+// deltas in the trading currency and checks that the groups can be satisfied.
+func (fc *FoodController) modifiers(groups []models.ModifierGroup) ([]models.ModifierGroup, error) {
+	if groups == nil {
+		return []models.ModifierGroup{}, nil
+	}
+	for g := range groups {
+		group := &groups[g]
+		if group.GroupID == "" {
+			group.GroupID = primitive.NewObjectID().Hex()
+		}
+		for o := range group.Options {
+			option := &group.Options[o]
+			if option.OptionID == "" {
+				option.OptionID = primitive.NewObjectID().Hex()
+			}
+			delta, err := fc.pricing.Price(option.PriceDelta)
+			if err != nil {
+				return nil, fmt.Errorf("modifier option %q: %s", option.Name, err)
+			}
+			option.PriceDelta = delta
 		}
-		c.JSON(http.StatusOK, result)
 	}
-}
\ No newline at end of file
+	return groups, models.CheckModifierGroups(groups)
+}
@@ -2,54 +2,78 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"sort"
 	"time"
 
-	"github.com/Micah-Shallom/modules/database"
+	"github.com/Micah-Shallom/modules/helpers"
 	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/payments"
+	"github.com/Micah-Shallom/modules/printing"
+	"github.com/Micah-Shallom/modules/receipts"
+	"github.com/Micah-Shallom/modules/repository"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var invoiceCollection *mongo.Collection = database.OpenCollection(database.Client, "invoice")
+type InvoiceController struct {
+	invoices     repository.InvoiceRepository
+	orders       repository.OrderRepository
+	orderItems   repository.OrderItemRepository
+	tables       repository.TableRepository
+	payments     repository.PaymentRepository
+	creditNotes  repository.CreditNoteRepository
+	counters     repository.CounterRepository
+	transactions repository.Transactor
+	// gateway takes card payments; nil when only cash is accepted
+	gateway   payments.Gateway
+	pricing   helpers.Pricing
+	numbering helpers.InvoiceNumbering
+	header    receipts.Header
+	printer   *printing.Queue
+}
+
+func NewInvoiceController(invoices repository.InvoiceRepository, orders repository.OrderRepository, orderItems repository.OrderItemRepository, tables repository.TableRepository, paymentRecords repository.PaymentRepository, creditNotes repository.CreditNoteRepository, counters repository.CounterRepository, transactions repository.Transactor, gateway payments.Gateway, pricing helpers.Pricing, numbering helpers.InvoiceNumbering, header receipts.Header, printer *printing.Queue) *InvoiceController {
+	return &InvoiceController{invoices: invoices, orders: orders, orderItems: orderItems, tables: tables, payments: paymentRecords, creditNotes: creditNotes, counters: counters, transactions: transactions, gateway: gateway, pricing: pricing, numbering: numbering, header: header, printer: printer}
+}
 
-func GetInvoices() gin.HandlerFunc{
+func (ic *InvoiceController) GetInvoices() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		result, err := invoiceCollection.Find(context.TODO(), bson.M{})
-		defer cancel()
+		ctx := c.Request.Context()
+
+		allInvoices, err := ic.invoices.List(ctx)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":"error occured while listing invoice items"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing invoice items"})
 			return
 		}
-		var allInvoices []bson.M
-		if err = result.All(ctx, &allInvoices); err != nil {
-			log.Fatal(err)
-		}
 		c.JSON(http.StatusOK, allInvoices)
-	}	
+	}
 }
 
-func GetInvoice() gin.HandlerFunc{
+func (ic *InvoiceController) GetInvoice() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		var invoice models.Invoice
+		ctx := c.Request.Context()
 		invoiceID := c.Param("invoice_id")
-		filter := bson.M{"invoice_id":invoiceID}
-		err := invoiceCollection.FindOne(ctx,filter).Decode(&invoice)
-		defer cancel()
+
+		invoice, err := ic.invoices.FindByID(ctx, invoiceID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while getting invoice item"})
+			return
+		}
+
+		allOrderItems, err := ic.orderItems.ItemsByOrder(ctx, invoice.OrderID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":"error occured while getting invoice item"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing the invoice order items"})
 			return
 		}
 
 		var invoiceView models.InvoiceViewFormat
-		allOrderItems, err := ItemsByOrder(invoice.OrderID)
 		invoiceView.OrderID = invoice.OrderID
 		invoiceView.PaymentDueDate = invoice.PaymentDueDate
 
@@ -59,38 +83,78 @@ func GetInvoice() gin.HandlerFunc{
 		}
 
 		invoiceView.InvoiceID = invoice.InvoiceID
+		invoiceView.InvoiceNumber = invoice.Number()
 		invoiceView.PaymentStatus = invoice.PaymentStatus
-		invoiceView.PaymentDue = allOrderItems[0]["payment_due"]
-		invoiceView.TableNumber = allOrderItems[0]["table_number"]
-		invoiceView.OrderDetails = allOrderItems[0]["order_items"]
+		invoiceView.Total = invoice.Total
+		invoiceView.Paid = invoice.Paid
+		invoiceView.Balance = invoice.Balance
+		invoiceView.Refunded = invoice.Refunded
+		invoiceView.Voided = invoice.Voided
+		invoiceView.SplitMode = invoice.SplitMode
+		invoiceView.Splits = invoice.Splits
+		if len(allOrderItems) > 0 {
+			invoiceView.PaymentDue = allOrderItems[0].PaymentDue
+			invoiceView.TableNumber = allOrderItems[0].TableNumber
+			invoiceView.OrderDetails = allOrderItems[0].OrderItems
+		}
+
+		// orders priced by the server carry their totals; the aggregated
+		// payment_due only covers orders stored before that
+		order, err := ic.orders.FindByID(ctx, invoice.OrderID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the invoice order"})
+			return
+		}
+		if order.Totals != nil {
+			invoiceView.Totals = order.Totals
+			invoiceView.PaymentDue = order.Totals.GrandTotal
+		}
 
 		c.JSON(http.StatusOK, invoiceView)
 	}
 }
 
-func CreateInvoice() gin.HandlerFunc{
+// invoiceRequest is the body of POST /invoices. Amounts, splits, payments and
+// reversals are never taken from the client: they follow from the order and
+// from the payment, refund and void endpoints.
+type invoiceRequest struct {
+	OrderID       string  `json:"order_id"`
+	PaymentMethod *string `json:"payment_method"`
+}
+
+func (ic *InvoiceController) CreateInvoice() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		var invoice models.Invoice
-		var order models.Order
+		ctx := c.Request.Context()
+		var request invoiceRequest
 
-		if err := c.ShouldBindJSON(&invoice); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		err := orderCollection.FindOne(ctx, bson.M{"order_id": invoice.OrderID}).Decode(&order)
+		order, err := ic.orders.FindByID(ctx, request.OrderID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
 		if err != nil {
-			msg := fmt.Sprintf("Message: Order was not found")
-			c.JSON(http.StatusInternalServerError, gin.H{"error":msg})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the order"})
 			return
 		}
-		status := "PENDING"
-		if invoice.PaymentStatus == nil {
-			invoice.PaymentStatus = &status
+		// drafts are not ordered yet and cancelled or voided orders are not
+		// owed
+		switch status := order.CurrentStatus(); status {
+		case models.OrderStatusDraft, models.OrderStatusCancelled, models.OrderStatusVoided:
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("a %s order cannot be invoiced", status)})
+			return
+		}
+		invoice := models.Invoice{OrderID: request.OrderID, PaymentMethod: request.PaymentMethod}
+		if err := ic.openBill(ctx, &invoice); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while totalling the invoice order"})
+			return
 		}
 
-		invoice.PaymentDueDate, _ = time.Parse(time.RFC3339, time.Now().AddDate(0,0,1).Format(time.RFC3339))
+		invoice.PaymentDueDate, _ = time.Parse(time.RFC3339, time.Now().AddDate(0, 0, 1).Format(time.RFC3339))
 		invoice.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		invoice.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		invoice.ID = primitive.NewObjectID()
@@ -98,25 +162,48 @@ func CreateInvoice() gin.HandlerFunc{
 
 		validationErr := validate.Struct(invoice)
 		if validationErr != nil {
-			c.JSON(http.StatusInternalServerError, validationErr.Error())
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 			return
 		}
 
-		result, insertErr := invoiceCollection.InsertOne(ctx, invoice)
-		if insertErr != nil {
-			msg := fmt.Sprintf("Invoice item was not created")
-			c.JSON(http.StatusInternalServerError, msg)
+		// the number is taken in the same transaction as the invoice is
+		// created in, so a failed creation gives it back and leaves no gap
+		err = ic.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			fiscalYear := ic.numbering.FiscalYear(invoice.CreatedAt)
+			seq, err := ic.counters.Next(ctx, ic.numbering.Counter(fiscalYear))
+			if err != nil {
+				return err
+			}
+			invoice.InvoiceNumber = ic.numbering.Number(fiscalYear, seq)
+			// taking the number serialises the creations, so two requests
+			// cannot both find the order unbilled
+			existing, err := ic.invoices.ListByOrder(ctx, invoice.OrderID)
+			if err != nil {
+				return err
+			}
+			for _, other := range existing {
+				if other.Voided == nil {
+					return fmt.Errorf("%w: invoice %s", errOrderInvoiced, other.Number())
+				}
+			}
+			return ic.invoices.Create(ctx, &invoice)
+		})
+		if errors.Is(err, errOrderInvoiced) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invoice item was not created"})
 			return
 		}
-		defer cancel()
 
-		c.JSON(http.StatusOK, result)
+		c.JSON(http.StatusOK, invoice)
 	}
 }
 
-func UpdateInvoice() gin.HandlerFunc{
-	return func(c *gin.Context){
-		var ctx, cancel = context.WithTimeout(context.Background(), 100 * time.Second)
+func (ic *InvoiceController) UpdateInvoice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
 		var invoice models.Invoice
 		invoiceID := c.Param("invoice_id")
 
@@ -125,43 +212,306 @@ func UpdateInvoice() gin.HandlerFunc{
 			return
 		}
 
-		filter := bson.M{"invoice_id":invoiceID}
-
-		var updateObj primitive.D
+		existing, err := ic.invoices.FindByID(ctx, invoiceID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "invoice item update failed"})
+			return
+		}
 
-		if invoice.PaymentMethod != nil {
-			updateObj = append(updateObj, bson.E{"payment_method", invoice.PaymentMethod})
+		if invoice.PaymentStatus != nil && (existing.PaymentStatus == nil || *invoice.PaymentStatus != *existing.PaymentStatus) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "payment_status follows from the payments recorded against the invoice"})
+			return
 		}
-		if invoice.PaymentStatus != nil {
-			updateObj = append(updateObj, bson.E{"payment_status", invoice.PaymentStatus})
+		if invoice.PaymentMethod != nil {
+			existing.PaymentMethod = invoice.PaymentMethod
 		}
-		invoice.UpdatedAt, _ =  time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{"updated_at", invoice.UpdatedAt})
+		existing.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-		upsert := true
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		validationErr := validate.Struct(existing)
+		if validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
 		}
 
-		status := "PENDING"
-		if invoice.PaymentStatus == nil {
-			invoice.PaymentStatus = &status
+		if err := ic.invoices.Update(ctx, &existing); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "invoice item update failed"})
+			return
 		}
+		c.JSON(http.StatusOK, existing)
+	}
+}
 
-		result, err := invoiceCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{"$set", updateObj},
-			},
-			&opt,
-		)
-		defer cancel()
-		if err != nil {
-			msg := fmt.Sprintf("invoice item update failed")
-			c.JSON(http.StatusInternalServerError, gin.H{"error":msg})
+type splitRequest struct {
+	Mode   string `json:"mode" validate:"required,oneof=FULL EVEN ITEM SEAT"`
+	Guests int    `json:"guests" validate:"omitempty,min=2,max=50"`
+	Splits []struct {
+		Label        string   `json:"label"`
+		OrderItemIDs []string `json:"order_item_ids"`
+	} `json:"splits"`
+}
+
+// SplitInvoice divides the invoice into sub-bills: FULL puts everything on one
+// bill, EVEN shares it between guests, ITEM assigns every order item to one
+// of the given splits and SEAT bills each seat for its items, sharing items
+// without a seat evenly. Order-level charges such as tax and service are
+// shared in proportion to each bill's items. An invoice can only be split
+// again while no payment has been taken.
+func (ic *InvoiceController) SplitInvoice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var request splitRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, result)
+		if err := validate.Struct(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		invoiceID := c.Param("invoice_id")
+		var invoice models.Invoice
+		err := ic.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			invoice, err = ic.invoices.FindByID(ctx, invoiceID)
+			if err != nil {
+				return err
+			}
+			if invoice.Voided != nil {
+				return errInvoiceVoided
+			}
+			if invoice.HasPayments() {
+				return errInvoiceHasPayments
+			}
+			pending, err := ic.payments.ListByInvoice(ctx, invoiceID)
+			if err != nil {
+				return err
+			}
+			for _, payment := range pending {
+				if payment.IsOpen() {
+					return errInvoiceHasPayments
+				}
+			}
+			if err := ic.openBill(ctx, &invoice); err != nil {
+				return err
+			}
+			items, err := ic.orderItems.ListByOrder(ctx, invoice.OrderID)
+			if err != nil {
+				return err
+			}
+			splits, err := buildSplits(request, items)
+			if err != nil {
+				return err
+			}
+			shareSplits(*invoice.Total, splits)
+
+			invoice.SplitMode = request.Mode
+			invoice.Splits = splits
+			invoice.Settle()
+			invoice.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			return ic.invoices.Update(ctx, &invoice)
+		})
+		var splitErr splitError
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+		case errors.Is(err, errInvoiceHasPayments), errors.Is(err, errInvoiceVoided):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.As(err, &splitErr):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "invoice split failed"})
+		default:
+			c.JSON(http.StatusOK, invoice)
+		}
+	}
+}
+
+var (
+	errInvoiceHasPayments = errors.New("the invoice already has payments and cannot be split again")
+	errOrderInvoiced      = errors.New("the order is already billed on an invoice that was not voided")
+)
+
+// splitError is a problem with the split or payment requested by the client.
+type splitError string
+
+func (e splitError) Error() string {
+	return string(e)
+}
+
+// openBill snapshots the amount due on an invoice that has none yet, either
+// because it is new or because it was created before invoices had totals,
+// and puts all of it on a single bill.
+func (ic *InvoiceController) openBill(ctx context.Context, invoice *models.Invoice) error {
+	if invoice.Total != nil {
+		return nil
+	}
+	total, err := ic.amountDue(ctx, invoice.OrderID)
+	if err != nil {
+		return err
+	}
+	items, err := ic.orderItems.ListByOrder(ctx, invoice.OrderID)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.OrderItemID)
+	}
+
+	invoice.Total = &total
+	invoice.SplitMode = models.SplitFull
+	invoice.Splits = []models.InvoiceSplit{{
+		SplitID:      primitive.NewObjectID().Hex(),
+		Label:        "Full bill",
+		OrderItemIDs: ids,
+		Amount:       total,
+		Payments:     []models.InvoicePayment{},
+	}}
+	invoice.Settle()
+	return nil
+}
+
+// amountDue is the grand total of the order, or for orders stored before the
+// server computed totals, the sum of their lines.
+func (ic *InvoiceController) amountDue(ctx context.Context, orderID string) (models.Money, error) {
+	order, err := ic.orders.FindByID(ctx, orderID)
+	if err != nil {
+		return models.Money{}, err
+	}
+	if order.Totals != nil {
+		return order.Totals.GrandTotal.WithCurrency(ic.pricing.Currency), nil
+	}
+	summaries, err := ic.orderItems.ItemsByOrder(ctx, orderID)
+	if err != nil || len(summaries) == 0 {
+		return models.NewMoney(0, ic.pricing.Currency), err
 	}
-}
\ No newline at end of file
+	return summaries[0].PaymentDue.WithCurrency(ic.pricing.Currency), nil
+}
+
+// buildSplits lays out the sub-bills requested, without amounts yet. The
+// Amount of each split temporarily holds its weight: the value of its items.
+func buildSplits(request splitRequest, items []models.OrderItem) ([]models.InvoiceSplit, error) {
+	lineValue := map[string]int64{}
+	for _, item := range items {
+		switch {
+		case item.LineTotal != nil:
+			lineValue[item.OrderItemID] = item.LineTotal.Minor
+		case item.UnitPrice != nil:
+			lineValue[item.OrderItemID] = helpers.LineTotal(*item.UnitPrice, item.Units).Minor
+		default:
+			lineValue[item.OrderItemID] = 0
+		}
+	}
+	newSplit := func(label string, weight int64, ids []string) models.InvoiceSplit {
+		if ids == nil {
+			ids = []string{}
+		}
+		return models.InvoiceSplit{
+			SplitID:      primitive.NewObjectID().Hex(),
+			Label:        label,
+			OrderItemIDs: ids,
+			Amount:       models.NewMoney(weight, ""),
+			Payments:     []models.InvoicePayment{},
+		}
+	}
+
+	switch request.Mode {
+	case models.SplitFull:
+		ids := []string{}
+		for _, item := range items {
+			ids = append(ids, item.OrderItemID)
+		}
+		return []models.InvoiceSplit{newSplit("Full bill", 1, ids)}, nil
+
+	case models.SplitEven:
+		if request.Guests < 2 {
+			return nil, splitError("an even split needs at least 2 guests")
+		}
+		splits := make([]models.InvoiceSplit, 0, request.Guests)
+		for g := 1; g <= request.Guests; g++ {
+			splits = append(splits, newSplit(fmt.Sprintf("Guest %d", g), 1, nil))
+		}
+		return splits, nil
+
+	case models.SplitItem:
+		if len(request.Splits) == 0 {
+			return nil, splitError("an item split needs the items of every split")
+		}
+		assigned := map[string]bool{}
+		splits := make([]models.InvoiceSplit, 0, len(request.Splits))
+		for s, requested := range request.Splits {
+			var weight int64
+			for _, id := range requested.OrderItemIDs {
+				value, ok := lineValue[id]
+				if !ok {
+					return nil, splitError(fmt.Sprintf("order item %s is not on this invoice", id))
+				}
+				if assigned[id] {
+					return nil, splitError(fmt.Sprintf("order item %s is on more than one split", id))
+				}
+				assigned[id] = true
+				weight += value
+			}
+			label := requested.Label
+			if label == "" {
+				label = fmt.Sprintf("Split %d", s+1)
+			}
+			splits = append(splits, newSplit(label, weight, requested.OrderItemIDs))
+		}
+		for _, item := range items {
+			if !assigned[item.OrderItemID] {
+				return nil, splitError(fmt.Sprintf("order item %s is not on any split", item.OrderItemID))
+			}
+		}
+		return splits, nil
+
+	case models.SplitSeat:
+		bySeat := map[int][]string{}
+		seatValue := map[int]int64{}
+		var seats []int
+		var shared int64
+		for _, item := range items {
+			if item.Seat == nil {
+				shared += lineValue[item.OrderItemID]
+				continue
+			}
+			if _, ok := bySeat[*item.Seat]; !ok {
+				seats = append(seats, *item.Seat)
+			}
+			bySeat[*item.Seat] = append(bySeat[*item.Seat], item.OrderItemID)
+			seatValue[*item.Seat] += lineValue[item.OrderItemID]
+		}
+		if len(seats) == 0 {
+			return nil, splitError("no order item has a seat")
+		}
+		sort.Ints(seats)
+		splits := make([]models.InvoiceSplit, 0, len(seats))
+		for _, seat := range seats {
+			// scaled by the number of seats so that shared items divide evenly
+			weight := seatValue[seat]*int64(len(seats)) + shared
+			split := newSplit(fmt.Sprintf("Seat %d", seat), weight, bySeat[seat])
+			split.Seat = &[]int{seat}[0]
+			splits = append(splits, split)
+		}
+		return splits, nil
+	}
+	return nil, splitError(fmt.Sprintf("unknown split mode %q", request.Mode))
+}
+
+// shareSplits replaces the weights left in the split amounts by their share
+// of total.
+func shareSplits(total models.Money, splits []models.InvoiceSplit) {
+	weights := make([]int64, len(splits))
+	for i, split := range splits {
+		weights[i] = split.Amount.Minor
+	}
+	for i, share := range helpers.Allocate(total, weights) {
+		splits[i].Amount = share
+	}
+}
@@ -0,0 +1,392 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/payments"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type paymentRequest struct {
+	Method string        `json:"method" validate:"required,oneof=CARD CASH"`
+	Amount *models.Money `json:"amount" validate:"required"`
+	// Source is the gateway's token for the card; unused for cash.
+	Source string `json:"source"`
+	// Capture charges the card straight away. Without it the amount is only
+	// authorized and captured later with POST /payments/:payment_id/capture.
+	Capture *bool `json:"capture"`
+}
+
+func (r paymentRequest) capture() bool {
+	return r.Capture == nil || *r.Capture
+}
+
+var (
+	errSplitNotFound     = errors.New("split was not found on the invoice")
+	errKeyReused         = errors.New("the Idempotency-Key was already used for a different payment")
+	errKeyInFlight       = errors.New("a request with the same Idempotency-Key is in progress")
+	errNotCapturable     = errors.New("only authorized payments can be captured")
+	errCardsNotAccepted  = errors.New("card payments are not configured")
+	errGatewayDown       = errors.New("the payment gateway could not be reached; retry with the same Idempotency-Key")
+	errIdempotencyHeader = errors.New("an Idempotency-Key header is required")
+)
+
+// AddPayment takes a payment against one split of an invoice. Payments may be
+// partial and of different methods, but never more than the balance not yet
+// paid or held by another payment. Cash is recorded as captured; cards are
+// authorized and, unless capture is false, captured through the gateway.
+//
+// Every request carries an Idempotency-Key header. A request repeated with the
+// same key, for example after a timeout, answers with the payment the key was
+// first used for and resumes it where it stopped, never charging twice.
+func (ic *InvoiceController) AddPayment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var request paymentRequest
+
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errIdempotencyHeader.Error()})
+			return
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		amount, err := ic.pricing.Price(*request.Amount)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if request.Method == models.PaymentMethodCard && ic.gateway == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errCardsNotAccepted.Error()})
+			return
+		}
+
+		invoiceID, splitID := c.Param("invoice_id"), c.Param("split_id")
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		payment := models.Payment{
+			ID:        primitive.NewObjectID(),
+			InvoiceID: invoiceID,
+			SplitID:   splitID,
+			Method:    request.Method,
+			Amount:    amount,
+			Status:    models.PaymentPending,
+			// keys are per user, so two clients cannot collide on one
+			IdempotencyKey: c.GetString("uid") + ":" + key,
+			CreatedBy:      c.GetString("uid"),
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		payment.PaymentID = payment.ID.Hex()
+		if request.Method == models.PaymentMethodCard {
+			payment.Provider = ic.gateway.Name()
+		} else {
+			payment.Provider = "cash"
+			payment.Status = models.PaymentCaptured
+		}
+		claim := models.IdempotencyKey{
+			Key:         payment.IdempotencyKey,
+			Fingerprint: fmt.Sprintf("%s|%s|%s|%s %s|%s|%t", invoiceID, splitID, request.Method, amount, amount.Currency, request.Source, request.capture()),
+			PaymentID:   payment.PaymentID,
+			CreatedAt:   now,
+		}
+
+		err = ic.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			previous, err := ic.payments.FindKey(ctx, claim.Key)
+			if err == nil {
+				if previous.Fingerprint != claim.Fingerprint {
+					return errKeyReused
+				}
+				payment, err = ic.payments.FindByID(ctx, previous.PaymentID)
+				return err
+			}
+			if !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			// a concurrent request with the same key claims it first
+			err = ic.payments.ClaimKey(ctx, &claim)
+			if errors.Is(err, repository.ErrDuplicate) {
+				return errKeyInFlight
+			}
+			if err != nil {
+				return err
+			}
+			return ic.openPayment(ctx, &payment)
+		})
+		if err == nil {
+			err = ic.advancePayment(ctx, &payment, request.Source, request.capture())
+		}
+		ic.respondPayment(c, http.StatusCreated, payment, err)
+	}
+}
+
+// CapturePayment charges a card payment that was only authorized. Capturing a
+// payment that is already captured answers with it unchanged.
+func (ic *InvoiceController) CapturePayment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		payment, err := ic.payments.FindByID(ctx, c.Param("payment_id"))
+		if err == nil && payment.Status != models.PaymentAuthorized && payment.Status != models.PaymentCaptured {
+			err = errNotCapturable
+		}
+		if err == nil {
+			err = ic.advancePayment(ctx, &payment, "", true)
+		}
+		ic.respondPayment(c, http.StatusOK, payment, err)
+	}
+}
+
+func (ic *InvoiceController) GetPayment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		payment, err := ic.payments.FindByID(ctx, c.Param("payment_id"))
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "payment was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the payment"})
+			return
+		}
+		c.JSON(http.StatusOK, payment)
+	}
+}
+
+func (ic *InvoiceController) GetInvoicePayments() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		invoiceID := c.Param("invoice_id")
+
+		if _, err := ic.invoices.FindByID(ctx, invoiceID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing payments"})
+			return
+		}
+		invoicePayments, err := ic.payments.ListByInvoice(ctx, invoiceID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing payments"})
+			return
+		}
+		c.JSON(http.StatusOK, invoicePayments)
+	}
+}
+
+// openPayment checks a new payment against the split it pays and records it.
+// Cash is settled on the invoice at once; card payments hold their amount of
+// the balance until the gateway answers.
+func (ic *InvoiceController) openPayment(ctx context.Context, payment *models.Payment) error {
+	invoice, err := ic.invoices.FindByID(ctx, payment.InvoiceID)
+	if err != nil {
+		return err
+	}
+	if invoice.Voided != nil {
+		return errInvoiceVoided
+	}
+	if err := ic.openBill(ctx, &invoice); err != nil {
+		return err
+	}
+	split := findSplit(&invoice, payment.SplitID)
+	if split == nil {
+		return errSplitNotFound
+	}
+	if payment.Amount.Minor <= 0 {
+		return splitError("payment amount must be positive")
+	}
+
+	available := split.Balance
+	existing, err := ic.payments.ListByInvoice(ctx, invoice.InvoiceID)
+	if err != nil {
+		return err
+	}
+	for _, other := range existing {
+		if other.SplitID == split.SplitID && other.IsOpen() {
+			available = available.Sub(other.Amount)
+		}
+	}
+	if payment.Amount.Cmp(available) > 0 {
+		return splitError(fmt.Sprintf("payment of %s exceeds the balance of %s", payment.Amount, available))
+	}
+
+	if err := ic.payments.Create(ctx, payment); err != nil {
+		return err
+	}
+	if payment.Status == models.PaymentCaptured {
+		return ic.settlePayment(ctx, &invoice, *payment)
+	}
+	// writing the invoice makes concurrent payments against it conflict
+	// instead of both fitting in the same balance
+	invoice.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return ic.invoices.Update(ctx, &invoice)
+}
+
+// advancePayment moves a card payment through the gateway as far as it should
+// go: a pending payment is authorized and an authorized one captured when
+// capture is set. The gateway calls use keys derived from the payment, so a
+// payment resumed after a crash or a retry is never authorized or captured
+// twice.
+func (ic *InvoiceController) advancePayment(ctx context.Context, payment *models.Payment, source string, capture bool) error {
+	if payment.Method != models.PaymentMethodCard {
+		return nil
+	}
+	if ic.gateway == nil {
+		return errCardsNotAccepted
+	}
+
+	if payment.Status == models.PaymentPending {
+		result, err := ic.gateway.Authorize(ctx, payments.AuthorizeRequest{
+			Amount:         payment.Amount,
+			Source:         source,
+			Reference:      payment.InvoiceID + "/" + payment.SplitID,
+			IdempotencyKey: payment.PaymentID + ":authorize",
+		})
+		if err := ic.recordGatewayResult(ctx, payment, models.PaymentAuthorized, result, err); err != nil {
+			return err
+		}
+	}
+	if payment.Status == models.PaymentAuthorized && capture {
+		result, err := ic.gateway.Capture(ctx, payment.AuthorizationRef, payment.Amount, payment.PaymentID+":capture")
+		if err := ic.recordGatewayResult(ctx, payment, models.PaymentCaptured, result, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordGatewayResult stores what the gateway answered. A decline fails the
+// payment for good; any other error leaves it as it was so it can be resumed.
+// A capture is settled on the invoice in the same transaction, once only even
+// when two retries of the same payment race.
+func (ic *InvoiceController) recordGatewayResult(ctx context.Context, payment *models.Payment, status string, result payments.Result, gatewayErr error) error {
+	var declined *payments.DeclinedError
+	if gatewayErr != nil && !errors.As(gatewayErr, &declined) {
+		return fmt.Errorf("%w: %v", errGatewayDown, gatewayErr)
+	}
+
+	return ic.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		current, err := ic.payments.FindByID(ctx, payment.PaymentID)
+		if err != nil {
+			return err
+		}
+		if current.Status != payment.Status {
+			// another request got there first
+			*payment = current
+			return nil
+		}
+
+		current.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if declined != nil {
+			current.Status = models.PaymentFailed
+			current.DeclineCode = declined.Code
+		} else {
+			current.Status = status
+			if status == models.PaymentAuthorized {
+				current.AuthorizationRef = result.Ref
+			} else {
+				current.CaptureRef = result.Ref
+			}
+		}
+		if err := ic.payments.Update(ctx, &current); err != nil {
+			return err
+		}
+		if current.Status == models.PaymentCaptured {
+			invoice, err := ic.invoices.FindByID(ctx, current.InvoiceID)
+			if err != nil {
+				return err
+			}
+			if err := ic.settlePayment(ctx, &invoice, current); err != nil {
+				return err
+			}
+		}
+		*payment = current
+		return nil
+	})
+}
+
+// settlePayment adds a captured payment to its split of the invoice. Paying
+// the invoice in full leaves the table of its order to be cleaned.
+func (ic *InvoiceController) settlePayment(ctx context.Context, invoice *models.Invoice, payment models.Payment) error {
+	split := findSplit(invoice, payment.SplitID)
+	if split == nil {
+		return errSplitNotFound
+	}
+	split.Payments = append(split.Payments, models.InvoicePayment{
+		PaymentID:  payment.PaymentID,
+		Method:     payment.Method,
+		Amount:     payment.Amount,
+		ReceivedBy: payment.CreatedBy,
+		CreatedAt:  payment.UpdatedAt,
+	})
+	invoice.Settle()
+	invoice.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	if err := ic.invoices.Update(ctx, invoice); err != nil {
+		return err
+	}
+	if *invoice.PaymentStatus != models.PaymentStatusPaid {
+		return nil
+	}
+	// a party that has paid is about to leave its table
+	order, err := ic.orders.FindByID(ctx, invoice.OrderID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && order.TableID == nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return releaseOrder(ctx, ic.tables, *order.TableID, order.OrderID, models.TableStatusDirty, invoice.UpdatedAt)
+}
+
+// respondPayment answers with the payment and the invoice it pays, or with
+// the error that stopped it. A declined card is reported as 402 along with
+// the failed payment.
+func (ic *InvoiceController) respondPayment(c *gin.Context, status int, payment models.Payment, err error) {
+	var splitErr splitError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "invoice or payment was not found"})
+	case errors.Is(err, errSplitNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errKeyReused), errors.Is(err, errKeyInFlight), errors.Is(err, errNotCapturable), errors.Is(err, errInvoiceVoided):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errGatewayDown):
+		c.JSON(http.StatusBadGateway, gin.H{"error": errGatewayDown.Error(), "payment": payment})
+	case errors.As(err, &splitErr), errors.Is(err, errCardsNotAccepted):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "payment was not recorded"})
+	case payment.Status == models.PaymentFailed:
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": "the card was declined: " + payment.DeclineCode, "payment": payment})
+	default:
+		invoice, err := ic.invoices.FindByID(c.Request.Context(), payment.InvoiceID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the invoice"})
+			return
+		}
+		c.JSON(status, gin.H{"payment": payment, "invoice": invoice})
+	}
+}
+
+func findSplit(invoice *models.Invoice, splitID string) *models.InvoiceSplit {
+	for s := range invoice.Splits {
+		if invoice.Splits[s].SplitID == splitID {
+			return &invoice.Splits[s]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Micah-Shallom/modules/helpers"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/payments"
+	"github.com/Micah-Shallom/modules/receipts"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// racedKeys hides claimed idempotency keys from FindKey, as a request does
+// that checks the key just before a concurrent one with the same key claims it.
+type racedKeys struct {
+	repository.PaymentRepository
+}
+
+func (r racedKeys) FindKey(ctx context.Context, key string) (models.IdempotencyKey, error) {
+	return models.IdempotencyKey{}, repository.ErrNotFound
+}
+
+// newPaymentTest stores an invoice of 50.00 on a single bill and returns the
+// repositories and a router taking payments against it as a cashier.
+func newPaymentTest(t *testing.T, wrap func(repository.PaymentRepository) repository.PaymentRepository) (*repository.Repositories, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+
+	total := models.NewMoney(5000, "NGN")
+	invoice := models.Invoice{
+		InvoiceID: "invoice1",
+		OrderID:   "order1",
+		Total:     &total,
+		SplitMode: models.SplitFull,
+		Splits: []models.InvoiceSplit{{
+			SplitID:      "split1",
+			Label:        "Full bill",
+			OrderItemIDs: []string{},
+			Amount:       total,
+			Payments:     []models.InvoicePayment{},
+		}},
+	}
+	invoice.Settle()
+	if err := repos.Invoices.Create(context.Background(), &invoice); err != nil {
+		t.Fatalf("Create invoice: %v", err)
+	}
+
+	paymentRecords := repos.Payments
+	if wrap != nil {
+		paymentRecords = wrap(paymentRecords)
+	}
+	ic := NewInvoiceController(repos.Invoices, repos.Orders, repos.OrderItems, repos.Tables, paymentRecords, repos.CreditNotes, repos.Counters,
+		repos.Transactions, payments.NewFakeGateway(), helpers.Pricing{Currency: "NGN"}, helpers.InvoiceNumbering{}, receipts.Header{}, nil)
+
+	router := gin.New()
+	router.POST("/invoices/:invoice_id/splits/:split_id/payments", func(c *gin.Context) {
+		c.Set("uid", "cashier1")
+		c.Set("userType", models.RoleCashier)
+	}, ic.AddPayment())
+	return repos, router
+}
+
+func postPayment(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/invoices/invoice1/splits/split1/payments", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		request.Header.Set("Idempotency-Key", key)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func paymentID(t *testing.T, recorder *httptest.ResponseRecorder) string {
+	t.Helper()
+	var response struct {
+		Payment models.Payment `json:"payment"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding %s: %v", recorder.Body, err)
+	}
+	return response.Payment.PaymentID
+}
+
+func TestAddPaymentRetriedWithSameKey(t *testing.T) {
+	repos, router := newPaymentTest(t, nil)
+	body := `{"method": "CARD", "amount": "20.00", "source": "tok_visa"}`
+
+	first := postPayment(router, "key1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: got %d %s, want 201", first.Code, first.Body)
+	}
+	retried := postPayment(router, "key1", body)
+	if retried.Code != http.StatusCreated {
+		t.Fatalf("retried request: got %d %s, want 201", retried.Code, retried.Body)
+	}
+	if paymentID(t, retried) != paymentID(t, first) {
+		t.Errorf("retried request answered with payment %s, want %s", paymentID(t, retried), paymentID(t, first))
+	}
+
+	ctx := context.Background()
+	recorded, err := repos.Payments.ListByInvoice(ctx, "invoice1")
+	if err != nil {
+		t.Fatalf("ListByInvoice: %v", err)
+	}
+	if len(recorded) != 1 || recorded[0].Status != models.PaymentCaptured {
+		t.Errorf("payments: got %+v, want one captured payment", recorded)
+	}
+	invoice, err := repos.Invoices.FindByID(ctx, "invoice1")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if invoice.Paid.Minor != 2000 || invoice.Balance.Minor != 3000 {
+		t.Errorf("invoice paid %v with %v left, want 20.00 paid and 30.00 left", invoice.Paid, invoice.Balance)
+	}
+}
+
+func TestAddPaymentKeyReusedForAnotherPayment(t *testing.T) {
+	repos, router := newPaymentTest(t, nil)
+
+	if recorder := postPayment(router, "key1", `{"method": "CASH", "amount": "20.00"}`); recorder.Code != http.StatusCreated {
+		t.Fatalf("first request: got %d %s, want 201", recorder.Code, recorder.Body)
+	}
+	recorder := postPayment(router, "key1", `{"method": "CASH", "amount": "25.00"}`)
+	if recorder.Code != http.StatusConflict || !strings.Contains(recorder.Body.String(), errKeyReused.Error()) {
+		t.Errorf("request reusing the key: got %d %s, want 409 for a reused key", recorder.Code, recorder.Body)
+	}
+
+	recorded, err := repos.Payments.ListByInvoice(context.Background(), "invoice1")
+	if err != nil {
+		t.Fatalf("ListByInvoice: %v", err)
+	}
+	if len(recorded) != 1 {
+		t.Errorf("payments: got %d, want 1", len(recorded))
+	}
+}
+
+func TestAddPaymentKeyClaimedConcurrently(t *testing.T) {
+	repos, router := newPaymentTest(t, func(records repository.PaymentRepository) repository.PaymentRepository {
+		return racedKeys{records}
+	})
+	ctx := context.Background()
+	// the concurrent request has claimed the key but not yet recorded its
+	// payment
+	claim := models.IdempotencyKey{Key: "cashier1:key1", PaymentID: "other"}
+	if err := repos.Payments.ClaimKey(ctx, &claim); err != nil {
+		t.Fatalf("ClaimKey: %v", err)
+	}
+
+	recorder := postPayment(router, "key1", `{"method": "CASH", "amount": "20.00"}`)
+	if recorder.Code != http.StatusConflict || !strings.Contains(recorder.Body.String(), errKeyInFlight.Error()) {
+		t.Errorf("request with a claimed key: got %d %s, want 409 for a request in progress", recorder.Code, recorder.Body)
+	}
+	recorded, err := repos.Payments.ListByInvoice(ctx, "invoice1")
+	if err != nil {
+		t.Fatalf("ListByInvoice: %v", err)
+	}
+	if len(recorded) != 0 {
+		t.Errorf("payments: got %d, want none", len(recorded))
+	}
+}
+
+func TestAddPaymentDeclined(t *testing.T) {
+	repos, router := newPaymentTest(t, nil)
+
+	recorder := postPayment(router, "key1", `{"method": "CARD", "amount": "20.00", "source": "`+payments.FakeSourceDeclined+`"}`)
+	if recorder.Code != http.StatusPaymentRequired {
+		t.Fatalf("declined card: got %d %s, want 402", recorder.Code, recorder.Body)
+	}
+	invoice, err := repos.Invoices.FindByID(context.Background(), "invoice1")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if invoice.Paid.Minor != 0 {
+		t.Errorf("invoice paid %v after a decline, want nothing", invoice.Paid)
+	}
+	if recorder := postPayment(router, "", `{"method": "CASH", "amount": "20.00"}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("request without a key: got %d, want 400", recorder.Code)
+	}
+}
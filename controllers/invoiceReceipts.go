@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/printing"
+	"github.com/Micah-Shallom/modules/receipts"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// GetReceipt renders the customer receipt of an invoice. format picks text
+// (the default), html or pdf; text is 42 columns wide unless width=80.
+func (ic *InvoiceController) GetReceipt() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		format := c.DefaultQuery("format", "text")
+		width, err := strconv.Atoi(c.DefaultQuery("width", strconv.Itoa(receipts.NarrowWidth)))
+		if err != nil || (width != receipts.NarrowWidth && width != receipts.WideWidth) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "width must be 42 or 80"})
+			return
+		}
+		if format != "text" && format != "html" && format != "pdf" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be text, html or pdf"})
+			return
+		}
+
+		invoice, err := ic.invoices.FindByID(ctx, c.Param("invoice_id"))
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while getting invoice item"})
+			return
+		}
+		receipt, err := ic.receipt(ctx, invoice)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while assembling the receipt"})
+			return
+		}
+
+		var body bytes.Buffer
+		switch format {
+		case "text":
+			body.WriteString(receipts.Text(receipt, width))
+			c.Data(http.StatusOK, "text/plain; charset=utf-8", body.Bytes())
+			return
+		case "html":
+			err = receipts.HTML(&body, receipt)
+		case "pdf":
+			err = receipts.PDF(&body, receipt)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while rendering the receipt"})
+			return
+		}
+		if format == "html" {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+			return
+		}
+		c.Header("Content-Disposition", `inline; filename="`+receipt.InvoiceNumber+`.pdf"`)
+		c.Data(http.StatusOK, "application/pdf", body.Bytes())
+	}
+}
+
+// receipt assembles the receipt of invoice from its order lines, totals and
+// payments.
+func (ic *InvoiceController) receipt(ctx context.Context, invoice models.Invoice) (receipts.Receipt, error) {
+	// legacy invoices get their total computed, but not stored, here
+	if err := ic.openBill(ctx, &invoice); err != nil {
+		return receipts.Receipt{}, err
+	}
+	receipt := receipts.Receipt{
+		Header:        ic.header,
+		InvoiceNumber: invoice.Number(),
+		IssuedAt:      invoice.CreatedAt.In(ic.numbering.TimeZone),
+		Lines:         []receipts.Line{},
+		Total:         *invoice.Total,
+		Payments:      []receipts.Payment{},
+		Paid:          *invoice.Paid,
+		Balance:       *invoice.Balance,
+		Status:        *invoice.PaymentStatus,
+	}
+	if invoice.Refunded != nil {
+		receipt.Refunded = *invoice.Refunded
+	}
+
+	order, err := ic.orders.FindByID(ctx, invoice.OrderID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return receipt, err
+	}
+	receipt.Totals = order.Totals
+
+	summaries, err := ic.orderItems.ItemsByOrder(ctx, invoice.OrderID)
+	if err != nil {
+		return receipt, err
+	}
+	for _, summary := range summaries {
+		receipt.TableNumber = summary.TableNumber
+		for _, item := range summary.OrderItems {
+			line := receipts.Line{Name: "Item", Units: item.Quantity}
+			if item.FoodName != nil {
+				line.Name = *item.FoodName
+			}
+			if item.Price != nil {
+				line.UnitPrice = item.Price.WithCurrency(ic.pricing.Currency)
+			}
+			if item.Amount != nil {
+				line.Amount = item.Amount.WithCurrency(ic.pricing.Currency)
+			}
+			for _, modifier := range item.Modifiers {
+				line.Modifiers = append(line.Modifiers, receipts.Modifier{
+					Name:       modifier.OptionName,
+					PriceDelta: modifier.PriceDelta.WithCurrency(ic.pricing.Currency),
+				})
+			}
+			receipt.Lines = append(receipt.Lines, line)
+		}
+	}
+
+	for _, split := range invoice.Splits {
+		for _, payment := range split.Payments {
+			line := receipts.Payment{Method: payment.Method, Amount: payment.Amount, Refunded: payment.Refunded}
+			if len(invoice.Splits) > 1 {
+				line.Label = split.Label
+			}
+			receipt.Payments = append(receipt.Payments, line)
+		}
+	}
+	return receipt, nil
+}
+
+// PrintReceipt sends the customer receipt of an invoice to the receipt
+// printer, 42 columns wide unless width=80. The receipt prints in the
+// background; the job returned can be followed at GET /printJobs/:job_id.
+func (ic *InvoiceController) PrintReceipt() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		width, err := strconv.Atoi(c.DefaultQuery("width", strconv.Itoa(receipts.NarrowWidth)))
+		if err != nil || (width != receipts.NarrowWidth && width != receipts.WideWidth) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "width must be 42 or 80"})
+			return
+		}
+
+		invoice, err := ic.invoices.FindByID(ctx, c.Param("invoice_id"))
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while getting invoice item"})
+			return
+		}
+		receipt, err := ic.receipt(ctx, invoice)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while assembling the receipt"})
+			return
+		}
+
+		job, err := ic.printer.Submit(printing.PrinterReceipt, printing.JobReceipt, invoice.InvoiceID, printing.Receipt(receipt, width))
+		if err != nil {
+			respondPrintError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+	}
+}
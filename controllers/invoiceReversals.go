@@ -0,0 +1,440 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/payments"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type refundRequest struct {
+	// Amount defaults to everything not yet refunded.
+	Amount *models.Money `json:"amount"`
+	models.Reversal
+}
+
+var (
+	errNotRefundable     = errors.New("only captured payments can be refunded")
+	errInvoiceVoided     = errors.New("the invoice is voided")
+	errInvoicePaid       = errors.New("the invoice has payments that were not refunded; refund them before voiding it")
+	errPaymentInProgress = errors.New("a payment against the invoice is still in progress")
+)
+
+// RefundPayment gives back all or part of a captured payment: card payments
+// through the gateway that took them, cash over the counter. Every completed
+// refund issues a credit note against the invoice.
+//
+// Like payments, refunds need an Idempotency-Key header, and a request
+// repeated with the same key never refunds twice.
+func (ic *InvoiceController) RefundPayment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var request refundRequest
+
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errIdempotencyHeader.Error()})
+			return
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var amount *models.Money
+		if request.Amount != nil {
+			priced, err := ic.pricing.Price(*request.Amount)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			amount = &priced
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		reason := request.Reversal
+		reason.By, reason.At = c.GetString("uid"), now
+		paymentID := c.Param("payment_id")
+		claim := models.IdempotencyKey{
+			Key:         c.GetString("uid") + ":" + key,
+			Fingerprint: fmt.Sprintf("refund|%s|%v|%s", paymentID, amount, reason.ReasonCode),
+			PaymentID:   paymentID,
+			RefundID:    primitive.NewObjectID().Hex(),
+			CreatedAt:   now,
+		}
+
+		var payment models.Payment
+		err := ic.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			previous, err := ic.payments.FindKey(ctx, claim.Key)
+			if err == nil {
+				if previous.Fingerprint != claim.Fingerprint {
+					return errKeyReused
+				}
+				claim = previous
+				payment, err = ic.payments.FindByID(ctx, previous.PaymentID)
+				return err
+			}
+			if !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			err = ic.payments.ClaimKey(ctx, &claim)
+			if errors.Is(err, repository.ErrDuplicate) {
+				return errKeyInFlight
+			}
+			if err != nil {
+				return err
+			}
+
+			payment, err = ic.payments.FindByID(ctx, paymentID)
+			if err != nil {
+				return err
+			}
+			if !payment.IsCaptured() {
+				return errNotRefundable
+			}
+			refundable := payment.Refundable()
+			if amount == nil {
+				amount = &refundable
+			}
+			if amount.Minor <= 0 || amount.Cmp(refundable) > 0 {
+				return splitError(fmt.Sprintf("refund of %s must be positive and at most the %s left on the payment", amount, refundable))
+			}
+			payment.Refunds = append(payment.Refunds, models.PaymentRefund{
+				RefundID: claim.RefundID,
+				Amount:   *amount,
+				Status:   models.RefundPending,
+				Reason:   reason,
+			})
+			if payment.Method == models.PaymentMethodCash {
+				return ic.completeRefund(ctx, &payment, claim.RefundID, "")
+			}
+			payment.UpdatedAt = now
+			return ic.payments.Update(ctx, &payment)
+		})
+		if err == nil {
+			err = ic.advanceRefund(ctx, &payment, claim.RefundID)
+		}
+		ic.respondRefund(c, payment, claim.RefundID, err)
+	}
+}
+
+// VoidInvoice cancels an invoice that holds no money: nothing was paid, or
+// everything paid was refunded. Card authorizations not yet captured are
+// released, and a credit note cancels the part of the invoice total that
+// refunds did not already credit. Voiding a voided invoice answers with it
+// unchanged.
+func (ic *InvoiceController) VoidInvoice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var reason models.Reversal
+
+		if err := c.ShouldBindJSON(&reason); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(&reason); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		reason.By = c.GetString("uid")
+		reason.At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		invoiceID := c.Param("invoice_id")
+		invoice, err := ic.invoices.FindByID(ctx, invoiceID)
+		if err == nil && invoice.Voided == nil {
+			err = ic.releaseAuthorizations(ctx, invoiceID)
+		}
+		if err == nil && invoice.Voided == nil {
+			err = ic.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+				return ic.voidInvoice(ctx, &invoice, reason)
+			})
+		}
+
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+		case errors.Is(err, errInvoicePaid), errors.Is(err, errPaymentInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, errGatewayDown):
+			c.JSON(http.StatusBadGateway, gin.H{"error": errGatewayDown.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "invoice was not voided"})
+		default:
+			c.JSON(http.StatusOK, invoice)
+		}
+	}
+}
+
+func (ic *InvoiceController) GetCreditNotes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		creditNotes, err := ic.creditNotes.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing credit notes"})
+			return
+		}
+		c.JSON(http.StatusOK, creditNotes)
+	}
+}
+
+func (ic *InvoiceController) GetCreditNote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		creditNote, err := ic.creditNotes.FindByID(ctx, c.Param("credit_note_id"))
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "credit note was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the credit note"})
+			return
+		}
+		c.JSON(http.StatusOK, creditNote)
+	}
+}
+
+func (ic *InvoiceController) GetInvoiceCreditNotes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		creditNotes, err := ic.creditNotes.ListByInvoice(ctx, c.Param("invoice_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing credit notes"})
+			return
+		}
+		c.JSON(http.StatusOK, creditNotes)
+	}
+}
+
+// advanceRefund sends a pending card refund to the gateway, keyed by the
+// refund so that a retry is answered with the first outcome.
+func (ic *InvoiceController) advanceRefund(ctx context.Context, payment *models.Payment, refundID string) error {
+	refund := findRefund(payment, refundID)
+	if refund == nil || refund.Status != models.RefundPending {
+		return nil
+	}
+	if ic.gateway == nil {
+		return errCardsNotAccepted
+	}
+
+	result, err := ic.gateway.Refund(ctx, payment.CaptureRef, refund.Amount, refundID)
+	var declined *payments.DeclinedError
+	if err != nil && !errors.As(err, &declined) {
+		return fmt.Errorf("%w: %v", errGatewayDown, err)
+	}
+
+	return ic.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		current, err := ic.payments.FindByID(ctx, payment.PaymentID)
+		if err != nil {
+			return err
+		}
+		*payment = current
+		refund := findRefund(payment, refundID)
+		if refund == nil || refund.Status != models.RefundPending {
+			// another request got there first
+			return nil
+		}
+		if declined != nil {
+			refund.Status = models.RefundFailed
+			refund.DeclineCode = declined.Code
+			payment.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			return ic.payments.Update(ctx, payment)
+		}
+		return ic.completeRefund(ctx, payment, refundID, result.Ref)
+	})
+}
+
+// completeRefund marks a refund as done, reports it on the invoice and issues
+// its credit note.
+func (ic *InvoiceController) completeRefund(ctx context.Context, payment *models.Payment, refundID, ref string) error {
+	refund := findRefund(payment, refundID)
+	refund.Status = models.RefundCompleted
+	refund.Ref = ref
+	payment.SettleRefunds()
+	payment.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	if err := ic.payments.Update(ctx, payment); err != nil {
+		return err
+	}
+
+	invoice, err := ic.invoices.FindByID(ctx, payment.InvoiceID)
+	if err != nil {
+		return err
+	}
+	if split := findSplit(&invoice, payment.SplitID); split != nil {
+		for p := range split.Payments {
+			if split.Payments[p].PaymentID == payment.PaymentID {
+				split.Payments[p].Refunded = payment.Refunded
+			}
+		}
+	}
+	invoice.Settle()
+	invoice.UpdatedAt = payment.UpdatedAt
+	if err := ic.invoices.Update(ctx, &invoice); err != nil {
+		return err
+	}
+
+	return ic.issueCreditNote(ctx, invoice, models.CreditNote{
+		Kind:      models.CreditNoteRefund,
+		PaymentID: payment.PaymentID,
+		RefundID:  refundID,
+		Amount:    refund.Amount,
+	}, refund.Reason)
+}
+
+// releaseAuthorizations voids, at the gateway, the card authorizations taken
+// against the invoice that were never captured. The gateway calls are keyed
+// by payment, so releasing twice is harmless.
+func (ic *InvoiceController) releaseAuthorizations(ctx context.Context, invoiceID string) error {
+	invoicePayments, err := ic.payments.ListByInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	for _, payment := range invoicePayments {
+		switch payment.Status {
+		case models.PaymentPending:
+			return errPaymentInProgress
+		case models.PaymentAuthorized:
+			if ic.gateway == nil {
+				return errCardsNotAccepted
+			}
+			_, err := ic.gateway.Void(ctx, payment.AuthorizationRef, payment.PaymentID+":void")
+			var declined *payments.DeclinedError
+			if errors.As(err, &declined) {
+				// captured in the meantime, so the invoice is paid
+				return errInvoicePaid
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %v", errGatewayDown, err)
+			}
+		}
+	}
+	return nil
+}
+
+// voidInvoice voids the invoice and the authorizations released for it,
+// unless a payment came in since they were released.
+func (ic *InvoiceController) voidInvoice(ctx context.Context, invoice *models.Invoice, reason models.Reversal) error {
+	current, err := ic.invoices.FindByID(ctx, invoice.InvoiceID)
+	if err != nil {
+		return err
+	}
+	*invoice = current
+	if invoice.Voided != nil {
+		return nil
+	}
+	if err := ic.openBill(ctx, invoice); err != nil {
+		return err
+	}
+	if invoice.HasUnrefundedPayments() {
+		return errInvoicePaid
+	}
+
+	invoicePayments, err := ic.payments.ListByInvoice(ctx, invoice.InvoiceID)
+	if err != nil {
+		return err
+	}
+	for _, payment := range invoicePayments {
+		switch payment.Status {
+		case models.PaymentPending:
+			return errPaymentInProgress
+		case models.PaymentAuthorized:
+			payment.Status = models.PaymentVoided
+			payment.Voided = &reason
+			payment.UpdatedAt = reason.At
+			if err := ic.payments.Update(ctx, &payment); err != nil {
+				return err
+			}
+		}
+	}
+
+	invoice.Voided = &reason
+	invoice.Settle()
+	invoice.UpdatedAt = reason.At
+	if err := ic.invoices.Update(ctx, invoice); err != nil {
+		return err
+	}
+	// refunds already credited what was paid
+	left := invoice.Total.Sub(*invoice.Refunded)
+	if left.Minor <= 0 {
+		return nil
+	}
+	return ic.issueCreditNote(ctx, *invoice, models.CreditNote{
+		Kind:   models.CreditNoteVoid,
+		Amount: left,
+	}, reason)
+}
+
+func (ic *InvoiceController) issueCreditNote(ctx context.Context, invoice models.Invoice, creditNote models.CreditNote, reason models.Reversal) error {
+	creditNote.ID = primitive.NewObjectID()
+	creditNote.CreditNoteID = creditNote.ID.Hex()
+	creditNote.InvoiceID = invoice.InvoiceID
+	creditNote.InvoiceNumber = invoice.Number()
+	creditNote.ReasonCode = reason.ReasonCode
+	creditNote.Note = reason.Note
+	creditNote.IssuedBy = reason.By
+	creditNote.CreatedAt = reason.At
+	return ic.creditNotes.Create(ctx, &creditNote)
+}
+
+// respondRefund answers with the payment, the refund and its credit note, or
+// with the error that stopped it. A refund the gateway declined is reported
+// as 402.
+func (ic *InvoiceController) respondRefund(c *gin.Context, payment models.Payment, refundID string, err error) {
+	var splitErr splitError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "payment was not found"})
+		return
+	case errors.Is(err, errKeyReused), errors.Is(err, errKeyInFlight), errors.Is(err, errNotRefundable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errGatewayDown):
+		c.JSON(http.StatusBadGateway, gin.H{"error": errGatewayDown.Error(), "payment": payment})
+		return
+	case errors.As(err, &splitErr), errors.Is(err, errCardsNotAccepted):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "refund was not recorded"})
+		return
+	}
+
+	refund := findRefund(&payment, refundID)
+	if refund != nil && refund.Status == models.RefundFailed {
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": "the refund was declined: " + refund.DeclineCode, "payment": payment})
+		return
+	}
+	var creditNote *models.CreditNote
+	creditNotes, err := ic.creditNotes.ListByInvoice(c.Request.Context(), payment.InvoiceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the credit note"})
+		return
+	}
+	for i := range creditNotes {
+		if creditNotes[i].RefundID == refundID {
+			creditNote = &creditNotes[i]
+		}
+	}
+	c.JSON(http.StatusCreated, gin.H{"payment": payment, "refund": refund, "credit_note": creditNote})
+}
+
+func findRefund(payment *models.Payment, refundID string) *models.PaymentRefund {
+	for r := range payment.Refunds {
+		if payment.Refunds[r].RefundID == refundID {
+			return &payment.Refunds[r]
+		}
+	}
+	return nil
+}
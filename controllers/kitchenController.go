@@ -0,0 +1,290 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Micah-Shallom/modules/kds"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recallable is how many of the last bumped tickets of a station are offered
+// for recall.
+const recallable = 50
+
+// keepAlive is how often an idle stream sends a comment, so that proxies do not
+// close it.
+const keepAlive = 15 * time.Second
+
+var errTicketStatus = errors.New("ticket is not in the expected status")
+
+type KitchenController struct {
+	stations     repository.StationRepository
+	tickets      repository.KitchenTicketRepository
+	transactions repository.Transactor
+	kitchen      *kds.Hub
+}
+
+func NewKitchenController(stations repository.StationRepository, tickets repository.KitchenTicketRepository, transactions repository.Transactor, kitchen *kds.Hub) *KitchenController {
+	return &KitchenController{stations: stations, tickets: tickets, transactions: transactions, kitchen: kitchen}
+}
+
+// stationUpdate is the body of PATCH /stations/:station_id; fields left out
+// are not changed.
+type stationUpdate struct {
+	Name       *string   `json:"name" validate:"omitempty,min=2,max=50"`
+	Categories *[]string `json:"categories" validate:"omitempty,dive,required"`
+	Default    *bool     `json:"default"`
+}
+
+func (kc *KitchenController) GetStations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		stations, err := kc.stations.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing stations"})
+			return
+		}
+		c.JSON(http.StatusOK, stations)
+	}
+}
+
+func (kc *KitchenController) GetStation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		station, ok := kc.findStation(c)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, station)
+	}
+}
+
+// CreateStation adds a station. Dishes of the menu categories it lists are
+// routed to it from the next order on.
+func (kc *KitchenController) CreateStation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var station models.Station
+
+		if err := c.ShouldBindJSON(&station); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if station.Categories == nil {
+			station.Categories = []string{}
+		}
+		if err := validate.Struct(station); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		station.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		station.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		station.ID = primitive.NewObjectID()
+		station.StationID = station.ID.Hex()
+
+		if err := kc.stations.Create(ctx, &station); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "station was not created"})
+			return
+		}
+		c.JSON(http.StatusOK, station)
+	}
+}
+
+// UpdateStation renames a station or changes what it prepares. Tickets already
+// on a station stay there; new routing applies to lines ordered or edited
+// afterwards.
+func (kc *KitchenController) UpdateStation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var update stationUpdate
+
+		if err := c.ShouldBindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		station, ok := kc.findStation(c)
+		if !ok {
+			return
+		}
+		if update.Name != nil {
+			station.Name = *update.Name
+		}
+		if update.Categories != nil {
+			station.Categories = *update.Categories
+		}
+		if update.Default != nil {
+			station.Default = *update.Default
+		}
+		station.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := kc.stations.Update(ctx, &station); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "station update failed"})
+			return
+		}
+		c.JSON(http.StatusOK, station)
+	}
+}
+
+// GetStationTickets lists the queue of a station, oldest first, or with
+// status=BUMPED the tickets bumped last, which can be recalled.
+func (kc *KitchenController) GetStationTickets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		status := c.DefaultQuery("status", models.TicketStatusNew)
+		if status != models.TicketStatusNew && status != models.TicketStatusBumped {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be NEW or BUMPED"})
+			return
+		}
+		station, ok := kc.findStation(c)
+		if !ok {
+			return
+		}
+
+		var tickets []models.KitchenTicket
+		var err error
+		if status == models.TicketStatusNew {
+			tickets, err = kc.tickets.Queue(ctx, station.StationID)
+		} else {
+			tickets, err = kc.tickets.RecentlyBumped(ctx, station.StationID, recallable)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing tickets"})
+			return
+		}
+		c.JSON(http.StatusOK, tickets)
+	}
+}
+
+// BumpTicket takes a finished ticket off the queue of its station.
+func (kc *KitchenController) BumpTicket() gin.HandlerFunc {
+	return kc.moveTicket(models.TicketStatusNew, func(ticket *models.KitchenTicket, by string, at time.Time) {
+		ticket.Status = models.TicketStatusBumped
+		ticket.BumpedBy = by
+		ticket.BumpedAt = &at
+	})
+}
+
+// RecallTicket puts a bumped ticket back into the queue of its station.
+func (kc *KitchenController) RecallTicket() gin.HandlerFunc {
+	return kc.moveTicket(models.TicketStatusBumped, func(ticket *models.KitchenTicket, by string, at time.Time) {
+		ticket.Status = models.TicketStatusNew
+		ticket.BumpedBy, ticket.BumpedAt = "", nil
+		ticket.RecalledAt = &at
+	})
+}
+
+// moveTicket applies change to a ticket in status from and pushes the result
+// to the displays of its station.
+func (kc *KitchenController) moveTicket(from string, change func(ticket *models.KitchenTicket, by string, at time.Time)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		ticketID := c.Param("ticket_id")
+
+		var ticket models.KitchenTicket
+		err := kc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			ticket, err = kc.tickets.FindByID(ctx, ticketID)
+			if err != nil {
+				return err
+			}
+			if ticket.Status != from {
+				return errTicketStatus
+			}
+			now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			change(&ticket, c.GetString("uid"), now)
+			ticket.UpdatedAt = now
+			return kc.tickets.Update(ctx, &ticket)
+		})
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "ticket was not found"})
+			return
+		case errors.Is(err, errTicketStatus):
+			c.JSON(http.StatusConflict, gin.H{"error": "ticket is " + ticket.Status + ", not " + from})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ticket update failed"})
+			return
+		}
+		kc.kitchen.Publish(kds.Event{Type: kds.TicketUpdated, Ticket: ticket})
+		c.JSON(http.StatusOK, ticket)
+	}
+}
+
+// StreamStation pushes the tickets of a station as Server-Sent Events. The
+// stream opens with a snapshot event holding the current queue, followed by
+// ticket.created, ticket.updated and ticket.removed events carrying one
+// ticket each. When the stream ends the client reconnects and starts over
+// from a new snapshot.
+func (kc *KitchenController) StreamStation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		station, ok := kc.findStation(c)
+		if !ok {
+			return
+		}
+		// subscribed before the snapshot is read so that no change falls in
+		// between; the client applies events to the snapshot by ticket_id
+		events, unsubscribe := kc.kitchen.Subscribe(station.StationID)
+		defer unsubscribe()
+		queue, err := kc.tickets.Queue(ctx, station.StationID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing tickets"})
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.SSEvent("snapshot", queue)
+		c.Writer.Flush()
+
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return false
+				}
+				c.SSEvent(event.Type, event.Ticket)
+				return true
+			case <-ticker.C:
+				_, err := io.WriteString(w, ": keep-alive\n\n")
+				return err == nil
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}
+}
+
+// findStation loads the station of the request, answering it when there is
+// none.
+func (kc *KitchenController) findStation(c *gin.Context) (models.Station, bool) {
+	station, err := kc.stations.FindByID(c.Request.Context(), c.Param("station_id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "station was not found"})
+		return station, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the station"})
+		return station, false
+	}
+	return station, true
+}
@@ -1,72 +1,103 @@
 package controllers
 
 import (
-	"context"
-	"fmt"
-	"log"
+	"errors"
 	"net/http"
 	"time"
 
-	"github.com/Micah-Shallom/modules/database"
 	"github.com/Micah-Shallom/modules/helpers"
 	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var menuCollection *mongo.Collection = database.OpenCollection(database.Client, "menu")
+type MenuController struct {
+	menus    repository.MenuRepository
+	schedule helpers.MenuSchedule
+}
+
+func NewMenuController(menus repository.MenuRepository, schedule helpers.MenuSchedule) *MenuController {
+	return &MenuController{menus: menus, schedule: schedule}
+}
 
-func GetMenus() gin.HandlerFunc{
+func (mc *MenuController) GetMenus() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(),100*time.Second)
-		result, err := menuCollection.Find(context.TODO(), bson.M{})
-		defer cancel()
+		ctx := c.Request.Context()
 
+		allMenus, err := mc.menus.List(ctx)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":"error occured while listing all items"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing all items"})
 			return
 		}
+		c.JSON(http.StatusOK, allMenus)
+	}
+}
 
-		var allMenus []bson.M
-		if err := result.All(ctx, &allMenus); err != nil {
-			log.Fatal(err)
+// GetActiveMenus lists the menus served now, or at ?at= given as a time such
+// as 2026-10-20T08:30:00+01:00.
+func (mc *MenuController) GetActiveMenus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		at := time.Now()
+		if value := c.Query("at"); value != "" {
+			var err error
+			if at, err = time.Parse(time.RFC3339, value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "at must be a time such as 2026-10-20T08:30:00+01:00"})
+				return
+			}
 		}
-		c.JSON(http.StatusOK, result)
+		allMenus, err := mc.menus.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing all items"})
+			return
+		}
+		active := []models.Menu{}
+		for _, menu := range allMenus {
+			if mc.schedule.Served(menu, at) {
+				active = append(active, menu)
+			}
+		}
+		c.JSON(http.StatusOK, active)
 	}
 }
 
-func GetMenu() gin.HandlerFunc{
+func (mc *MenuController) GetMenu() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		ctx := c.Request.Context()
 		menuID := c.Param("menu_id")
-		var menu models.Menu
 
-		err := foodCollection.FindOne(ctx, bson.M{"menu_id": menuID}).Decode(&menu)
-		defer cancel()
+		menu, err := mc.menus.FindByID(ctx, menuID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "menu was not found"})
+			return
+		}
 		if err != nil {
-			msg := fmt.Sprintf("error occured while fetching the menu")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-			return 
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the menu"})
+			return
 		}
+		c.JSON(http.StatusOK, menu)
 	}
 }
 
-func CreateMenu() gin.HandlerFunc{
+func (mc *MenuController) CreateMenu() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		ctx := c.Request.Context()
 		var menu models.Menu
 
 		if err := c.ShouldBindJSON(&menu); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":err.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		validationErr := validate.Struct(menu)
 		if validationErr != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": validationErr.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+		if err := mc.schedule.Check(menu); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
@@ -75,76 +106,71 @@ func CreateMenu() gin.HandlerFunc{
 		menu.ID = primitive.NewObjectID()
 		menu.MenuID = menu.ID.Hex()
 
-		result, insertErr := menuCollection.InsertOne(
-			ctx,
-			menu,
-		)
-		defer cancel()
-
-		if insertErr != nil {
-			msg := fmt.Sprintf("Menu item was not created")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		if err := mc.menus.Create(ctx, &menu); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Menu item was not created"})
 			return
 		}
-		c.JSON(http.StatusOK, result)
+		c.JSON(http.StatusOK, menu)
 	}
 }
 
-func UpdateMenu() gin.HandlerFunc{
-	return func(c *gin.Context){
-		var ctx, cancel = context.WithTimeout(context.Background(), 00*time.Second)
+func (mc *MenuController) UpdateMenu() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
 		var menu models.Menu
 
 		if err := c.ShouldBindJSON(&menu); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error":err.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		menuID := c.Param("menu_id")
-		filter := bson.M{"menu_id": menuID}
-
-		var updateObj primitive.D
-
-		if menu.StartDate != nil && menu.EndDate != nil {
-			if !helpers.InTimeSpan(*menu.StartDate, *menu.EndDate, time.Now()) {
-				msg := "kindly retype the time"
-				c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-				return
-			}
+		existing, err := mc.menus.FindByID(ctx, menuID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "menu was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Menu update failed"})
+			return
 		}
 
-		updateObj = append(updateObj, bson.E{"start_date", menu.StartDate})
-		updateObj = append(updateObj, bson.E{"end_date", menu.EndDate})
+		// a season may be set in advance or after it has ended; only the
+		// fields sent are changed
+		if menu.StartDate != nil {
+			existing.StartDate = menu.StartDate
+		}
+		if menu.EndDate != nil {
+			existing.EndDate = menu.EndDate
+		}
+		if menu.Windows != nil {
+			existing.Windows = menu.Windows
+		}
+		if menu.TimeZone != nil {
+			existing.TimeZone = menu.TimeZone
+		}
 
 		if menu.Name != "" {
-			updateObj = append(updateObj, bson.E{"name", menu.Name})
+			existing.Name = menu.Name
 		}
 		if menu.Category != "" {
-			updateObj = append(updateObj, bson.E{"name", menu.Category})
+			existing.Category = menu.Category
+		}
+		if err := validate.Struct(existing); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := mc.schedule.Check(existing); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
 
-		menu.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{"updated_at", menu.UpdatedAt})
-
-		upsert := true
-		opt := options.UpdateOptions{
-			Upsert : &upsert,
-		}
-
-		result, err := menuCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{"$set", updateObj},
-			},
-			&opt,
-		)
-		defer  cancel()
-		if err != nil {
-			msg := "Menu update failed"
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		existing.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := mc.menus.Update(ctx, &existing); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Menu update failed"})
 			return
 		}
-		c.JSON(http.StatusOK, result)
+		c.JSON(http.StatusOK, existing)
 	}
-}
\ No newline at end of file
+}
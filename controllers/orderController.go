@@ -2,166 +2,364 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/Micah-Shallom/modules/database"
+	"github.com/Micah-Shallom/modules/helpers"
+	"github.com/Micah-Shallom/modules/kds"
 	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-var orderCollection *mongo.Collection = database.OpenCollection(database.Client, "order")
+type OrderController struct {
+	orders repository.OrderRepository
+	tables repository.TableRepository
+	placer *orderPlacer
+}
+
+func NewOrderController(orders repository.OrderRepository, orderItems repository.OrderItemRepository, foods repository.FoodRepository, menus repository.MenuRepository, tables repository.TableRepository, invoices repository.InvoiceRepository, taxRules repository.TaxRuleRepository, stations repository.StationRepository, tickets repository.KitchenTicketRepository, transactions repository.Transactor, pricing helpers.Pricing, schedule helpers.MenuSchedule, kitchen *kds.Hub) *OrderController {
+	return &OrderController{
+		orders: orders,
+		tables: tables,
+		placer: &orderPlacer{
+			orders:       orders,
+			orderItems:   orderItems,
+			foods:        foods,
+			menus:        menus,
+			tables:       tables,
+			invoices:     invoices,
+			taxRules:     taxRules,
+			stations:     stations,
+			tickets:      tickets,
+			transactions: transactions,
+			pricing:      pricing,
+			schedule:     schedule,
+			kitchen:      kitchen,
+		},
+	}
+}
 
-func GetOrders() gin.HandlerFunc{
+// orderWithItems is an order as submitted to and returned by POST /orders,
+// optionally carrying its line items.
+type orderWithItems struct {
+	models.Order
+	OrderItems []models.OrderItem `json:"order_items"`
+}
+
+func (oc *OrderController) GetOrders() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		result, err := orderCollection.Find(context.TODO(), bson.M{})
+		ctx := c.Request.Context()
+
+		allOrders, err := oc.orders.List(ctx)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":"error occured while listing order items"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing order items"})
 			return
 		}
-
-		var allOrders []bson.M
-
-		if err := result.All(ctx, &allOrders); err != nil {
-			log.Fatal(err)
-		}
-		defer cancel()
 		c.JSON(http.StatusOK, allOrders)
 	}
 }
 
-func GetOrder() gin.HandlerFunc{
+func (oc *OrderController) GetOrder() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		var order models.Order
+		ctx := c.Request.Context()
 		orderID := c.Param("order_id")
 
-		err := orderCollection.FindOne(ctx, bson.M{"order_id": orderID}).Decode(&order)
-		defer cancel()
+		order, err := oc.orders.FindByID(ctx, orderID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":"error occured while fetching the food item"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the order"})
 			return
 		}
 		c.JSON(http.StatusOK, order)
 	}
 }
 
-func CreateOrder() gin.HandlerFunc{
+// CreateOrder opens an order. Line items sent along with it are created in the
+// same transaction, priced from the current food prices.
+func (oc *OrderController) CreateOrder() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		var order models.Order
-		var table models.Table
+		ctx := c.Request.Context()
+		var request orderWithItems
 
-		if err := c.ShouldBindJSON(&order); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":err.Error()})
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		validationErr := validate.Struct(&order)
+		validationErr := validate.Struct(&request.Order)
 		if validationErr != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": validationErr.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 			return
 		}
 
-		if order.TableID != nil {
-			err := tableCollection.FindOne(ctx, bson.M{"table_id": order.TableID}).Decode(&table)
-			if err != nil {
-				msg := fmt.Sprintf("message: Table was not found")
-				c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-				return
-			}
+		if request.Discount != nil && !helpers.HasRole(c, models.RoleManager) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only managers may give an order a discount"})
+			return
 		}
 
-		order.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-
-		order.ID = primitive.NewObjectID()
-		order.OrderID = order.ID.Hex()
-
-		result, insertErr := orderCollection.InsertOne(ctx, order)
-		defer cancel()
-
-		if insertErr != nil {
-			msg := fmt.Sprintf("Order Item was not created")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		order := request.Order
+		items, err := oc.placer.place(ctx, &order, request.OrderItems, c.GetString("uid"), c.GetString("userType"))
+		if errors.Is(err, errInvalidOrder) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, errTableStatus) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order was not created"})
 			return
 		}
 
-		c.JSON(http.StatusOK, result)
+		c.JSON(http.StatusOK, orderWithItems{Order: order, OrderItems: items})
 	}
 }
 
-func UpdateOrder() gin.HandlerFunc{
-	return func(c *gin.Context){
-		var table models.Table
+// UpdateOrder moves an order to another table, which the party then occupies,
+// or, for managers, changes its discount and recomputes its totals. Finished
+// orders, and orders billed on an invoice that was not voided, cannot change.
+func (oc *OrderController) UpdateOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
 		var order models.Order
 
-		var updateObj primitive.D
-
 		orderID := c.Param("order_id")
 		if err := c.ShouldBindJSON(&order); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
+		if order.Discount != nil {
+			if !helpers.HasRole(c, models.RoleManager) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "only managers may change the discount of an order"})
+				return
+			}
+			discount, err := oc.placer.pricing.Price(*order.Discount)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "discount: " + err.Error()})
+				return
+			}
+			if discount.IsNegative() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "discount must not be negative"})
+				return
+			}
+			order.Discount = &discount
+		}
 		if order.TableID != nil {
-			err := menuCollection.FindOne(ctx, bson.M{"table_id": order.TableID}).Decode(&table)
-			defer cancel()
+			_, err := oc.tables.FindByID(ctx, *order.TableID)
+			if errors.Is(err, repository.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+				return
+			}
 			if err != nil {
-				msg := fmt.Sprintf("message: menu was found")
-				c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the table"})
 				return
 			}
+		}
 
-			updateObj = append(updateObj, bson.E{"menu", order.TableID})
+		// the read-modify-write runs in a transaction so that it cannot undo a
+		// concurrent status transition
+		var existing models.Order
+		var events []kds.Event
+		err := oc.placer.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			existing, err = oc.orders.FindByID(ctx, orderID)
+			if err != nil {
+				return err
+			}
+			if err := checkNotFinished(existing); err != nil {
+				return err
+			}
+			if err := oc.placer.checkNotInvoiced(ctx, existing.OrderID); err != nil {
+				return err
+			}
+			if order.TableID != nil {
+				now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+				if err := oc.placer.moveTable(ctx, existing, *order.TableID, now); err != nil {
+					return err
+				}
+				existing.TableID = order.TableID
+			}
+			if order.Discount != nil {
+				existing.Discount = order.Discount
+			}
+			if err := oc.placer.retotal(ctx, &existing); err != nil || order.TableID == nil {
+				return err
+			}
+			// the tickets in the kitchen show the table
+			items, err := oc.placer.orderItems.ListByOrder(ctx, orderID)
+			if err != nil {
+				return err
+			}
+			events, err = oc.placer.syncTickets(ctx, existing, items)
+			return err
+		})
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+		if errors.Is(err, errTableStatus) || errors.Is(err, errOrderFinished) || errors.Is(err, errOrderInvoiced) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
 		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order item update failed"})
+			return
+		}
+		oc.placer.kitchen.Publish(events...)
+		c.JSON(http.StatusOK, existing)
+	}
+}
+
+type orderTransitionRequest struct {
+	Status string  `json:"status" validate:"required"`
+	Reason *string `json:"reason"`
+}
 
-		order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{"updated_at",  order.UpdatedAt})
+// TransitionOrder moves an order to the requested status, refusing moves the
+// order state machine does not allow.
+func (oc *OrderController) TransitionOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var request orderTransitionRequest
 
-		upsert := true
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !models.IsOrderStatus(request.Status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown order status %q", request.Status)})
+			return
+		}
+		if roles, ok := models.OrderTransitionRoles[request.Status]; ok && !helpers.HasRole(c, roles...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("only %s may move an order to %s", strings.Join(roles, ", "), request.Status)})
+			return
+		}
 
-		filter := bson.M{"order_id": orderID}
+		orderID := c.Param("order_id")
+		existing, err := oc.orders.FindByID(ctx, orderID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the order"})
+			return
+		}
 
-		opts := options.UpdateOptions{
-			Upsert: &upsert,
+		from := existing.CurrentStatus()
+		if !models.CanTransition(from, request.Status) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   fmt.Sprintf("order cannot move from %s to %s", from, request.Status),
+				"allowed": models.NextOrderStatuses(from),
+			})
+			return
 		}
 
-		result, err := orderCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{"$set", updateObj},
-			},
-			&opts,
-		)	
+		transition := models.OrderTransition{
+			From:      from,
+			To:        request.Status,
+			ChangedBy: c.GetString("uid"),
+			Role:      c.GetString("userType"),
+			Reason:    request.Reason,
+		}
+		transition.ChangedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
+		// a placed order reaches the kitchen displays, a cancelled or voided
+		// one leaves them and its table, and a closed one frees its table for
+		// cleaning
+		var order models.Order
+		var events []kds.Event
+		err = oc.placer.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			order, err = oc.orders.Transition(ctx, orderID, from, transition)
+			if err != nil {
+				return err
+			}
+			if order.Status == models.OrderStatusClosed && order.TableID != nil {
+				return releaseOrder(ctx, oc.tables, *order.TableID, order.OrderID, models.TableStatusDirty, transition.ChangedAt)
+			}
+			if order.Status == models.OrderStatusPlaced {
+				items, err := oc.placer.orderItems.ListByOrder(ctx, order.OrderID)
+				if err != nil {
+					return err
+				}
+				events, err = oc.placer.syncTickets(ctx, order, items)
+				return err
+			}
+			if order.Status != models.OrderStatusCancelled && order.Status != models.OrderStatusVoided {
+				return nil
+			}
+			if order.TableID != nil {
+				if err := releaseOrder(ctx, oc.tables, *order.TableID, order.OrderID, models.TableStatusSeated, transition.ChangedAt); err != nil {
+					return err
+				}
+			}
+			events, err = oc.placer.syncTickets(ctx, order, nil)
+			return err
+		})
+		if errors.Is(err, repository.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "order status changed concurrently, reload and retry"})
+			return
+		}
 		if err != nil {
-			msg := fmt.Sprintf("order item update failed")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order transition failed"})
 			return
 		}
-		c.JSON(http.StatusOK, result)
+		oc.placer.kitchen.Publish(events...)
+		c.JSON(http.StatusOK, order)
 	}
-
 }
 
-func OrderItemOrderCreator(order models.Order) string{
-	order.CreatedAt, _ =  time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-	order.UpdatedAt, _ =  time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-	order.ID = primitive.NewObjectID()
-	order.OrderID = order.ID.Hex()
+// GetOrderTransitions lists the status history of an order, oldest first.
+func (oc *OrderController) GetOrderTransitions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		orderID := c.Param("order_id")
 
-	orderCollection.InsertOne(ctx, order)
-	defer cancel()
+		order, err := oc.orders.FindByID(ctx, orderID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the order"})
+			return
+		}
+
+		history := order.StatusHistory
+		if history == nil {
+			history = []models.OrderTransition{}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"order_id": order.OrderID,
+			"status":   order.CurrentStatus(),
+			"allowed":  models.NextOrderStatuses(order.CurrentStatus()),
+			"history":  history,
+		})
+	}
+}
 
-	return order.OrderID
-}
\ No newline at end of file
+// openOrder puts a new order in DRAFT and records who opened it as the first
+// entry of its history.
+func openOrder(order *models.Order, createdBy, role string) {
+	order.Status = models.OrderStatusDraft
+	order.StatusHistory = []models.OrderTransition{{
+		To:        models.OrderStatusDraft,
+		ChangedBy: createdBy,
+		Role:      role,
+		ChangedAt: order.CreatedAt,
+	}}
+}
@@ -2,91 +2,98 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
-	"github.com/Micah-Shallom/modules/database"
 	"github.com/Micah-Shallom/modules/helpers"
+	"github.com/Micah-Shallom/modules/kds"
 	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var orderItemCollection *mongo.Collection = database.OpenCollection(database.Client, "orderItem")
+type OrderItemController struct {
+	orderItems repository.OrderItemRepository
+	orders     repository.OrderRepository
+	placer     *orderPlacer
+}
+
+func NewOrderItemController(orderItems repository.OrderItemRepository, orders repository.OrderRepository, foods repository.FoodRepository, menus repository.MenuRepository, tables repository.TableRepository, invoices repository.InvoiceRepository, taxRules repository.TaxRuleRepository, stations repository.StationRepository, tickets repository.KitchenTicketRepository, transactions repository.Transactor, pricing helpers.Pricing, schedule helpers.MenuSchedule, kitchen *kds.Hub) *OrderItemController {
+	return &OrderItemController{
+		orderItems: orderItems,
+		orders:     orders,
+		placer: &orderPlacer{
+			orders:       orders,
+			orderItems:   orderItems,
+			foods:        foods,
+			menus:        menus,
+			tables:       tables,
+			invoices:     invoices,
+			taxRules:     taxRules,
+			stations:     stations,
+			tickets:      tickets,
+			transactions: transactions,
+			pricing:      pricing,
+			schedule:     schedule,
+			kitchen:      kitchen,
+		},
+	}
+}
 
-func CreateOrderItem() gin.HandlerFunc{
+// CreateOrderItem opens a new order for the table of the pack and creates its
+// items in the same transaction.
+func (oic *OrderItemController) CreateOrderItem() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		ctx := c.Request.Context()
 		var orderItemPack models.OrderItemPack
 		var order models.Order
 
-		if err := c.ShouldBindJSON(&order); err != nil {
+		if err := c.ShouldBindJSON(&orderItemPack); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		order.OrderDate, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		orderItemsToBeInserted := []interface{}{}
 		order.TableID = orderItemPack.TableID
-		orderID := OrderItemOrderCreator(order)
 
-		for _, orderItem := range orderItemPack.OrderItems {
-			orderItem.OrderID = orderID
-
-			validationErr := validate.Struct(orderItem)
-			if validationErr != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
-				return
-			}
-
-			orderItem.ID = primitive.NewObjectID()
-			orderItem.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-			orderItem.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-			orderItem.OrderItemID = orderItem.ID.Hex()
-			var num = helpers.ToFixed(*orderItem.UnitPrice, 2)
-			orderItem.UnitPrice = &num
-			orderItemsToBeInserted = append(orderItemsToBeInserted, orderItem)
+		items, err := oic.placer.place(ctx, &order, orderItemPack.OrderItems, c.GetString("uid"), c.GetString("userType"))
+		if errors.Is(err, errInvalidOrder) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, errTableStatus) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
 		}
-
-		insertedOrderItems, err := orderItemCollection.InsertMany(ctx, orderItemsToBeInserted)
-
 		if err != nil {
-			log.Fatal(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order items were not created"})
+			return
 		}
-		defer cancel()
-		c.JSON(http.StatusOK, insertedOrderItems)
+		c.JSON(http.StatusOK, items)
 	}
 }
 
-func GetOrderItems() gin.HandlerFunc{
+func (oic *OrderItemController) GetOrderItems() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel =  context.WithTimeout(context.Background(), 100*time.Second)
-		result, err := orderItemCollection.Find(context.TODO(), bson.M{})
-		defer cancel()
+		ctx := c.Request.Context()
 
+		allOrderedItems, err := oic.orderItems.List(ctx)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":"error occurred while listing ordered items"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing ordered items"})
 			return
 		}
-
-		var allOrderedItems []bson.M
-		if err = result.All(ctx, &allOrderedItems); err != nil {{
-			log.Fatal(err)
-			return
-		}}
-		c.JSON(http.StatusOK, result)
+		c.JSON(http.StatusOK, allOrderedItems)
 	}
 }
 
-func GetOrderItemsByOrder() gin.HandlerFunc{
+func (oic *OrderItemController) GetOrderItemsByOrder() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		ctx := c.Request.Context()
 		orderID := c.Param("order_id")
-		allOrderedItems, err := ItemsByOrder(orderID)
 
+		allOrderedItems, err := oic.orderItems.ItemsByOrder(ctx, orderID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing order items by order ID"})
 			return
@@ -95,84 +102,16 @@ func GetOrderItemsByOrder() gin.HandlerFunc{
 	}
 }
 
-func ItemsByOrder(id string)(OrderItems []primitive.M, err error){
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-	matchStage := bson.D{{"$match", bson.D{{"order_id", id}}}}
-	lookupStage := bson.D{{"$lookup", bson.D{{"from", "food"}, {"localField", "food_id"}, {"foreignField", "food_id"}, {"as", "food"}}}}
-	unwindStage := bson.D{{"$unwind", bson.D{{"path", "$food"}, {"preserveNullAndEmptyArrays", true}}}}
-
-	lookupOrderStage := bson.D{{"$lookup", bson.D{{"from", "order"}, {"localField", "order_id"}, {"foreignField","order_id"}, {"as", "order"}}}}
-	unwindOrderStage := bson.D{{"$unwind", bson.D{{"path","$order"}, {"preserveNullAndEmptyArrays", true}}}}
-	
-	lookupTableStage := bson.D{{"$lookup", bson.D{{"from", "table"}, {"localField", "order.table_id"}, {"foreignField","table_id"}, {"as","tabele"} }}}
-	unwindTableStage := bson.D{{"$unwind", bson.D{{"path","$table"}, {"preserveNullAndEmptyArrays", true}}}}
-
-	//this stage determines what goes to the frontend
-	projectStage := bson.D{
-		{
-			"$project", bson.D{
-				{"id", 0},
-				{"amount", "$food.price"},
-				{"total_count", 1},
-				{"food_name", "$food.name"},
-				{"food_image", "$food.food_image"},
-				{"table_number", "$table.table_number"},
-				{"table_id", "$table.table_id"},
-				{"order_id", "$order.order_id"},
-				{"price", "$food.price"},
-				{"quantity", 1},
-			},
-		},
-	}
-
-	groupStage := bson.D{{"$group", bson.D{{"_id", bson.D{{"order_id", "$order_id"}, {"table_id", "$table_id"}, {"table_number", "$table_number"}}}, {"payment_due", bson.D{{"$sum", "$amount"}}}, {"total_count", bson.D{{"$sum", 1}}}, {"order_items", bson.D{{"$push", "$$ROOT"}}}}}}
-
-	projectStage2 := bson.D{
-		{"$project", bson.D{
-
-			{"id", 0},
-			{"payment_due", 1},
-			{"total_count", 1},
-			{"table_number", "$_id.table_number"},
-			{"order_items", 1},
-		}}}
-
-	result, err := orderItemCollection.Aggregate(ctx, mongo.Pipeline{
-		matchStage,
-		lookupStage,
-		unwindStage,
-		lookupOrderStage,
-		unwindOrderStage,
-		lookupTableStage,
-		unwindTableStage,
-		projectStage,
-		groupStage,
-		projectStage2})
-
-	if err != nil {
-		panic(err)
-	}
-
-	if err = result.All(ctx, &OrderItems); err != nil {
-		panic(err)
-	}
-
-	defer cancel()
-
-	return OrderItems, err
-
-}
-
-func GetOrderItem() gin.HandlerFunc{
-	return func(c *gin.Context){
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		orderItemID := c.Param("order_item_id")
+func (oic *OrderItemController) GetOrderItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		orderItemID := c.Param("orderItem_id")
 
-		var orderItem models.OrderItem
-		
-		err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": orderItemID}).Decode(&orderItem)
-		defer cancel()
+		orderItem, err := oic.orderItems.FindByID(ctx, orderItemID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order item was not found"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while getting order item"})
 			return
@@ -181,55 +120,117 @@ func GetOrderItem() gin.HandlerFunc{
 	}
 }
 
-
-func UpdateOrderItem() gin.HandlerFunc{
-	return func(c *gin.Context){
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+// UpdateOrderItem changes a line of an order that is neither finished nor
+// billed on an invoice that was not voided. The food and units of a line the
+// kitchen has already made cannot change.
+func (oic *OrderItemController) UpdateOrderItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
 		var orderItem models.OrderItem
 
 		if err := c.ShouldBindJSON(&orderItem); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error":err.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		orderItemID := c.Param("order_item_id")
-
-		filter := bson.M{"order_item_id": orderItemID}
-
-		var updateObj primitive.D
-
-		if orderItem.UnitPrice != nil {
-			updateObj = append(updateObj, bson.E{"unit_price", orderItem.UnitPrice})
-		}
-		if orderItem.Quantity != nil {
-			updateObj =  append(updateObj, bson.E{"quantity", *orderItem.Quantity})
-		}
-		if orderItem.FoodID != nil {
-			updateObj = append(updateObj, bson.E{"food_id", *orderItem.FoodID})
-		}
-
-		orderItem.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{"updated_at", orderItem.UpdatedAt})
+		// the read-modify-write runs in a transaction so that it cannot undo
+		// a concurrent bump or preparation step
+		orderItemID := c.Param("orderItem_id")
+		var existing models.OrderItem
+		var events []kds.Event
+		err := oic.placer.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			existing, err = oic.orderItems.FindByID(ctx, orderItemID)
+			if err != nil {
+				return err
+			}
+			order, err := oic.orders.FindByID(ctx, existing.OrderID)
+			if err != nil {
+				return err
+			}
+			if err := checkNotFinished(order); err != nil {
+				return err
+			}
+			if err := oic.placer.checkNotInvoiced(ctx, order.OrderID); err != nil {
+				return err
+			}
 
-		upsert := true
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
-		}
+			foodChanged := orderItem.FoodID != nil && (existing.FoodID == nil || *existing.FoodID != *orderItem.FoodID)
+			unitsChanged := orderItem.Units != nil && (existing.Units == nil || *existing.Units != *orderItem.Units)
+			if status := existing.CurrentPrepStatus(); (foodChanged || unitsChanged) && (status == models.PrepStatusReady || status == models.PrepStatusServed) {
+				return fmt.Errorf("%w: it is %s", errItemPrepared, status)
+			}
+			// more of a dish, or another one, is only ordered while its menu
+			// is served
+			ordersMore := foodChanged || orderItem.Units != nil && (existing.Units == nil || *orderItem.Units > *existing.Units)
+
+			if orderItem.FoodID != nil {
+				// modifiers of the previous food mean nothing for the new one
+				if foodChanged {
+					existing.Modifiers = nil
+				}
+				existing.FoodID = orderItem.FoodID
+			}
+			if orderItem.Units != nil {
+				existing.Units = orderItem.Units
+			}
+			if orderItem.Quantity != nil {
+				existing.Quantity = orderItem.Quantity
+			}
+			if orderItem.Seat != nil {
+				existing.Seat = orderItem.Seat
+			}
+			// the selected modifiers are replaced as a whole and checked
+			// against the food when the item is repriced
+			if orderItem.Modifiers != nil {
+				existing.Modifiers = orderItem.Modifiers
+			}
+			if orderItem.Notes != nil {
+				existing.Notes = orderItem.Notes
+			}
+			if ordersMore {
+				if err := oic.placer.checkServed(ctx, existing.FoodID, time.Now()); err != nil {
+					return err
+				}
+			}
+			// prices always come from the food, never from the client, and
+			// the line keeps the price it was ordered at unless what was
+			// ordered changes
+			if orderItem.FoodID != nil || orderItem.Units != nil || orderItem.Modifiers != nil {
+				if err := oic.placer.reprice(ctx, &existing); err != nil {
+					return err
+				}
+			}
+			if err := validate.Struct(existing); err != nil {
+				return fmt.Errorf("%w: %s", errInvalidOrder, err)
+			}
+			existing.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-		result, err := orderItemCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{"$set", updateObj},
-			},
-			&opt,
-		)
-		defer cancel()
-		if err != nil {
-			msg := "Order Item Update Failed"
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-			return
+			if err := oic.orderItems.Update(ctx, &existing); err != nil {
+				return err
+			}
+			if err := oic.placer.retotal(ctx, &order); err != nil {
+				return err
+			}
+			items, err := oic.orderItems.ListByOrder(ctx, order.OrderID)
+			if err != nil {
+				return err
+			}
+			events, err = oic.placer.syncTickets(ctx, order, items)
+			return err
+		})
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "order item was not found"})
+		case errors.Is(err, errInvalidOrder):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, errItemPrepared), errors.Is(err, errOrderFinished), errors.Is(err, errOrderInvoiced):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order Item Update Failed"})
+		default:
+			oic.placer.kitchen.Publish(events...)
+			c.JSON(http.StatusOK, existing)
 		}
-		c.JSON(http.StatusOK, result)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Micah-Shallom/modules/helpers"
+	"github.com/Micah-Shallom/modules/kds"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errInvalidOrder marks problems with the submitted order itself, which are
+// reported to the client as bad requests.
+var errInvalidOrder = errors.New("invalid order")
+
+// orderPlacer creates an order together with its line items. Both are written
+// in one transaction so that a failure never leaves an order without its items
+// or items without their order.
+type orderPlacer struct {
+	orders       repository.OrderRepository
+	orderItems   repository.OrderItemRepository
+	foods        repository.FoodRepository
+	menus        repository.MenuRepository
+	tables       repository.TableRepository
+	invoices     repository.InvoiceRepository
+	taxRules     repository.TaxRuleRepository
+	stations     repository.StationRepository
+	tickets      repository.KitchenTicketRepository
+	transactions repository.Transactor
+	pricing      helpers.Pricing
+	// schedule decides which foods can be ordered: those of the menus
+	// being served
+	schedule helpers.MenuSchedule
+	// kitchen is told about the tickets of new and changed orders
+	kitchen *kds.Hub
+}
+
+// place checks the table and every food of order, which must be on a menu
+// being served, copies the current food prices onto the items, computes the
+// order totals and stores the order along with its items and marks the table
+// as ORDERED. The order opens as a DRAFT, so the items only get kitchen
+// tickets once it is placed. The prices sent by the client are ignored.
+func (p *orderPlacer) place(ctx context.Context, order *models.Order, items []models.OrderItem, createdBy, role string) ([]models.OrderItem, error) {
+	if order.TableID != nil {
+		_, err := p.tables.FindByID(ctx, *order.TableID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("%w: table %s was not found", errInvalidOrder, *order.TableID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if order.Discount != nil {
+		discount, err := p.pricing.Price(*order.Discount)
+		if err != nil {
+			return nil, fmt.Errorf("%w: discount: %s", errInvalidOrder, err)
+		}
+		if discount.IsNegative() {
+			return nil, fmt.Errorf("%w: discount must not be negative", errInvalidOrder)
+		}
+		order.Discount = &discount
+	}
+
+	order.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	order.ID = primitive.NewObjectID()
+	order.OrderID = order.ID.Hex()
+	openOrder(order, createdBy, role)
+
+	placed := make([]models.OrderItem, 0, len(items))
+	for i, item := range items {
+		if item.Units == nil {
+			units := 1
+			item.Units = &units
+		}
+		if err := p.checkServed(ctx, item.FoodID, order.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := p.reprice(ctx, &item); err != nil {
+			return nil, err
+		}
+		item.OrderID = order.OrderID
+		item.PrepStatus = models.PrepStatusPending
+		item.FiredAt, item.CookingAt, item.ReadyAt, item.ServedAt = nil, nil, nil, nil
+		if err := validate.Struct(item); err != nil {
+			return nil, fmt.Errorf("%w: item %d: %s", errInvalidOrder, i, err)
+		}
+
+		item.ID = primitive.NewObjectID()
+		item.OrderItemID = item.ID.Hex()
+		item.CreatedAt = order.CreatedAt
+		item.UpdatedAt = order.CreatedAt
+		placed = append(placed, item)
+	}
+
+	if err := p.applyTotals(ctx, order, placed); err != nil {
+		return nil, err
+	}
+
+	err := p.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := p.orders.Create(ctx, order); err != nil {
+			return err
+		}
+		if err := p.orderItems.CreateMany(ctx, placed); err != nil {
+			return err
+		}
+		if order.TableID != nil {
+			return attachOrder(ctx, p.tables, *order.TableID, order.OrderID, order.CreatedAt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return placed, nil
+}
+
+// reprice snapshots the current price, modifiers and category of the food of
+// item and recomputes its line total. The selected modifiers must be legal for
+// the food and add their price deltas to the unit price.
+func (p *orderPlacer) reprice(ctx context.Context, item *models.OrderItem) error {
+	if item.FoodID == nil {
+		return fmt.Errorf("%w: the item has no food_id", errInvalidOrder)
+	}
+	food, err := p.foods.FindByID(ctx, *item.FoodID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: food %s was not found", errInvalidOrder, *item.FoodID)
+	}
+	if err != nil {
+		return err
+	}
+	if food.Price == nil {
+		return fmt.Errorf("%w: food %s has no price", errInvalidOrder, *item.FoodID)
+	}
+	price, err := p.pricing.Price(*food.Price)
+	if err != nil {
+		return fmt.Errorf("%w: food %s: %s", errInvalidOrder, *item.FoodID, err)
+	}
+	modifiers, err := food.SelectModifiers(item.Modifiers)
+	if err != nil {
+		return fmt.Errorf("%w: food %s: %s", errInvalidOrder, *item.FoodID, err)
+	}
+	for _, modifier := range modifiers {
+		price = price.Add(modifier.PriceDelta)
+	}
+	if price.IsNegative() {
+		return fmt.Errorf("%w: food %s: the modifiers take the price below zero", errInvalidOrder, *item.FoodID)
+	}
+	item.Modifiers = modifiers
+	lineTotal := helpers.LineTotal(price, item.Units)
+	item.UnitPrice = &price
+	item.LineTotal = &lineTotal
+
+	// the category decides which tax rules apply to the line
+	item.Category = nil
+	if food.MenuID != nil {
+		menu, err := p.menus.FindByID(ctx, *food.MenuID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		if err == nil {
+			item.Category = &menu.Category
+		}
+	}
+	return nil
+}
+
+// checkServed refuses a food whose menu is not being served at at. Foods
+// without a menu can always be ordered.
+func (p *orderPlacer) checkServed(ctx context.Context, foodID *string, at time.Time) error {
+	if foodID == nil {
+		return nil
+	}
+	food, err := p.foods.FindByID(ctx, *foodID)
+	if err != nil || food.MenuID == nil {
+		// reprice reports foods that do not exist
+		return nil
+	}
+	menu, err := p.menus.FindByID(ctx, *food.MenuID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !p.schedule.Served(menu, at) {
+		name := *foodID
+		if food.Name != nil {
+			name = *food.Name
+		}
+		return fmt.Errorf("%w: %s is not available, the %s menu is not being served", errInvalidOrder, name, menu.Name)
+	}
+	return nil
+}
+
+// checkNotInvoiced refuses changes to an order billed on an invoice that was
+// not voided: the invoice keeps the total it was created with, so the bill
+// would no longer match the order. Callers run it in the transaction that
+// changes the order.
+func (p *orderPlacer) checkNotInvoiced(ctx context.Context, orderID string) error {
+	invoices, err := p.invoices.ListByOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	for _, invoice := range invoices {
+		if invoice.Voided == nil {
+			return fmt.Errorf("%w: invoice %s; void it first", errOrderInvoiced, invoice.Number())
+		}
+	}
+	return nil
+}
+
+// retotal recomputes the totals of order from its stored lines and saves it.
+// Callers run it in the transaction that changed the lines or the order.
+func (p *orderPlacer) retotal(ctx context.Context, order *models.Order) error {
+	items, err := p.orderItems.ListByOrder(ctx, order.OrderID)
+	if err != nil {
+		return err
+	}
+	if err := p.applyTotals(ctx, order, items); err != nil {
+		return err
+	}
+	order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return p.orders.Update(ctx, order)
+}
+
+func (p *orderPlacer) applyTotals(ctx context.Context, order *models.Order, items []models.OrderItem) error {
+	var discount models.Money
+	if order.Discount != nil {
+		discount = *order.Discount
+	}
+	rules, err := p.taxRules.List(ctx)
+	if err != nil {
+		return err
+	}
+	totals, err := p.pricing.Totals(items, discount, order.Takeaway, rules)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errInvalidOrder, err)
+	}
+	order.Totals = &totals
+	return nil
+}
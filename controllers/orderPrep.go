@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Micah-Shallom/modules/helpers"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// errPrepBackwards is returned when an item is asked to move to a stage it
+	// has already reached.
+	errPrepBackwards = errors.New("order item cannot move back to an earlier stage")
+	// errOrderFinished is returned when the items of a closed, cancelled or
+	// voided order are asked to move.
+	errOrderFinished = errors.New("order is finished")
+	// errItemPrepared is returned when the food or units of an item the
+	// kitchen has already made are asked to change.
+	errItemPrepared = errors.New("order item is already prepared and cannot be changed")
+)
+
+// rollUpPath are the statuses an order walks through as its items progress.
+var rollUpPath = []string{models.OrderStatusInKitchen, models.OrderStatusReady, models.OrderStatusServed}
+
+type prepRequest struct {
+	Status string `json:"status" validate:"required"`
+	// OrderItemIDs restricts an order-wide move to some items, such as one
+	// course. All the items of the order move when it is empty.
+	OrderItemIDs []string `json:"order_item_ids"`
+}
+
+// checkNotFinished refuses changes to a closed, cancelled or voided order.
+func checkNotFinished(order models.Order) error {
+	switch order.CurrentStatus() {
+	case models.OrderStatusClosed, models.OrderStatusCancelled, models.OrderStatusVoided:
+		return fmt.Errorf("%w: it is %s", errOrderFinished, order.CurrentStatus())
+	}
+	return nil
+}
+
+// advancePrep moves items of an order to status, all of them when
+// orderItemIDs is empty, and then rolls the order status up. With strict set
+// an item already at or past status is an error, otherwise it is left alone.
+// It returns the order and the items that moved.
+func (p *orderPlacer) advancePrep(ctx context.Context, orderID string, orderItemIDs []string, status string, strict bool, changedBy, role string) (models.Order, []models.OrderItem, error) {
+	var order models.Order
+	var moved []models.OrderItem
+	err := p.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		order, err = p.orders.FindByID(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if err := checkNotFinished(order); err != nil {
+			return err
+		}
+		items, err := p.orderItems.ListByOrder(ctx, orderID)
+		if err != nil {
+			return err
+		}
+
+		selected, missing := map[string]bool{}, map[string]bool{}
+		for _, id := range orderItemIDs {
+			selected[id], missing[id] = true, true
+		}
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		moved = []models.OrderItem{}
+		for i := range items {
+			item := &items[i]
+			if len(selected) > 0 && !selected[item.OrderItemID] {
+				continue
+			}
+			delete(missing, item.OrderItemID)
+			if !item.CanAdvancePrep(status) {
+				if strict {
+					return fmt.Errorf("%w: it is %s", errPrepBackwards, item.CurrentPrepStatus())
+				}
+				continue
+			}
+			item.AdvancePrep(status, now)
+			item.UpdatedAt = now
+			if err := p.orderItems.Update(ctx, item); err != nil {
+				return err
+			}
+			moved = append(moved, *item)
+		}
+		for id := range missing {
+			return fmt.Errorf("%w: order item %s is not part of the order", errInvalidOrder, id)
+		}
+
+		order, err = p.rollUp(ctx, order, items, changedBy, role, now)
+		return err
+	})
+	return order, moved, err
+}
+
+// rollUp moves order along IN_KITCHEN, READY and SERVED as far as its items
+// allow, recording each step in its history. Orders whose status cannot
+// follow, such as drafts, stay where they are.
+func (p *orderPlacer) rollUp(ctx context.Context, order models.Order, items []models.OrderItem, changedBy, role string, at time.Time) (models.Order, error) {
+	target := models.RollUpOrderStatus(items)
+	if target == "" {
+		return order, nil
+	}
+	reason := "rolled up from the order items"
+	for _, status := range rollUpPath {
+		from := order.CurrentStatus()
+		if models.CanTransition(from, status) {
+			var err error
+			order, err = p.orders.Transition(ctx, order.OrderID, from, models.OrderTransition{
+				From:      from,
+				To:        status,
+				ChangedBy: changedBy,
+				Role:      role,
+				Reason:    &reason,
+				ChangedAt: at,
+			})
+			if err != nil {
+				return order, err
+			}
+		}
+		if status == target {
+			break
+		}
+	}
+	return order, nil
+}
+
+// AdvanceOrderItemPrep moves one order item forward to a preparation stage,
+// such as COOKING or READY, and rolls its order up.
+func (oic *OrderItemController) AdvanceOrderItemPrep() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		request, ok := bindPrepRequest(c)
+		if !ok {
+			return
+		}
+		item, err := oic.orderItems.FindByID(ctx, c.Param("orderItem_id"))
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order item was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while getting order item"})
+			return
+		}
+
+		order, moved, err := oic.placer.advancePrep(ctx, item.OrderID, []string{item.OrderItemID}, request.Status, true, c.GetString("uid"), c.GetString("userType"))
+		if err != nil {
+			respondPrepError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"order": order, "order_item": moved[0]})
+	}
+}
+
+// AdvanceOrderPrep moves the items of an order, or those listed in
+// order_item_ids, to a stage; items already past it are left as they are.
+// Firing a whole course is POST {"status": "FIRED", "order_item_ids": [...]}.
+func (oc *OrderController) AdvanceOrderPrep() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		request, ok := bindPrepRequest(c)
+		if !ok {
+			return
+		}
+		order, moved, err := oc.placer.advancePrep(ctx, c.Param("order_id"), request.OrderItemIDs, request.Status, false, c.GetString("uid"), c.GetString("userType"))
+		if err != nil {
+			respondPrepError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"order": order, "order_items": moved})
+	}
+}
+
+// GetPrepTimes reports how long each food took to prepare, over the items that
+// became ready between from and to. Both are dates (YYYY-MM-DD, in UTC) or
+// RFC 3339 timestamps; the whole of the to date is included. The default is
+// the last 7 days.
+func (oic *OrderItemController) GetPrepTimes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		from, err := parseDay(c.Query("from"), today.AddDate(0, 0, -6), false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from: " + err.Error()})
+			return
+		}
+		to, err := parseDay(c.Query("to"), today, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to: " + err.Error()})
+			return
+		}
+		if !from.Before(to) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+			return
+		}
+
+		items, err := oic.orderItems.ListReadyBetween(ctx, from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while listing ordered items"})
+			return
+		}
+		times := helpers.PrepTimesByFood(items)
+		for i := range times {
+			food, err := oic.placer.foods.FindByID(ctx, times[i].FoodID)
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the food"})
+				return
+			}
+			times[i].FoodName = food.Name
+		}
+		c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "foods": times})
+	}
+}
+
+func bindPrepRequest(c *gin.Context) (prepRequest, bool) {
+	var request prepRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return request, false
+	}
+	if err := validate.Struct(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return request, false
+	}
+	if !models.IsPrepStatus(request.Status) || request.Status == models.PrepStatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be FIRED, COOKING, READY or SERVED"})
+		return request, false
+	}
+	return request, true
+}
+
+func respondPrepError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+	case errors.Is(err, errInvalidOrder):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errPrepBackwards), errors.Is(err, errOrderFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "order status changed concurrently, reload and retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "order item update failed"})
+	}
+}
+
+// parseDay reads a YYYY-MM-DD date, as midnight UTC or, with end set, the
+// midnight after it, or an RFC 3339 timestamp. An empty value gives def.
+func parseDay(value string, def time.Time, end bool) (time.Time, error) {
+	if value == "" {
+		if end {
+			return def.AddDate(0, 0, 1), nil
+		}
+		return def, nil
+	}
+	if day, err := time.Parse(time.DateOnly, value); err == nil {
+		if end {
+			return day.AddDate(0, 0, 1), nil
+		}
+		return day, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/Micah-Shallom/modules/kds"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// syncTickets brings the kitchen tickets of order in line with its items: each
+// item has one ticket on every station that prepares it. New items get
+// tickets, edited ones have theirs updated, and tickets left without an item
+// or station are voided. Drafts have no tickets until they are placed, and
+// cancelled and voided orders lose all theirs. Callers run it in the
+// transaction that changed the order and publish the events it returns once
+// that transaction commits.
+func (p *orderPlacer) syncTickets(ctx context.Context, order models.Order, items []models.OrderItem) ([]kds.Event, error) {
+	switch order.CurrentStatus() {
+	case models.OrderStatusDraft, models.OrderStatusCancelled, models.OrderStatusVoided:
+		items = nil
+	}
+	existing, err := p.tickets.ListByOrder(ctx, order.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	wanted, err := p.ticketsFor(ctx, order, items)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct{ orderItemID, stationID string }
+	open := map[key]models.KitchenTicket{}
+	for _, ticket := range existing {
+		if ticket.Status != models.TicketStatusVoided {
+			open[key{ticket.OrderItemID, ticket.StationID}] = ticket
+		}
+	}
+
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	events := []kds.Event{}
+	created := []models.KitchenTicket{}
+	for _, want := range wanted {
+		k := key{want.OrderItemID, want.StationID}
+		ticket, ok := open[k]
+		if !ok {
+			created = append(created, want)
+			events = append(events, kds.Event{Type: kds.TicketCreated, Ticket: want})
+			continue
+		}
+		delete(open, k)
+		if !retarget(&ticket, want) {
+			continue
+		}
+		ticket.UpdatedAt = now
+		if err := p.tickets.Update(ctx, &ticket); err != nil {
+			return nil, err
+		}
+		events = append(events, kds.Event{Type: kds.TicketUpdated, Ticket: ticket})
+	}
+	if err := p.tickets.CreateMany(ctx, created); err != nil {
+		return nil, err
+	}
+
+	for _, ticket := range existing {
+		if _, ok := open[key{ticket.OrderItemID, ticket.StationID}]; !ok {
+			continue
+		}
+		ticket.Status = models.TicketStatusVoided
+		ticket.UpdatedAt = now
+		if err := p.tickets.Update(ctx, &ticket); err != nil {
+			return nil, err
+		}
+		events = append(events, kds.Event{Type: kds.TicketRemoved, Ticket: ticket})
+	}
+	return events, nil
+}
+
+// ticketsFor builds a NEW ticket for every item of order on every station
+// that prepares it.
+func (p *orderPlacer) ticketsFor(ctx context.Context, order models.Order, items []models.OrderItem) ([]models.KitchenTicket, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	stations, err := p.stations.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tableNumber *int
+	if order.TableID != nil && !order.Takeaway {
+		table, err := p.tables.FindByID(ctx, *order.TableID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		tableNumber = table.TableNumber
+	}
+
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	foodNames := map[string]string{}
+	tickets := []models.KitchenTicket{}
+	for _, item := range items {
+		routed := models.RouteStations(stations, item.Category)
+		if len(routed) == 0 {
+			continue
+		}
+		foodName, ok := foodNames[*item.FoodID]
+		if !ok {
+			food, err := p.foods.FindByID(ctx, *item.FoodID)
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return nil, err
+			}
+			foodName = "Item"
+			if food.Name != nil {
+				foodName = *food.Name
+			}
+			foodNames[*item.FoodID] = foodName
+		}
+		units := 1
+		if item.Units != nil {
+			units = *item.Units
+		}
+		modifiers := make([]string, 0, len(item.Modifiers))
+		for _, modifier := range item.Modifiers {
+			modifiers = append(modifiers, modifier.OptionName)
+		}
+
+		for _, station := range routed {
+			ticket := models.KitchenTicket{
+				ID:          primitive.NewObjectID(),
+				StationID:   station.StationID,
+				OrderID:     order.OrderID,
+				OrderItemID: item.OrderItemID,
+				TableNumber: tableNumber,
+				Takeaway:    order.Takeaway,
+				FoodName:    foodName,
+				Quantity:    item.Quantity,
+				Units:       units,
+				Seat:        item.Seat,
+				Modifiers:   modifiers,
+				Notes:       item.Notes,
+				Status:      models.TicketStatusNew,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			ticket.TicketID = ticket.ID.Hex()
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets, nil
+}
+
+// retarget copies what the kitchen sees of want onto ticket and reports
+// whether anything changed. A bumped ticket whose dish changed goes back into
+// the queue to be made again.
+func retarget(ticket *models.KitchenTicket, want models.KitchenTicket) bool {
+	dishChanged := ticket.FoodName != want.FoodName || ticket.Units != want.Units || !equalPtr(ticket.Quantity, want.Quantity) ||
+		!slices.Equal(ticket.Modifiers, want.Modifiers) || !equalPtr(ticket.Notes, want.Notes)
+	changed := dishChanged || ticket.Takeaway != want.Takeaway || !equalPtr(ticket.Seat, want.Seat) || !equalPtr(ticket.TableNumber, want.TableNumber)
+	if !changed {
+		return false
+	}
+	ticket.FoodName, ticket.Units, ticket.Quantity = want.FoodName, want.Units, want.Quantity
+	ticket.Modifiers, ticket.Notes = want.Modifiers, want.Notes
+	ticket.Takeaway, ticket.Seat, ticket.TableNumber = want.Takeaway, want.Seat, want.TableNumber
+	if dishChanged && ticket.Status == models.TicketStatusBumped {
+		ticket.Status = models.TicketStatusNew
+		ticket.BumpedBy, ticket.BumpedAt = "", nil
+	}
+	return true
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
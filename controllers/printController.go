@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Micah-Shallom/modules/printing"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type PrintController struct {
+	orders     repository.OrderRepository
+	orderItems repository.OrderItemRepository
+	printer    *printing.Queue
+	// timeZone is the one kitchen tickets are timed in
+	timeZone *time.Location
+}
+
+func NewPrintController(orders repository.OrderRepository, orderItems repository.OrderItemRepository, printer *printing.Queue, timeZone *time.Location) *PrintController {
+	return &PrintController{orders: orders, orderItems: orderItems, printer: printer, timeZone: timeZone}
+}
+
+// PrintKitchenTicket sends the kitchen ticket of an order to the kitchen
+// printer. The ticket prints in the background; the job returned can be
+// followed at GET /printJobs/:job_id.
+func (pc *PrintController) PrintKitchenTicket() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		orderID := c.Param("order_id")
+
+		if _, err := pc.orders.FindByID(ctx, orderID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the order"})
+			return
+		}
+		summaries, err := pc.orderItems.ItemsByOrder(ctx, orderID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing order items by order ID"})
+			return
+		}
+		ticket := printing.TicketFromSummaries(orderID, summaries, time.Now().In(pc.timeZone))
+		if len(ticket.Lines) == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "the order has no items to prepare"})
+			return
+		}
+
+		job, err := pc.printer.Submit(printing.PrinterKitchen, printing.JobKitchenTicket, orderID, printing.KitchenTicket(ticket))
+		if err != nil {
+			respondPrintError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+func (pc *PrintController) GetPrintJobs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, pc.printer.Jobs())
+	}
+}
+
+func (pc *PrintController) GetPrintJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := pc.printer.Job(c.Param("job_id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "print job was not found"})
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+func respondPrintError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, printing.ErrUnknownPrinter):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no printer is configured for this job"})
+	case errors.Is(err, printing.ErrQueueFull), errors.Is(err, printing.ErrQueueClosed):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "the printer is not accepting jobs, try again later"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while queueing the print job"})
+	}
+}
@@ -0,0 +1,455 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Micah-Shallom/modules/helpers"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	errInvalidBooking    = errors.New("invalid reservation")
+	errNoTable           = errors.New("no table seats the party at that time")
+	errReservationStatus = errors.New("reservation is not in a status that allows this")
+	errNotLate           = errors.New("the party is not late enough to be marked as a no-show")
+)
+
+// tableTaken is returned when the table asked for is held by other
+// reservations at the requested time.
+type tableTaken struct {
+	tableNumber  *int
+	reservations []string
+}
+
+func (e *tableTaken) Error() string {
+	if e.tableNumber == nil {
+		return "the table is already booked at that time"
+	}
+	return fmt.Sprintf("table %d is already booked at that time", *e.tableNumber)
+}
+
+type ReservationController struct {
+	reservations repository.ReservationRepository
+	tables       repository.TableRepository
+	counters     repository.CounterRepository
+	transactions repository.Transactor
+	booking      helpers.Booking
+}
+
+func NewReservationController(reservations repository.ReservationRepository, tables repository.TableRepository, counters repository.CounterRepository, transactions repository.Transactor, booking helpers.Booking) *ReservationController {
+	return &ReservationController{reservations: reservations, tables: tables, counters: counters, transactions: transactions, booking: booking}
+}
+
+// reservationUpdate is the body of PATCH /reservations/:reservation_id; fields
+// left out are not changed.
+type reservationUpdate struct {
+	GuestName *string    `json:"guest_name" validate:"omitempty,max=100"`
+	Phone     *string    `json:"phone" validate:"omitempty,max=20"`
+	Email     *string    `json:"email" validate:"omitempty,email"`
+	PartySize *int       `json:"party_size" validate:"omitempty,min=1"`
+	StartsAt  *time.Time `json:"starts_at"`
+	TableID   *string    `json:"table_id"`
+	Notes     *string    `json:"notes" validate:"omitempty,max=200"`
+}
+
+// GetReservations lists the reservations starting on date, local to the
+// restaurant and today by default, in order of time.
+func (rc *ReservationController) GetReservations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		day, err := rc.booking.Day(c.DefaultQuery("date", rc.booking.Date(time.Now())))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be a day such as 2026-10-20"})
+			return
+		}
+		next := day.AddDate(0, 0, 1)
+		reservations, err := rc.reservations.ListBetween(ctx, day, next)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing reservations"})
+			return
+		}
+		starting := []models.Reservation{}
+		for _, reservation := range reservations {
+			if !reservation.StartsAt.Before(day) && reservation.StartsAt.Before(next) {
+				starting = append(starting, reservation)
+			}
+		}
+		sort.SliceStable(starting, func(i, j int) bool { return starting[i].StartsAt.Before(starting[j].StartsAt) })
+		c.JSON(http.StatusOK, starting)
+	}
+}
+
+func (rc *ReservationController) GetReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reservation, ok := rc.findReservation(c)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, reservation)
+	}
+}
+
+// CreateReservation books a table for a party. The table given as table_id
+// is booked when it is free and large enough; otherwise the smallest free
+// table that seats the party is assigned.
+func (rc *ReservationController) CreateReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var reservation models.Reservation
+
+		if err := c.ShouldBindJSON(&reservation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(reservation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		reservation.StartsAt = reservation.StartsAt.Truncate(time.Minute)
+		if err := rc.checkStart(reservation.StartsAt, now); err != nil {
+			respondReservationError(c, err)
+			return
+		}
+		reservation.EndsAt = reservation.StartsAt.Add(rc.booking.TurnTime(reservation.PartySize))
+		reservation.Status = models.ReservationStatusBooked
+		reservation.BookedBy = c.GetString("uid")
+		reservation.SeatedAt, reservation.CompletedAt, reservation.CancelledAt, reservation.NoShowAt = nil, nil, nil, nil
+		reservation.CreatedAt, reservation.UpdatedAt = now, now
+		reservation.ID = primitive.NewObjectID()
+		reservation.ReservationID = reservation.ID.Hex()
+
+		requested := reservation.TableID
+		reservation.TableID, reservation.TableNumber = "", nil
+		err := rc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := rc.assign(ctx, &reservation, requested); err != nil {
+				return err
+			}
+			return rc.reservations.Create(ctx, &reservation)
+		})
+		if err != nil {
+			respondReservationError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, reservation)
+	}
+}
+
+// UpdateReservation changes a booked reservation. A new party size, time or
+// table is checked against the other bookings; the party keeps its table when
+// it is still free and large enough.
+func (rc *ReservationController) UpdateReservation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var update reservationUpdate
+
+		if err := c.ShouldBindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		var reservation models.Reservation
+		err := rc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			reservation, err = rc.reservations.FindByID(ctx, c.Param("reservation_id"))
+			if err != nil {
+				return err
+			}
+			if reservation.Status != models.ReservationStatusBooked {
+				return fmt.Errorf("%w: it is %s", errReservationStatus, reservation.Status)
+			}
+
+			if update.GuestName != nil {
+				reservation.GuestName = *update.GuestName
+			}
+			if update.Phone != nil {
+				reservation.Phone = *update.Phone
+			}
+			if update.Email != nil {
+				reservation.Email = update.Email
+			}
+			if update.Notes != nil {
+				reservation.Notes = update.Notes
+			}
+			reservation.UpdatedAt = now
+
+			if update.PartySize == nil && update.StartsAt == nil && update.TableID == nil {
+				return rc.reservations.Update(ctx, &reservation)
+			}
+			if update.PartySize != nil {
+				reservation.PartySize = *update.PartySize
+			}
+			if update.StartsAt != nil {
+				reservation.StartsAt = update.StartsAt.Truncate(time.Minute)
+				if err := rc.checkStart(reservation.StartsAt, now); err != nil {
+					return err
+				}
+			}
+			reservation.EndsAt = reservation.StartsAt.Add(rc.booking.TurnTime(reservation.PartySize))
+			requested := ""
+			if update.TableID != nil {
+				requested = *update.TableID
+			}
+			if err := rc.assign(ctx, &reservation, requested); err != nil {
+				return err
+			}
+			return rc.reservations.Update(ctx, &reservation)
+		})
+		if err != nil {
+			respondReservationError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, reservation)
+	}
+}
+
+// CancelReservation releases the table of a booked reservation.
+func (rc *ReservationController) CancelReservation() gin.HandlerFunc {
+	return rc.moveReservation(models.ReservationStatusCancelled, func(ctx context.Context, reservation *models.Reservation, at time.Time) error {
+		reservation.CancelledAt = &at
+		return nil
+	})
+}
+
+// MarkNoShow records that a party never came, once it is later than the
+// no-show grace period, and releases its table.
+func (rc *ReservationController) MarkNoShow() gin.HandlerFunc {
+	return rc.moveReservation(models.ReservationStatusNoShow, func(ctx context.Context, reservation *models.Reservation, at time.Time) error {
+		if at.Before(reservation.StartsAt.Add(rc.booking.NoShowAfter)) {
+			return errNotLate
+		}
+		reservation.NoShowAt = &at
+		return nil
+	})
+}
+
+// SeatReservation seats the party at its table, which must be AVAILABLE.
+func (rc *ReservationController) SeatReservation() gin.HandlerFunc {
+	return rc.moveReservation(models.ReservationStatusSeated, func(ctx context.Context, reservation *models.Reservation, at time.Time) error {
+		reservation.SeatedAt = &at
+		return seatParty(ctx, rc.tables, reservation.TableID, reservation.PartySize, at)
+	})
+}
+
+// CompleteReservation records that the party has left. A party leaving before
+// its turn time is up frees the table for later bookings.
+func (rc *ReservationController) CompleteReservation() gin.HandlerFunc {
+	return rc.moveReservation(models.ReservationStatusCompleted, func(ctx context.Context, reservation *models.Reservation, at time.Time) error {
+		reservation.CompletedAt = &at
+		if at.Before(reservation.EndsAt) && at.After(reservation.StartsAt) {
+			reservation.EndsAt = at
+		}
+		return nil
+	})
+}
+
+// moveReservation moves a reservation to status to when its state machine
+// allows it and change, which records the move, agrees.
+func (rc *ReservationController) moveReservation(to string, change func(ctx context.Context, reservation *models.Reservation, at time.Time) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		var reservation models.Reservation
+		err := rc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			reservation, err = rc.reservations.FindByID(ctx, c.Param("reservation_id"))
+			if err != nil {
+				return err
+			}
+			if !models.CanMoveReservation(reservation.Status, to) {
+				return fmt.Errorf("%w: it cannot move from %s to %s", errReservationStatus, reservation.Status, to)
+			}
+			if err := change(ctx, &reservation, now); err != nil {
+				return err
+			}
+			reservation.Status = to
+			reservation.UpdatedAt = now
+			return rc.reservations.Update(ctx, &reservation)
+		})
+		if err != nil {
+			respondReservationError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, reservation)
+	}
+}
+
+// GetAvailability lists the times on date a party of party_size can still be
+// booked at, with the tables free for each.
+func (rc *ReservationController) GetAvailability() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		date := c.DefaultQuery("date", rc.booking.Date(time.Now()))
+		day, err := rc.booking.Day(date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be a day such as 2026-10-20"})
+			return
+		}
+		partySize, err := strconv.Atoi(c.Query("party_size"))
+		if err != nil || partySize < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "party_size must be a positive number"})
+			return
+		}
+
+		slots := rc.booking.Slots(day)
+		turn := rc.booking.TurnTime(partySize)
+		tables, err := rc.tables.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing tables"})
+			return
+		}
+		reservations, err := rc.reservations.ListBetween(ctx, slots[0], slots[len(slots)-1].Add(turn))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing reservations"})
+			return
+		}
+
+		now := time.Now()
+		open := []models.Slot{}
+		for _, start := range slots {
+			if start.Before(now) {
+				continue
+			}
+			slot := models.Slot{StartsAt: start, EndsAt: start.Add(turn), TableNumbers: []int{}}
+			for _, table := range helpers.FreeTables(tables, reservations, partySize, slot.StartsAt, slot.EndsAt, "") {
+				if table.TableNumber != nil {
+					slot.TableNumbers = append(slot.TableNumbers, *table.TableNumber)
+				}
+			}
+			if len(slot.TableNumbers) > 0 {
+				open = append(open, slot)
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"date":              date,
+			"party_size":        partySize,
+			"turn_time_minutes": int(turn / time.Minute),
+			"slots":             open,
+		})
+	}
+}
+
+// checkStart refuses bookings in the past or outside the booking hours.
+func (rc *ReservationController) checkStart(startsAt, now time.Time) error {
+	if startsAt.IsZero() {
+		return fmt.Errorf("%w: starts_at is required", errInvalidBooking)
+	}
+	if startsAt.Before(now) {
+		return fmt.Errorf("%w: starts_at is in the past", errInvalidBooking)
+	}
+	if !rc.booking.Bookable(startsAt) {
+		return fmt.Errorf("%w: bookings start between %s and %s", errInvalidBooking, clockTime(rc.booking.Opens), clockTime(rc.booking.Closes))
+	}
+	return nil
+}
+
+func clockTime(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset.Hours()), int(offset.Minutes())%60)
+}
+
+// assign gives reservation a table for its party and time: requested when set,
+// else the table it already has if that is still free, else the smallest free
+// table. It runs in the transaction that saves the reservation and bumps a
+// counter per day, so that concurrent bookings of the same day conflict and
+// are retried instead of both taking the same table.
+func (rc *ReservationController) assign(ctx context.Context, reservation *models.Reservation, requested string) error {
+	if _, err := rc.counters.Next(ctx, "reservations:"+rc.booking.Date(reservation.StartsAt)); err != nil {
+		return err
+	}
+	tables, err := rc.tables.List(ctx)
+	if err != nil {
+		return err
+	}
+	reservations, err := rc.reservations.ListBetween(ctx, reservation.StartsAt, reservation.EndsAt)
+	if err != nil {
+		return err
+	}
+
+	if requested != "" {
+		var table *models.Table
+		for i := range tables {
+			if tables[i].TableID == requested {
+				table = &tables[i]
+			}
+		}
+		if table == nil {
+			return fmt.Errorf("%w: table %s was not found", errInvalidBooking, requested)
+		}
+		if table.NumberOfGuests == nil || *table.NumberOfGuests < reservation.PartySize {
+			return fmt.Errorf("%w: the table does not seat %d guests", errInvalidBooking, reservation.PartySize)
+		}
+		taken := &tableTaken{tableNumber: table.TableNumber}
+		for _, other := range reservations {
+			if other.TableID == table.TableID && other.ReservationID != reservation.ReservationID && other.Holds(reservation.StartsAt, reservation.EndsAt) {
+				taken.reservations = append(taken.reservations, other.ReservationID)
+			}
+		}
+		if len(taken.reservations) > 0 {
+			return taken
+		}
+		reservation.TableID, reservation.TableNumber = table.TableID, table.TableNumber
+		return nil
+	}
+
+	free := helpers.FreeTables(tables, reservations, reservation.PartySize, reservation.StartsAt, reservation.EndsAt, reservation.ReservationID)
+	if len(free) == 0 {
+		return errNoTable
+	}
+	table := free[0]
+	for _, candidate := range free {
+		if candidate.TableID == reservation.TableID {
+			table = candidate
+		}
+	}
+	reservation.TableID, reservation.TableNumber = table.TableID, table.TableNumber
+	return nil
+}
+
+func (rc *ReservationController) findReservation(c *gin.Context) (models.Reservation, bool) {
+	reservation, err := rc.reservations.FindByID(c.Request.Context(), c.Param("reservation_id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "reservation was not found"})
+		return reservation, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the reservation"})
+		return reservation, false
+	}
+	return reservation, true
+}
+
+func respondReservationError(c *gin.Context, err error) {
+	var taken *tableTaken
+	switch {
+	case errors.As(err, &taken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "conflicts": taken.reservations})
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "reservation was not found"})
+	case errors.Is(err, errInvalidBooking):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errNoTable), errors.Is(err, errReservationStatus), errors.Is(err, errNotLate), errors.Is(err, errTableStatus):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "reservation changed concurrently, reload and retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reservation update failed"})
+	}
+}
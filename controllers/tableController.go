@@ -2,61 +2,80 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"sort"
 	"time"
 
-	"github.com/Micah-Shallom/modules/database"
 	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var tableCollection *mongo.Collection = database.OpenCollection(database.Client, "table")
+// errInvalidLayout marks a table placed outside the plan of its area or in
+// an area that does not exist.
+var errInvalidLayout = errors.New("invalid table layout")
 
-func GetTables() gin.HandlerFunc{
+// errTableMove marks a status the table state machine does not allow from the
+// status the table is in.
+var errTableMove = errors.New("table cannot move to this status")
+
+type TableController struct {
+	tables       repository.TableRepository
+	orders       repository.OrderRepository
+	areas        repository.AreaRepository
+	sections     repository.SectionRepository
+	shifts       repository.ShiftRepository
+	transactions repository.Transactor
+}
+
+func NewTableController(tables repository.TableRepository, orders repository.OrderRepository, areas repository.AreaRepository, sections repository.SectionRepository, shifts repository.ShiftRepository, transactions repository.Transactor) *TableController {
+	return &TableController{tables: tables, orders: orders, areas: areas, sections: sections, shifts: shifts, transactions: transactions}
+}
+
+// tableStatusRequest is the body of POST /tables/:table_id/status. Guests is
+// the size of the party when seating one.
+type tableStatusRequest struct {
+	Status string `json:"status" validate:"required"`
+	Guests *int   `json:"guests" validate:"omitempty,min=1"`
+}
+
+func (tc *TableController) GetTables() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		ctx := c.Request.Context()
 
-		result, err := orderCollection.Find(context.TODO(), bson.M{})
+		allTables, err := tc.tables.List(ctx)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":"error occured while listing tables"})
-			return
-		}
-
-		var allTables []bson.M
-		if err := result.All(ctx, &allTables); err != nil {
-			log.Fatal(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing tables"})
 			return
 		}
-		defer cancel()
-		
+		c.JSON(http.StatusOK, allTables)
 	}
 }
 
-func GetTable() gin.HandlerFunc{
+func (tc *TableController) GetTable() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		var table models.Table
+		ctx := c.Request.Context()
 		tableID := c.Param("table_id")
 
-		err := tableCollection.FindOne(ctx, bson.M{"table_id": tableID}).Decode(&table)
+		table, err := tc.tables.FindByID(ctx, tableID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":"error occured while fetching table"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching table"})
 			return
 		}
 		c.JSON(http.StatusOK, table)
-		defer cancel()
 	}
 }
 
-func CreateTable() gin.HandlerFunc{
+func (tc *TableController) CreateTable() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		ctx := c.Request.Context()
 		var table models.Table
 
 		if err := c.ShouldBindJSON(&table); err != nil {
@@ -66,68 +85,300 @@ func CreateTable() gin.HandlerFunc{
 
 		validationErr := validate.Struct(table)
 		if validationErr != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": validationErr.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 			return
 		}
 
 		table.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		table.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		table.ID = primitive.NewObjectID() 
+		table.Status, table.StatusChangedAt = models.TableStatusAvailable, &table.CreatedAt
+		table.Guests, table.SeatedAt, table.OrderIDs, table.MergedInto, table.Turns = nil, nil, nil, nil, nil
+		table.ID = primitive.NewObjectID()
 		table.TableID = table.ID.Hex()
+		if err := tc.checkLayout(ctx, table); err != nil {
+			respondLayoutError(c, err)
+			return
+		}
 
-		result, err := tableCollection.InsertOne(ctx, table)
-		defer cancel()
-		if err != nil {
-			msg := "Table item was not created"
-			c.JSON(http.StatusInternalServerError, gin.H{"error":msg})
+		if err := tc.tables.Create(ctx, &table); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Table item was not created"})
 			return
 		}
-		c.JSON(http.StatusOK, result)
+		c.JSON(http.StatusOK, table)
 	}
 }
 
-func UpdateTable() gin.HandlerFunc{
-	return func(c *gin.Context){
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+func (tc *TableController) UpdateTable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
 		var table models.Table
 		tableID := c.Param("table_id")
 
 		if err := c.ShouldBindJSON(&table); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		var updateObj primitive.D
+		if table.Layout != nil {
+			if err := validate.Struct(table.Layout); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		// the read-modify-write runs in a transaction so that it cannot undo
+		// the orders and status an order or payment gives the table meanwhile
+		var existing models.Table
+		err := tc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			existing, err = tc.tables.FindByID(ctx, tableID)
+			if err != nil {
+				return err
+			}
+			if table.NumberOfGuests != nil {
+				existing.NumberOfGuests = table.NumberOfGuests
+			}
+			if table.TableNumber != nil {
+				existing.TableNumber = table.TableNumber
+			}
+			if table.AreaID != nil {
+				existing.AreaID = table.AreaID
+			}
+			if table.Layout != nil {
+				existing.Layout = table.Layout
+			}
+			if err := tc.checkLayout(ctx, existing); err != nil {
+				return err
+			}
+			existing.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			return tc.tables.Update(ctx, &existing)
+		})
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+		case errors.Is(err, errInvalidLayout):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Table Item update failed"})
+		default:
+			c.JSON(http.StatusOK, existing)
+		}
+	}
+}
+
+// checkLayout checks that a table with a layout is placed on the plan of an
+// existing area.
+func (tc *TableController) checkLayout(ctx context.Context, table models.Table) error {
+	if table.AreaID == nil {
+		if table.Layout != nil {
+			return fmt.Errorf("%w: a table needs an area_id to be placed", errInvalidLayout)
+		}
+		return nil
+	}
+	area, err := tc.areas.FindByID(ctx, *table.AreaID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: area %s was not found", errInvalidLayout, *table.AreaID)
+	}
+	if err != nil {
+		return err
+	}
+	if table.Layout != nil && !table.Layout.Fits(area) {
+		return fmt.Errorf("%w: the table lies outside the %gx%g plan of %s", errInvalidLayout, area.Width, area.Height, area.Name)
+	}
+	return nil
+}
+
+func respondLayoutError(c *gin.Context, err error) {
+	if errors.Is(err, errInvalidLayout) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the area of the table"})
+}
+
+// MoveTable records what the host sees at a table: a party seated, the bill
+// asked for, the party gone, the table cleaned or taken out of service. ORDERED
+// follows from the orders of the table and cannot be set by hand.
+func (tc *TableController) MoveTable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var request tableStatusRequest
 
-		if table.NumberOfGuests != nil {
-			updateObj = append(updateObj, bson.E{"number_of_guests", table.NumberOfGuests})
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !models.IsTableStatus(request.Status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown table status %q", request.Status)})
+			return
 		}
-		if table.TableNumber != nil {
-			updateObj = append(updateObj, bson.E{"table_number",table.TableNumber})
+		if request.Status == models.TableStatusOrdered {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a table is ORDERED once an order is placed for it"})
+			return
 		}
-	
-		table.UpdatedAt, _ = time.Parse(time.RFC3339, string(time.Now().Format(time.RFC3339)))
 
-		upsert := true
-		filter := bson.M{"table_id": tableID}
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		// the read-modify-write runs in a transaction so that it cannot undo
+		// the orders an order or payment attaches to or takes off the table
+		var table models.Table
+		var from string
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		err := tc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			table, err = tc.tables.FindByID(ctx, c.Param("table_id"))
+			if err != nil {
+				return err
+			}
+			if table.MergedInto != nil {
+				return mergedError(ctx, tc.tables, table)
+			}
+			from = table.CurrentStatus()
+			if !models.CanMoveTable(from, request.Status) {
+				return errTableMove
+			}
+			table.MoveTo(request.Status, now)
+			if request.Guests != nil && table.Occupied() {
+				table.Guests = request.Guests
+			}
+			table.UpdatedAt = now
+			return tc.tables.Update(ctx, &table)
+		})
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+		case errors.Is(err, errTableMove):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   fmt.Sprintf("table cannot move from %s to %s", from, request.Status),
+				"allowed": models.NextTableStatuses(from),
+			})
+		case errors.Is(err, errTableStatus):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "table update failed"})
+		default:
+			c.JSON(http.StatusOK, table)
 		}
+	}
+}
 
-		result, err := tableCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{"&set", updateObj},
-			},
-			&opt,
-		)
-		defer cancel()
+// GetFloorPlan lists every table by number with its status, the unpaid
+// orders of the party at it and how many minutes the party has been seated,
+// where it stands and the waiter on shift for its section. ?area_id= keeps the
+// tables of one area.
+func (tc *TableController) GetFloorPlan() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		areaID := c.Query("area_id")
+
+		tables, err := tc.tables.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing tables"})
+			return
+		}
+		sort.SliceStable(tables, func(i, j int) bool {
+			if tables[i].TableNumber == nil || tables[j].TableNumber == nil {
+				return tables[j].TableNumber == nil && tables[i].TableNumber != nil
+			}
+			return *tables[i].TableNumber < *tables[j].TableNumber
+		})
+		sections, err := tc.sections.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing sections"})
+			return
+		}
+		now := time.Now()
+		shifts, err := tc.shifts.ListBetween(ctx, now, now.Add(time.Second))
 		if err != nil {
-			msg := fmt.Sprintf("Table Item update failed")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing shifts"})
 			return
 		}
-		c.JSON(http.StatusOK, result)
+
+		byID := map[string]models.Table{}
+		merged := map[string][]int{}
+		for _, table := range tables {
+			byID[table.TableID] = table
+			if table.MergedInto != nil && table.TableNumber != nil {
+				merged[*table.MergedInto] = append(merged[*table.MergedInto], *table.TableNumber)
+			}
+		}
+		sectionOf := map[string]string{}
+		for _, section := range sections {
+			for _, tableID := range section.TableIDs {
+				sectionOf[tableID] = section.SectionID
+			}
+		}
+		waiterOf := map[string]string{}
+		for _, shift := range shifts {
+			if shift.Covers(now) {
+				waiterOf[shift.SectionID] = shift.WaiterID
+			}
+		}
+
+		plan := make([]models.FloorPlanTable, 0, len(tables))
+		for _, table := range tables {
+			if areaID != "" && (table.AreaID == nil || *table.AreaID != areaID) {
+				continue
+			}
+			entry := models.FloorPlanTable{
+				TableID:      table.TableID,
+				TableNumber:  table.TableNumber,
+				Capacity:     table.NumberOfGuests,
+				AreaID:       table.AreaID,
+				Layout:       table.Layout,
+				MergedInto:   table.MergedInto,
+				MergedTables: merged[table.TableID],
+				Orders:       []models.FloorPlanOrder{},
+			}
+			if entry.MergedTables == nil {
+				entry.MergedTables = []int{}
+			}
+			if sectionID, ok := sectionOf[table.TableID]; ok {
+				entry.SectionID = &sectionID
+				if waiterID, ok := waiterOf[sectionID]; ok {
+					entry.WaiterID = &waiterID
+				}
+			}
+
+			// a merged table shows the party and orders of the table it is
+			// merged into
+			shown := table
+			if table.MergedInto != nil {
+				if primary, ok := byID[*table.MergedInto]; ok {
+					shown = primary
+				}
+			}
+			entry.Status = shown.CurrentStatus()
+			entry.StatusChangedAt = shown.StatusChangedAt
+			entry.Guests = shown.Guests
+			entry.SeatedAt = shown.SeatedAt
+			if shown.SeatedAt != nil {
+				minutes := int(now.Sub(*shown.SeatedAt) / time.Minute)
+				entry.SeatedMinutes = &minutes
+			}
+			if table.MergedInto != nil {
+				plan = append(plan, entry)
+				continue
+			}
+			for _, orderID := range table.OrderIDs {
+				order, err := tc.orders.FindByID(ctx, orderID)
+				if errors.Is(err, repository.ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the orders of the tables"})
+					return
+				}
+				line := models.FloorPlanOrder{OrderID: order.OrderID, Status: order.CurrentStatus(), CreatedAt: order.CreatedAt}
+				if order.Totals != nil {
+					line.GrandTotal = &order.Totals.GrandTotal
+				}
+				entry.Orders = append(entry.Orders, line)
+			}
+			plan = append(plan, entry)
+		}
+		c.JSON(http.StatusOK, plan)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// errTableMerge marks merges and splits the tables involved do not allow.
+var errTableMerge = errors.New("tables cannot be merged or split")
+
+// tableMergeRequest is the body of POST /tables/:table_id/merge and
+// /tables/:table_id/split. A split without table_ids frees every table merged
+// into the table.
+type tableMergeRequest struct {
+	TableIDs []string `json:"table_ids" validate:"dive,required"`
+}
+
+// MergeTables pushes tables against the one in the path for a large party.
+// The party, its status and its orders stay on that table, and orders placed
+// for it report the numbers of every merged table.
+func (tc *TableController) MergeTables() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var request tableMergeRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(request.TableIDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "table_ids lists the tables to merge"})
+			return
+		}
+
+		var primary models.Table
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		err := tc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			primary, err = tc.tables.FindByID(ctx, c.Param("table_id"))
+			if err != nil {
+				return err
+			}
+			if primary.MergedInto != nil {
+				return fmt.Errorf("%w: table %s is itself merged into another table", errTableMerge, tableLabel(primary))
+			}
+			tables, err := tc.tables.List(ctx)
+			if err != nil {
+				return err
+			}
+			for _, tableID := range request.TableIDs {
+				table, err := mergeable(tables, primary, tableID)
+				if err != nil {
+					return err
+				}
+				table.MergedInto = &primary.TableID
+				table.UpdatedAt = now
+				if err := tc.tables.Update(ctx, &table); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			respondMergeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, primary)
+	}
+}
+
+// mergeable finds the table tableID among tables and checks that it can be
+// merged into primary: it must be free, in the same area and not already part
+// of a merge.
+func mergeable(tables []models.Table, primary models.Table, tableID string) (models.Table, error) {
+	var table *models.Table
+	for i := range tables {
+		if tables[i].TableID == tableID {
+			table = &tables[i]
+		}
+	}
+	if table == nil {
+		return models.Table{}, fmt.Errorf("%w: table %s was not found", errTableMerge, tableID)
+	}
+	if table.TableID == primary.TableID {
+		return models.Table{}, fmt.Errorf("%w: table %s cannot be merged into itself", errTableMerge, tableLabel(primary))
+	}
+	if table.MergedInto != nil {
+		return models.Table{}, fmt.Errorf("%w: table %s is already merged into another table", errTableMerge, tableLabel(*table))
+	}
+	for _, other := range tables {
+		if other.MergedInto != nil && *other.MergedInto == table.TableID {
+			return models.Table{}, fmt.Errorf("%w: tables are merged into table %s", errTableMerge, tableLabel(*table))
+		}
+	}
+	if status := table.CurrentStatus(); status != models.TableStatusAvailable {
+		return models.Table{}, fmt.Errorf("%w: table %s is %s", errTableMerge, tableLabel(*table), status)
+	}
+	if primary.AreaID != nil && table.AreaID != nil && *primary.AreaID != *table.AreaID {
+		return models.Table{}, fmt.Errorf("%w: table %s is in another area", errTableMerge, tableLabel(*table))
+	}
+	return *table, nil
+}
+
+// SplitTables takes tables merged into the one in the path apart again. They
+// need cleaning when a party has been sitting at them.
+func (tc *TableController) SplitTables() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var request tableMergeRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil && c.Request.ContentLength != 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		split := []models.Table{}
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		err := tc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			split = split[:0]
+			primary, err := tc.tables.FindByID(ctx, c.Param("table_id"))
+			if err != nil {
+				return err
+			}
+			tables, err := tc.tables.List(ctx)
+			if err != nil {
+				return err
+			}
+			merged, labels := map[string]models.Table{}, map[string]string{}
+			for _, table := range tables {
+				labels[table.TableID] = tableLabel(table)
+				if table.MergedInto != nil && *table.MergedInto == primary.TableID {
+					merged[table.TableID] = table
+				}
+			}
+			tableIDs := request.TableIDs
+			if len(tableIDs) == 0 {
+				for _, table := range tables {
+					if _, ok := merged[table.TableID]; ok {
+						tableIDs = append(tableIDs, table.TableID)
+					}
+				}
+			}
+
+			status := models.TableStatusAvailable
+			if primary.Occupied() || primary.CurrentStatus() == models.TableStatusDirty {
+				status = models.TableStatusDirty
+			}
+			for _, tableID := range tableIDs {
+				table, ok := merged[tableID]
+				if !ok {
+					label, found := labels[tableID]
+					if !found {
+						label = tableID
+					}
+					return fmt.Errorf("%w: table %s is not merged into table %s", errTableMerge, label, tableLabel(primary))
+				}
+				table.MergedInto = nil
+				table.MoveTo(status, now)
+				table.UpdatedAt = now
+				if err := tc.tables.Update(ctx, &table); err != nil {
+					return err
+				}
+				split = append(split, table)
+			}
+			return nil
+		})
+		if err != nil {
+			respondMergeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, split)
+	}
+}
+
+func respondMergeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+	case errors.Is(err, errTableMerge):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while merging or splitting tables"})
+	}
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
+)
+
+// errTableStatus marks moves the table state machine does not allow, such as
+// ordering at a table that is being cleaned.
+var errTableStatus = errors.New("table is not in a status that allows this")
+
+// attachOrder records a new order of the party at a table, which then shows as
+// ORDERED. Callers run it in the transaction that stores the order.
+func attachOrder(ctx context.Context, tables repository.TableRepository, tableID, orderID string, at time.Time) error {
+	table, err := tables.FindByID(ctx, tableID)
+	if err != nil {
+		return err
+	}
+	if table.MergedInto != nil {
+		return mergedError(ctx, tables, table)
+	}
+	status := table.CurrentStatus()
+	if status != models.TableStatusOrdered {
+		if !models.CanMoveTable(status, models.TableStatusOrdered) {
+			return fmt.Errorf("%w: table %s is %s", errTableStatus, tableLabel(table), status)
+		}
+		table.MoveTo(models.TableStatusOrdered, at)
+	}
+	if !slices.Contains(table.OrderIDs, orderID) {
+		table.OrderIDs = append(table.OrderIDs, orderID)
+	}
+	table.UpdatedAt = at
+	return tables.Update(ctx, &table)
+}
+
+// releaseOrder takes an order that was paid, closed, cancelled or moved off
+// its table. When it was the last one the table moves to whenEmpty: DIRTY once
+// the party has paid and left, SEATED when it is still there without orders.
+func releaseOrder(ctx context.Context, tables repository.TableRepository, tableID, orderID, whenEmpty string, at time.Time) error {
+	table, err := tables.FindByID(ctx, tableID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	i := slices.Index(table.OrderIDs, orderID)
+	if i < 0 {
+		return nil
+	}
+	table.OrderIDs = slices.Delete(table.OrderIDs, i, i+1)
+	status := table.CurrentStatus()
+	if len(table.OrderIDs) == 0 && (status == models.TableStatusOrdered || status == models.TableStatusBillRequested) {
+		table.MoveTo(whenEmpty, at)
+	}
+	table.UpdatedAt = at
+	return tables.Update(ctx, &table)
+}
+
+// seatParty puts a party of guests at an available table.
+func seatParty(ctx context.Context, tables repository.TableRepository, tableID string, guests int, at time.Time) error {
+	table, err := tables.FindByID(ctx, tableID)
+	if err != nil {
+		return err
+	}
+	if table.MergedInto != nil {
+		return mergedError(ctx, tables, table)
+	}
+	if status := table.CurrentStatus(); status != models.TableStatusAvailable {
+		return fmt.Errorf("%w: table %s is %s", errTableStatus, tableLabel(table), status)
+	}
+	table.MoveTo(models.TableStatusSeated, at)
+	table.Guests = &guests
+	table.UpdatedAt = at
+	return tables.Update(ctx, &table)
+}
+
+// seatsAt counts the guests table seats together with the tables merged into
+// it.
+func seatsAt(ctx context.Context, tables repository.TableRepository, table models.Table) (int, error) {
+	seats := 0
+	if table.NumberOfGuests != nil {
+		seats = *table.NumberOfGuests
+	}
+	all, err := tables.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, other := range all {
+		if other.MergedInto != nil && *other.MergedInto == table.TableID && other.NumberOfGuests != nil {
+			seats += *other.NumberOfGuests
+		}
+	}
+	return seats, nil
+}
+
+// mergedError refuses a party or an order at a table merged into another,
+// naming the table they belong to.
+func mergedError(ctx context.Context, tables repository.TableRepository, table models.Table) error {
+	primary, err := tables.FindByID(ctx, *table.MergedInto)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: table %s is merged into another table", errTableStatus, tableLabel(table))
+	}
+	return fmt.Errorf("%w: table %s is merged into table %s", errTableStatus, tableLabel(table), tableLabel(primary))
+}
+
+func tableLabel(table models.Table) string {
+	if table.TableNumber == nil {
+		return table.TableID
+	}
+	return fmt.Sprint(*table.TableNumber)
+}
+
+// moveTable follows an order moved to another table: the party takes the new
+// table and leaves the old one to be cleaned. Orders the old table no longer
+// keeps, such as paid ones, only change their table_id. An open order that
+// had no table yet takes the new one.
+func (p *orderPlacer) moveTable(ctx context.Context, order models.Order, tableID string, at time.Time) error {
+	if order.TableID == nil {
+		if len(models.NextOrderStatuses(order.CurrentStatus())) == 0 {
+			return nil
+		}
+		return attachOrder(ctx, p.tables, tableID, order.OrderID, at)
+	}
+	if *order.TableID == tableID {
+		return nil
+	}
+	from, err := p.tables.FindByID(ctx, *order.TableID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(from.OrderIDs, order.OrderID) {
+		return nil
+	}
+	if err := releaseOrder(ctx, p.tables, from.TableID, order.OrderID, models.TableStatusDirty, at); err != nil {
+		return err
+	}
+	return attachOrder(ctx, p.tables, tableID, order.OrderID, at)
+}
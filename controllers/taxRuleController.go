@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TaxRuleController struct {
+	taxRules repository.TaxRuleRepository
+}
+
+func NewTaxRuleController(taxRules repository.TaxRuleRepository) *TaxRuleController {
+	return &TaxRuleController{taxRules: taxRules}
+}
+
+func (tc *TaxRuleController) GetTaxRules() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		allTaxRules, err := tc.taxRules.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing tax rules"})
+			return
+		}
+		c.JSON(http.StatusOK, allTaxRules)
+	}
+}
+
+func (tc *TaxRuleController) GetTaxRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		taxRuleID := c.Param("tax_rule_id")
+
+		taxRule, err := tc.taxRules.FindByID(ctx, taxRuleID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tax rule was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the tax rule"})
+			return
+		}
+		c.JSON(http.StatusOK, taxRule)
+	}
+}
+
+func (tc *TaxRuleController) CreateTaxRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var taxRule models.TaxRule
+
+		if err := c.ShouldBindJSON(&taxRule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if taxRule.Active == nil {
+			active := true
+			taxRule.Active = &active
+		}
+		if err := validateTaxRule(taxRule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		taxRule.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		taxRule.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		taxRule.ID = primitive.NewObjectID()
+		taxRule.TaxRuleID = taxRule.ID.Hex()
+
+		if err := tc.taxRules.Create(ctx, &taxRule); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "tax rule was not created"})
+			return
+		}
+		c.JSON(http.StatusOK, taxRule)
+	}
+}
+
+// UpdateTaxRule changes a rule for orders priced from now on. Orders already
+// priced keep the tax lines they were totalled with until they change.
+func (tc *TaxRuleController) UpdateTaxRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var taxRule models.TaxRule
+
+		if err := c.ShouldBindJSON(&taxRule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		taxRuleID := c.Param("tax_rule_id")
+		existing, err := tc.taxRules.FindByID(ctx, taxRuleID)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tax rule was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "tax rule update failed"})
+			return
+		}
+
+		if taxRule.Name != nil {
+			existing.Name = taxRule.Name
+		}
+		if taxRule.Rate != nil {
+			existing.Rate = taxRule.Rate
+		}
+		if taxRule.Inclusive != nil {
+			existing.Inclusive = taxRule.Inclusive
+		}
+		if taxRule.Compound != nil {
+			existing.Compound = taxRule.Compound
+		}
+		if taxRule.Priority != nil {
+			existing.Priority = taxRule.Priority
+		}
+		if taxRule.Categories != nil {
+			existing.Categories = taxRule.Categories
+		}
+		if taxRule.ExemptTakeaway != nil {
+			existing.ExemptTakeaway = taxRule.ExemptTakeaway
+		}
+		if taxRule.Active != nil {
+			existing.Active = taxRule.Active
+		}
+		if err := validateTaxRule(existing); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		existing.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := tc.taxRules.Update(ctx, &existing); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "tax rule update failed"})
+			return
+		}
+		c.JSON(http.StatusOK, existing)
+	}
+}
+
+func validateTaxRule(taxRule models.TaxRule) error {
+	if err := validate.Struct(taxRule); err != nil {
+		return err
+	}
+	if taxRule.IsInclusive() && taxRule.IsCompound() {
+		return errors.New("a tax rule cannot be both inclusive and compound")
+	}
+	return nil
+}
@@ -1,25 +1,27 @@
 package controllers
 
 import (
-	"context"
-	"log"
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
+
 	"github.com/Micah-Shallom/modules/helpers"
 	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/repository"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func GetUsers() gin.HandlerFunc {
+type UserController struct {
+	users repository.UserRepository
+}
+
+func NewUserController(users repository.UserRepository) *UserController {
+	return &UserController{users: users}
+}
+
+func (uc *UserController) GetUsers() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if err := helpers.CheckUserType(c, "ADMIN"); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		ctx := c.Request.Context()
 
 		recordPerPage, err := strconv.Atoi(c.Query("recordPerPage"))
 		if err != nil || recordPerPage < 1 {
@@ -29,60 +31,59 @@ func GetUsers() gin.HandlerFunc {
 		if err1 != nil || page < 1 {
 			page = 1
 		}
-		startIndex := (page - 1 ) * recordPerPage
-		startIndex, err = strconv.Atoi(c.Query("startIndex"))
-		
-
-		matchStage := bson.D{
-			{"$match", bson.D{{}}},
-		}
-		groupStage := bson.D{
-			{"$group", bson.D{
-				{"_id", bson.D{{"_id", "null"}}},
-				{"total_count", bson.D{{"$sum", 1}}},
-				{"data", bson.D{{"$push", "$$ROOT"}}},
-			}},
-		}
-		projectStage := bson.D{
-			{"$project", bson.D{
-				{"_id", 0},
-				{"total_count", 1},
-				{"user_items", bson.D{{"$slice", []interface{}{"$data", startIndex, recordPerPage}}}},
-			}},
-		}
-		result, err := userCollection.Aggregate(ctx, mongo.Pipeline{
-			matchStage, groupStage, projectStage,
-		})
-		defer cancel()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":"error occured while listing user items"})
+		startIndex := (page - 1) * recordPerPage
+		if index, err := strconv.Atoi(c.Query("startIndex")); err == nil && index >= 0 {
+			startIndex = index
 		}
 
-		var allUsers []bson.M
-		if err = result.All(ctx, &allUsers); err != nil {
-			log.Fatal(err)
+		users, total, err := uc.users.List(ctx, startIndex, recordPerPage)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing user items"})
+			return
 		}
-		c.JSON(http.StatusOK, allUsers[0])
+		c.JSON(http.StatusOK, gin.H{"total_count": total, "user_items": users})
 	}
 }
 
-func GetUser() gin.HandlerFunc {
+func (uc *UserController) GetUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userId := c.Param("user_id")
+		userId := c.Param("userid")
 		if err := helpers.MatchUserTypeToUid(c, userId); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
 
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		ctx := c.Request.Context()
 
-		var user models.User
-		err := userCollection.FindOne(ctx, bson.M{"userid": userId}).Decode(&user)
-		defer cancel()
+		user, err := uc.users.FindByID(ctx, userId)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusOK, user)
 	}
-}
\ No newline at end of file
+}
+
+// CreateUser lets an admin register an account with any role, including the
+// staff roles that cannot be obtained through sign-up.
+func (uc *UserController) CreateUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var user models.User
+
+		if !bindNewUser(c, uc.users, &user) {
+			return
+		}
+
+		if err := uc.users.Create(ctx, &user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "User item was not created"})
+			return
+		}
+		user.Password = nil
+		c.JSON(http.StatusOK, user)
+	}
+}
@@ -0,0 +1,403 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Micah-Shallom/modules/helpers"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/notifications"
+	"github.com/Micah-Shallom/modules/repository"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	errInvalidWaitlist = errors.New("invalid waitlist entry")
+	errNoTableFits     = errors.New("no table in service seats the party")
+	errWaitlistStatus  = errors.New("waitlist entry is not in a status that allows this")
+	errNotifyFailed    = errors.New("the guest could not be notified")
+)
+
+type WaitlistController struct {
+	waitlist     repository.WaitlistRepository
+	tables       repository.TableRepository
+	reservations repository.ReservationRepository
+	transactions repository.Transactor
+	booking      helpers.Booking
+	// notifier texts guests; nil when notifications are turned off
+	notifier   notifications.Notifier
+	restaurant string
+}
+
+func NewWaitlistController(waitlist repository.WaitlistRepository, tables repository.TableRepository, reservations repository.ReservationRepository, transactions repository.Transactor, booking helpers.Booking, notifier notifications.Notifier, restaurant string) *WaitlistController {
+	return &WaitlistController{waitlist: waitlist, tables: tables, reservations: reservations, transactions: transactions, booking: booking, notifier: notifier, restaurant: restaurant}
+}
+
+// waitlistUpdate is the body of PATCH /waitlist/:entry_id; fields left out
+// are not changed.
+type waitlistUpdate struct {
+	GuestName *string `json:"guest_name" validate:"omitempty,max=100"`
+	Phone     *string `json:"phone" validate:"omitempty,max=20"`
+	PartySize *int    `json:"party_size" validate:"omitempty,min=1"`
+	Notes     *string `json:"notes" validate:"omitempty,max=200"`
+}
+
+// waitlistSeating is the body of POST /waitlist/:entry_id/seat.
+type waitlistSeating struct {
+	TableID string `json:"table_id" validate:"required"`
+}
+
+// GetWaitlist lists the parties still waiting, first come first, with how
+// long each has waited and is expected to wait still.
+func (wc *WaitlistController) GetWaitlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		now := time.Now()
+
+		entries, err := wc.waitlist.ListWaiting(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing the waitlist"})
+			return
+		}
+		sortWaitlist(entries)
+		tables, reservations, err := wc.floor(ctx, now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while estimating waits"})
+			return
+		}
+		history := helpers.NewTurnHistory(wc.booking, tables)
+
+		positions := make([]models.WaitlistPosition, 0, len(entries))
+		for i, entry := range entries {
+			position := models.WaitlistPosition{
+				WaitlistEntry: entry,
+				Position:      i + 1,
+				WaitedMinutes: int(now.Sub(entry.CreatedAt) / time.Minute),
+			}
+			if wait, ok := helpers.EstimateWait(tables, reservations, entries[:i], entry.PartySize, history, now); ok {
+				position.EstimatedMinutes = helpers.QuoteMinutes(wait)
+			}
+			positions = append(positions, position)
+		}
+		c.JSON(http.StatusOK, positions)
+	}
+}
+
+// GetWaitEstimate tells the host what wait to quote a party of ?party_size=
+// before adding it to the list.
+func (wc *WaitlistController) GetWaitEstimate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		partySize, err := strconv.Atoi(c.Query("party_size"))
+		if err != nil || partySize < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "party_size must be a positive number"})
+			return
+		}
+		entries, err := wc.waitlist.ListWaiting(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing the waitlist"})
+			return
+		}
+		minutes, err := wc.estimate(ctx, entries, partySize, time.Now())
+		if err != nil {
+			respondWaitlistError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, models.WaitEstimate{PartySize: partySize, Ahead: len(entries), EstimatedMinutes: minutes})
+	}
+}
+
+func (wc *WaitlistController) GetWaitlistEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entry, err := wc.waitlist.FindByID(c.Request.Context(), c.Param("entry_id"))
+		if err != nil {
+			respondWaitlistError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// AddToWaitlist puts a walk-in party at the end of the list. It is quoted the
+// estimated wait unless the host sends quoted_minutes, and texted the quote
+// when it left a phone number.
+func (wc *WaitlistController) AddToWaitlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var entry models.WaitlistEntry
+
+		if err := c.ShouldBindJSON(&entry); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(entry); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if entry.QuotedMinutes < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "quoted_minutes cannot be negative"})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if entry.QuotedMinutes == 0 {
+			entries, err := wc.waitlist.ListWaiting(ctx)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing the waitlist"})
+				return
+			}
+			if entry.QuotedMinutes, err = wc.estimate(ctx, entries, entry.PartySize, now); err != nil {
+				respondWaitlistError(c, err)
+				return
+			}
+		}
+		entry.ID = primitive.NewObjectID()
+		entry.EntryID = entry.ID.Hex()
+		entry.Status = models.WaitlistStatusWaiting
+		entry.AddedBy = c.GetString("uid")
+		entry.TableID, entry.TableNumber = nil, nil
+		entry.NotifiedAt, entry.SeatedAt, entry.CancelledAt = nil, nil, nil
+		entry.CreatedAt, entry.UpdatedAt = now, now
+
+		if err := wc.waitlist.Create(ctx, &entry); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "waitlist entry was not created"})
+			return
+		}
+		// the party is on the list whether or not the text goes out
+		body := fmt.Sprintf("Hi %s, you are on the waitlist at %s for %d. Your wait should be about %d minutes; we will text you when your table is ready.", entry.GuestName, wc.restaurant, entry.PartySize, entry.QuotedMinutes)
+		if err := wc.notify(ctx, entry, body); err != nil {
+			log.Printf("waitlist entry %s: %v", entry.EntryID, err)
+		}
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// UpdateWaitlistEntry changes the details of a waiting party. Its place in the
+// queue and its quote are kept.
+func (wc *WaitlistController) UpdateWaitlistEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var update waitlistUpdate
+
+		if err := c.ShouldBindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var entry models.WaitlistEntry
+		err := wc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			entry, err = wc.waitlist.FindByID(ctx, c.Param("entry_id"))
+			if err != nil {
+				return err
+			}
+			if !entry.Waiting() {
+				return fmt.Errorf("%w: the party is %s", errWaitlistStatus, entry.Status)
+			}
+			if update.GuestName != nil {
+				entry.GuestName = *update.GuestName
+			}
+			if update.Phone != nil {
+				entry.Phone = update.Phone
+			}
+			if update.PartySize != nil {
+				entry.PartySize = *update.PartySize
+			}
+			if update.Notes != nil {
+				entry.Notes = update.Notes
+			}
+			entry.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			return wc.waitlist.Update(ctx, &entry)
+		})
+		if err != nil {
+			respondWaitlistError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// NotifyWaitlistEntry tells a party its table is ready, by text when it left a
+// phone number and notifications are on. Calling it again sends a reminder. A
+// text that cannot be sent answers 502 and leaves the entry as it was.
+func (wc *WaitlistController) NotifyWaitlistEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		entry, err := wc.waitlist.FindByID(ctx, c.Param("entry_id"))
+		if err != nil {
+			respondWaitlistError(c, err)
+			return
+		}
+		if !models.CanMoveWaitlistEntry(entry.Status, models.WaitlistStatusNotified) {
+			respondWaitlistError(c, fmt.Errorf("%w: it cannot move from %s to %s", errWaitlistStatus, entry.Status, models.WaitlistStatusNotified))
+			return
+		}
+		body := fmt.Sprintf("Hi %s, your table at %s is ready. Please come to the host stand.", entry.GuestName, wc.restaurant)
+		if err := wc.notify(ctx, entry, body); err != nil {
+			log.Printf("waitlist entry %s: %v", entry.EntryID, err)
+			respondWaitlistError(c, errNotifyFailed)
+			return
+		}
+		wc.moveEntry(models.WaitlistStatusNotified, func(ctx context.Context, entry *models.WaitlistEntry, at time.Time) error {
+			entry.NotifiedAt = &at
+			return nil
+		})(c)
+	}
+}
+
+// SeatWaitlistEntry seats the party at the table it is given, which must be
+// AVAILABLE and seat the party together with the tables merged into it.
+func (wc *WaitlistController) SeatWaitlistEntry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request waitlistSeating
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validate.Struct(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		wc.moveEntry(models.WaitlistStatusSeated, func(ctx context.Context, entry *models.WaitlistEntry, at time.Time) error {
+			table, err := wc.tables.FindByID(ctx, request.TableID)
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("%w: table %s was not found", errInvalidWaitlist, request.TableID)
+			}
+			if err != nil {
+				return err
+			}
+			// a table merged into another is refused by seatParty
+			if table.MergedInto == nil {
+				seats, err := seatsAt(ctx, wc.tables, table)
+				if err != nil {
+					return err
+				}
+				if entry.PartySize > seats {
+					return fmt.Errorf("%w: table %s seats %d, the party is %d", errInvalidWaitlist, tableLabel(table), seats, entry.PartySize)
+				}
+			}
+			if err := seatParty(ctx, wc.tables, table.TableID, entry.PartySize, at); err != nil {
+				return err
+			}
+			entry.TableID, entry.TableNumber = &table.TableID, table.TableNumber
+			entry.SeatedAt = &at
+			return nil
+		})(c)
+	}
+}
+
+// CancelWaitlistEntry takes a party that gave up waiting off the list.
+func (wc *WaitlistController) CancelWaitlistEntry() gin.HandlerFunc {
+	return wc.moveEntry(models.WaitlistStatusCancelled, func(ctx context.Context, entry *models.WaitlistEntry, at time.Time) error {
+		entry.CancelledAt = &at
+		return nil
+	})
+}
+
+// moveEntry moves a waitlist entry to status to when its state machine allows
+// it, applying change in the same transaction.
+func (wc *WaitlistController) moveEntry(to string, change func(ctx context.Context, entry *models.WaitlistEntry, at time.Time) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		var entry models.WaitlistEntry
+		err := wc.transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			entry, err = wc.waitlist.FindByID(ctx, c.Param("entry_id"))
+			if err != nil {
+				return err
+			}
+			if !models.CanMoveWaitlistEntry(entry.Status, to) {
+				return fmt.Errorf("%w: it cannot move from %s to %s", errWaitlistStatus, entry.Status, to)
+			}
+			if err := change(ctx, &entry, now); err != nil {
+				return err
+			}
+			entry.Status = to
+			entry.UpdatedAt = now
+			return wc.waitlist.Update(ctx, &entry)
+		})
+		if err != nil {
+			respondWaitlistError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// estimate is the wait, in quoted minutes, of a party of partySize joining
+// the list behind entries.
+func (wc *WaitlistController) estimate(ctx context.Context, entries []models.WaitlistEntry, partySize int, now time.Time) (int, error) {
+	sortWaitlist(entries)
+	tables, reservations, err := wc.floor(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	wait, ok := helpers.EstimateWait(tables, reservations, entries, partySize, helpers.NewTurnHistory(wc.booking, tables), now)
+	if !ok {
+		return 0, fmt.Errorf("%w of %d", errNoTableFits, partySize)
+	}
+	return helpers.QuoteMinutes(wait), nil
+}
+
+// floor loads the tables and the reservations of the next hours that waits
+// are estimated from.
+func (wc *WaitlistController) floor(ctx context.Context, now time.Time) ([]models.Table, []models.Reservation, error) {
+	tables, err := wc.tables.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	reservations, err := wc.reservations.ListBetween(ctx, now, now.Add(24*time.Hour))
+	if err != nil {
+		return nil, nil, err
+	}
+	return tables, reservations, nil
+}
+
+// notify texts the party when it left a phone number and notifications are
+// on.
+func (wc *WaitlistController) notify(ctx context.Context, entry models.WaitlistEntry, body string) error {
+	if wc.notifier == nil || entry.Phone == nil || *entry.Phone == "" {
+		return nil
+	}
+	if err := wc.notifier.Notify(ctx, notifications.Message{To: *entry.Phone, Body: body}); err != nil {
+		return fmt.Errorf("%s notifier: %w", wc.notifier.Name(), err)
+	}
+	return nil
+}
+
+func sortWaitlist(entries []models.WaitlistEntry) {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+}
+
+func respondWaitlistError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "waitlist entry was not found"})
+	case errors.Is(err, errInvalidWaitlist):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errNoTableFits), errors.Is(err, errWaitlistStatus), errors.Is(err, errTableStatus):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errNotifyFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "waitlist entry changed concurrently, reload and retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "waitlist update failed"})
+	}
+}
@@ -4,38 +4,59 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-func DBInstance() *mongo.Client{
-	err := godotenv.Load(".env")
-
-	if err != nil {
-		log.Fatal(err)
+// Connect opens a client against uri and pings the primary, retrying up to
+// attempts times with a linearly growing delay so that the API can come up
+// before MongoDB is ready to accept connections.
+func Connect(ctx context.Context, uri string, attempts int, backoff time.Duration) (*mongo.Client, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	clientOpts := options.Client().ApplyURI(uri)
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		client, err := connectOnce(ctx, clientOpts)
+		if err == nil {
+			log.Println("Connected to MongoDB")
+			return client, nil
+		}
+		lastErr = err
+		log.Printf("connecting to MongoDB failed (attempt %d/%d): %v", attempt, attempts, err)
+		if attempt == attempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(attempt) * backoff):
+		}
 	}
+	return nil, fmt.Errorf("could not connect to MongoDB after %d attempts: %w", attempts, lastErr)
+}
 
-	mongoDB := os.Getenv("MONGO_URL")
-	clientOpts := options.Client().ApplyURI(mongoDB)
+func connectOnce(ctx context.Context, clientOpts *options.ClientOptions) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	client, err := mongo.Connect(ctx, clientOpts)
-	defer cancel()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	fmt.Println("Connected to MongoDB")
-
-	return client
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, err
+	}
+	return client, nil
 }
 
-var Client *mongo.Client = DBInstance()
-
-func OpenCollection(client *mongo.Client, collectionName string) *mongo.Collection{
-	var collection *mongo.Collection = client.Database("restaurant").Collection(collectionName)
-	return collection
-}
\ No newline at end of file
+func OpenDatabase(client *mongo.Client, name string) *mongo.Database {
+	return client.Database(name)
+}
@@ -2,29 +2,41 @@ package helpers
 
 import (
 	"errors"
+
+	"github.com/Micah-Shallom/modules/models"
 	"github.com/gin-gonic/gin"
 )
 
+// HasRole reports whether the authenticated user holds one of roles. Admins
+// satisfy every role requirement.
+func HasRole(c *gin.Context, roles ...string) bool {
+	userType := c.GetString("userType")
+	if userType == models.RoleAdmin {
+		return true
+	}
+	for _, role := range roles {
+		if userType == role {
+			return true
+		}
+	}
+	return false
+}
+
 func CheckUserType(c *gin.Context, role string) (err error) {
-	userType := c.GetString("usertype")
-	err = nil 
-	if userType != role {
-		err = errors.New("unauthorized to access this resource")
-		return err
+	if !HasRole(c, role) {
+		return errors.New("unauthorized to access this resource")
 	}
-	return err
+	return nil
 }
 
+// MatchUserTypeToUid lets admins and managers access any user record and
+// everyone else only their own.
 func MatchUserTypeToUid(c *gin.Context, userId string) (err error) {
-	userType := c.GetString("usertype")
-	uid := c.GetString("uid")
-	err = nil
-
-	if userType == "USER" && uid != userId {
-		err = errors.New("unauthorized to access this resource")
-		return err
+	if HasRole(c, models.RoleManager) {
+		return nil
 	}
-
-	err = CheckUserType(c, userType)
-	return err
-}
\ No newline at end of file
+	if c.GetString("uid") != userId {
+		return errors.New("unauthorized to access this resource")
+	}
+	return nil
+}
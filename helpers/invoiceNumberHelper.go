@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// InvoiceNumbering lays out invoice numbers such as LAG-2026-000123: one
+// sequence per location and fiscal year, formatted by a template with the
+// {location}, {year} and {seq} placeholders. {seq:6} pads the sequence with
+// zeros to six digits.
+type InvoiceNumbering struct {
+	Location string
+	Format   string
+	// FiscalYearStart is the month fiscal years start in. A fiscal year is
+	// named after the calendar year it starts in.
+	FiscalYearStart time.Month
+	TimeZone        *time.Location
+}
+
+var numberPlaceholder = regexp.MustCompile(`\{(location|year|seq)(?::(\d+))?\}`)
+
+// FiscalYear is the fiscal year at, in the restaurant's time zone.
+func (n InvoiceNumbering) FiscalYear(at time.Time) int {
+	at = at.In(n.TimeZone)
+	if at.Month() < n.FiscalYearStart {
+		return at.Year() - 1
+	}
+	return at.Year()
+}
+
+// Counter names the sequence invoices of fiscal year take their number from.
+func (n InvoiceNumbering) Counter(fiscalYear int) string {
+	return fmt.Sprintf("invoice:%s:%d", n.Location, fiscalYear)
+}
+
+func (n InvoiceNumbering) Number(fiscalYear int, seq int64) string {
+	return numberPlaceholder.ReplaceAllStringFunc(n.Format, func(placeholder string) string {
+		match := numberPlaceholder.FindStringSubmatch(placeholder)
+		switch match[1] {
+		case "location":
+			return n.Location
+		case "year":
+			return strconv.Itoa(fiscalYear)
+		}
+		width, _ := strconv.Atoi(match[2])
+		return fmt.Sprintf("%0*d", width, seq)
+	})
+}
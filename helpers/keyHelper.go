@@ -0,0 +1,283 @@
+package helpers
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// SigningMethodEdDSA adds Ed25519 signatures (RFC 8037), which jwt-go v3 does
+// not ship.
+var SigningMethodEdDSA = &signingMethodEd25519{}
+
+type signingMethodEd25519 struct{}
+
+func init() {
+	jwt.RegisterSigningMethod(AlgorithmEdDSA, func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (m *signingMethodEd25519) Alg() string {
+	return AlgorithmEdDSA
+}
+
+func (m *signingMethodEd25519) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return errors.New("ed25519: verification error")
+	}
+	return nil
+}
+
+func (m *signingMethodEd25519) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+// Key is one signing or verification key, identified by the kid header of the
+// tokens it signs.
+type Key struct {
+	ID        string
+	Algorithm string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens are
+// still accepted from. Rotating keys means signing with a new key while the
+// previous public keys stay in the verification set until their tokens expire.
+type KeySet struct {
+	signing      *Key
+	legacy       *Key
+	verification map[string]*Key
+	order        []string
+}
+
+// NewKeySet builds a key set. With HS256 tokens are signed with secret. With
+// RS256 or EdDSA they are signed with the PEM private key at signingKeyFile,
+// and secret, when set, stays valid for verifying previously issued HS256
+// tokens. verificationKeyFiles are extra PEM keys (public or private) that are
+// accepted but never used for signing.
+func NewKeySet(algorithm, secret, signingKeyFile string, verificationKeyFiles []string) (*KeySet, error) {
+	ks := &KeySet{verification: map[string]*Key{}}
+
+	var hmacKey *Key
+	if secret != "" {
+		hmacKey = newHMACKey(secret)
+		ks.legacy = hmacKey
+	}
+
+	switch algorithm {
+	case AlgorithmHS256:
+		if hmacKey == nil {
+			return nil, errors.New("HS256 signing requires a secret key")
+		}
+		ks.signing = hmacKey
+	case AlgorithmRS256, AlgorithmEdDSA:
+		key, err := loadPEMKey(signingKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if key.signKey == nil {
+			return nil, fmt.Errorf("%s does not contain a private key", signingKeyFile)
+		}
+		if key.Algorithm != algorithm {
+			return nil, fmt.Errorf("%s holds a %s key, not a %s key", signingKeyFile, key.Algorithm, algorithm)
+		}
+		ks.signing = key
+		if hmacKey != nil {
+			ks.add(hmacKey)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	ks.add(ks.signing)
+
+	for _, path := range verificationKeyFiles {
+		key, err := loadPEMKey(path)
+		if err != nil {
+			return nil, err
+		}
+		ks.add(key)
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) add(key *Key) {
+	if _, ok := ks.verification[key.ID]; ok {
+		return
+	}
+	ks.verification[key.ID] = key
+	ks.order = append(ks.order, key.ID)
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.ID
+	return token.SignedString(ks.signing.signKey)
+}
+
+// keyFunc picks the verification key from the kid header and refuses tokens
+// whose alg does not match that key, which rules out algorithm confusion.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && ks.legacy != nil {
+		// HS256 tokens issued before key ids were introduced
+		kid = ks.legacy.ID
+	}
+	key, ok := ks.verification[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// JWKS returns the public verification keys as a JSON Web Key Set. Shared
+// HS256 secrets are never published.
+func (ks *KeySet) JWKS() map[string]interface{} {
+	keys := []map[string]string{}
+	for _, kid := range ks.order {
+		key := ks.verification[kid]
+		if jwk := publicJWK(key); jwk != nil {
+			keys = append(keys, jwk)
+		}
+	}
+	return map[string]interface{}{"keys": keys}
+}
+
+func newHMACKey(secret string) *Key {
+	sum := sha256.Sum256([]byte("kid:" + secret))
+	return &Key{
+		ID:        "hs-" + base64.RawURLEncoding.EncodeToString(sum[:9]),
+		Algorithm: AlgorithmHS256,
+		method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+func loadPEMKey(path string) (*Key, error) {
+	if path == "" {
+		return nil, errors.New("a PEM key file is required for asymmetric signing")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	key := &Key{}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm, key.method, key.signKey, key.verifyKey = AlgorithmRS256, jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Algorithm, key.method, key.verifyKey = AlgorithmRS256, jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Algorithm, key.method, key.signKey, key.verifyKey = AlgorithmEdDSA, SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Algorithm, key.method, key.verifyKey = AlgorithmEdDSA, SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T", path, parsed)
+	}
+
+	jwk := publicJWK(key)
+	key.ID = jwkThumbprint(jwk)
+	return key, nil
+}
+
+func publicJWK(key *Key) map[string]string {
+	var jwk map[string]string
+	switch k := key.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk = map[string]string{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		jwk = map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(k),
+		}
+	default:
+		return nil
+	}
+	if key.ID != "" {
+		jwk["kid"] = key.ID
+		jwk["alg"] = key.Algorithm
+		jwk["use"] = "sig"
+	}
+	return jwk
+}
+
+// jwkThumbprint derives the key id from the RFC 7638 thumbprint of the public
+// key, so the same key file always yields the same kid.
+func jwkThumbprint(jwk map[string]string) string {
+	var members []string
+	switch jwk["kty"] {
+	case "RSA":
+		members = []string{"e", "kty", "n"}
+	case "OKP":
+		members = []string{"crv", "kty", "x"}
+	}
+	// encoding/json sorts map keys, which is the canonical member order
+	canonical := map[string]string{}
+	for _, name := range members {
+		canonical[name] = jwk[name]
+	}
+	data, _ := json.Marshal(canonical)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,110 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+)
+
+// MenuSchedule decides when menus are served. Times of day are read in the
+// time zone of the menu, or TimeZone when it names none.
+type MenuSchedule struct {
+	TimeZone *time.Location
+}
+
+// Location is the time zone the windows of menu are in.
+func (s MenuSchedule) Location(menu models.Menu) (*time.Location, error) {
+	if menu.TimeZone == nil || *menu.TimeZone == "" {
+		return s.TimeZone, nil
+	}
+	return time.LoadLocation(*menu.TimeZone)
+}
+
+// Check reports what is wrong with the schedule of menu: an unknown time
+// zone, a season that ends before it starts or windows that cannot be read.
+func (s MenuSchedule) Check(menu models.Menu) error {
+	if _, err := s.Location(menu); err != nil {
+		return fmt.Errorf("time_zone: %w", err)
+	}
+	if menu.StartDate != nil && menu.EndDate != nil && !menu.StartDate.Before(*menu.EndDate) {
+		return errors.New("end_date must be after start_date")
+	}
+	for i, window := range menu.Windows {
+		if _, _, err := windowClock(window); err != nil {
+			return fmt.Errorf("window %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Served reports whether menu is served at at.
+func (s MenuSchedule) Served(menu models.Menu, at time.Time) bool {
+	if menu.StartDate != nil && at.Before(*menu.StartDate) {
+		return false
+	}
+	if menu.EndDate != nil && !at.Before(*menu.EndDate) {
+		return false
+	}
+	if len(menu.Windows) == 0 {
+		return true
+	}
+	location, err := s.Location(menu)
+	if err != nil {
+		return false
+	}
+	// compared on the wall clock so that windows keep their local times on
+	// days the clocks change
+	local := at.In(location)
+	now := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	today := models.Weekdays[local.Weekday()]
+	yesterday := models.Weekdays[(local.Weekday()+6)%7]
+	for _, window := range menu.Windows {
+		opens, closes, err := windowClock(window)
+		if err != nil {
+			continue
+		}
+		if closes > opens {
+			if slices.Contains(window.Days, today) && now >= opens && now < closes {
+				return true
+			}
+			continue
+		}
+		// runs past midnight
+		if slices.Contains(window.Days, today) && now >= opens {
+			return true
+		}
+		if slices.Contains(window.Days, yesterday) && now < closes {
+			return true
+		}
+	}
+	return false
+}
+
+// windowClock reads the times of window as offsets from midnight. Closes may
+// be 24:00.
+func windowClock(window models.MenuWindow) (opens, closes time.Duration, err error) {
+	opens, ok := parseClock(window.Opens)
+	if !ok {
+		return 0, 0, fmt.Errorf("opens must be a time such as 07:00, got %q", window.Opens)
+	}
+	if strings.TrimSpace(window.Closes) == "24:00" {
+		return opens, 24 * time.Hour, nil
+	}
+	closes, ok = parseClock(window.Closes)
+	if !ok {
+		return 0, 0, fmt.Errorf("closes must be a time such as 11:00, got %q", window.Closes)
+	}
+	return opens, closes, nil
+}
+
+func parseClock(s string) (time.Duration, bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, true
+}
@@ -0,0 +1,76 @@
+package helpers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+)
+
+// PrepTimesByFood computes the preparation times of every food from the stage
+// timestamps of its ready items, sorted by food ID. Food names are left for
+// the caller to fill in.
+func PrepTimesByFood(items []models.OrderItem) []models.PrepTimes {
+	type samples struct {
+		items            int
+		prep, cook, pass []time.Duration
+	}
+	byFood := map[string]*samples{}
+	for _, item := range items {
+		if item.FoodID == nil || item.ReadyAt == nil {
+			continue
+		}
+		s, ok := byFood[*item.FoodID]
+		if !ok {
+			s = &samples{}
+			byFood[*item.FoodID] = s
+		}
+		s.items++
+
+		start := item.CreatedAt
+		if item.FiredAt != nil {
+			start = *item.FiredAt
+		}
+		s.prep = append(s.prep, item.ReadyAt.Sub(start))
+		if item.CookingAt != nil {
+			s.cook = append(s.cook, item.ReadyAt.Sub(*item.CookingAt))
+		}
+		if item.ServedAt != nil {
+			s.pass = append(s.pass, item.ServedAt.Sub(*item.ReadyAt))
+		}
+	}
+
+	times := make([]models.PrepTimes, 0, len(byFood))
+	for foodID, s := range byFood {
+		times = append(times, models.PrepTimes{
+			FoodID: foodID,
+			Items:  s.items,
+			Prep:   durationStats(s.prep),
+			Cook:   durationStats(s.cook),
+			Pass:   durationStats(s.pass),
+		})
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].FoodID < times[j].FoodID })
+	return times
+}
+
+// durationStats summarizes durations using nearest-rank percentiles.
+func durationStats(durations []time.Duration) models.DurationStats {
+	stats := models.DurationStats{Count: len(durations)}
+	if len(durations) == 0 {
+		return stats
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	percentile := func(p int) int64 {
+		rank := (p*len(durations) + 99) / 100
+		return int64(durations[max(rank, 1)-1].Seconds())
+	}
+	stats.AverageSeconds = int64((total / time.Duration(len(durations))).Seconds())
+	stats.MedianSeconds = percentile(50)
+	stats.P90Seconds = percentile(90)
+	return stats
+}
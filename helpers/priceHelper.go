@@ -0,0 +1,226 @@
+package helpers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Micah-Shallom/modules/models"
+)
+
+// Pricing holds the currency the restaurant trades in and the rates applied
+// on top of the order lines. Rates are fractions, so 0.075 is 7.5%. TaxRate
+// only applies while no tax rule is configured.
+type Pricing struct {
+	Currency          string
+	TaxRate           float64
+	ServiceChargeRate float64
+}
+
+// Price checks that amount is in the trading currency, filling it in when the
+// client sent a bare number.
+func (p Pricing) Price(amount models.Money) (models.Money, error) {
+	amount, err := amount.InCurrency(p.Currency)
+	if err != nil {
+		return amount, err
+	}
+	if amount.Currency != p.Currency {
+		return amount, fmt.Errorf("amounts must be in %s, got %s", p.Currency, amount.Currency)
+	}
+	return amount, nil
+}
+
+// LineTotal prices units of an item at unitPrice. Lines without units count
+// as one.
+func LineTotal(unitPrice models.Money, units *int) models.Money {
+	n := int64(1)
+	if units != nil {
+		n = int64(*units)
+	}
+	return unitPrice.Mul(n)
+}
+
+// taxable is an amount, in minor units, that tax rules are matched against.
+type taxable struct {
+	category string
+	amount   float64
+}
+
+// Totals sums the line totals of items and applies discount, service charge
+// and taxes, in that order. The discount is capped at the subtotal and shared
+// out over the lines in proportion to their amount, and the service charge is
+// levied on the discounted subtotal. Each line and the service charge are
+// then taxed by the active rules that apply to their category. Taxes are
+// summed unrounded per rule and rounded to the minor unit once.
+func (p Pricing) Totals(items []models.OrderItem, discount models.Money, takeaway bool, rules []models.TaxRule) (models.OrderTotals, error) {
+	totals := models.OrderTotals{Subtotal: models.NewMoney(0, p.Currency), Taxes: []models.TaxLine{}}
+	lines := make([]taxable, 0, len(items)+1)
+	for _, item := range items {
+		var line models.Money
+		if item.LineTotal != nil {
+			line = *item.LineTotal
+		} else if item.UnitPrice != nil {
+			line = LineTotal(*item.UnitPrice, item.Units)
+		}
+		line, err := p.Price(line)
+		if err != nil {
+			return totals, err
+		}
+		totals.Subtotal = totals.Subtotal.Add(line)
+
+		category := ""
+		if item.Category != nil {
+			category = *item.Category
+		}
+		lines = append(lines, taxable{category: category, amount: float64(line.Minor)})
+	}
+
+	discount, err := p.Price(discount)
+	if err != nil {
+		return totals, err
+	}
+	if discount.Cmp(totals.Subtotal) > 0 {
+		discount = totals.Subtotal
+	}
+	totals.Discount = discount
+	net := totals.Subtotal.Sub(totals.Discount)
+	if !totals.Subtotal.IsZero() {
+		share := float64(net.Minor) / float64(totals.Subtotal.Minor)
+		for i := range lines {
+			lines[i].amount *= share
+		}
+	}
+
+	totals.ServiceCharge = net.MulRate(p.ServiceChargeRate)
+	if !totals.ServiceCharge.IsZero() {
+		lines = append(lines, taxable{category: models.TaxServiceCharge, amount: float64(totals.ServiceCharge.Minor)})
+	}
+
+	totals.Tax = models.NewMoney(0, p.Currency)
+	totals.InclusiveTax = models.NewMoney(0, p.Currency)
+	for _, line := range p.taxLines(lines, takeaway, rules) {
+		if line.Inclusive {
+			totals.InclusiveTax = totals.InclusiveTax.Add(line.Amount)
+		} else {
+			totals.Tax = totals.Tax.Add(line.Amount)
+		}
+		totals.Taxes = append(totals.Taxes, line)
+	}
+	totals.GrandTotal = net.Add(totals.ServiceCharge).Add(totals.Tax)
+	return totals, nil
+}
+
+// taxLines evaluates rules against every amount. Inclusive taxes are carved
+// out of the amount first, leaving its net base. Exclusive taxes are levied on
+// that base in priority order; compound ones on the base plus the exclusive
+// taxes before them.
+func (p Pricing) taxLines(lines []taxable, takeaway bool, rules []models.TaxRule) []models.TaxLine {
+	rules = p.activeRules(rules)
+	amounts := make([]float64, len(rules))
+	bases := make([]float64, len(rules))
+
+	for _, line := range lines {
+		inclusiveRate := 0.0
+		for _, rule := range rules {
+			if rule.IsInclusive() && rule.AppliesTo(line.category, takeaway) {
+				inclusiveRate += *rule.Rate
+			}
+		}
+		base := line.amount / (1 + inclusiveRate)
+
+		levied := 0.0
+		for i, rule := range rules {
+			if !rule.AppliesTo(line.category, takeaway) {
+				continue
+			}
+			taxed := base
+			if rule.IsCompound() && !rule.IsInclusive() {
+				taxed += levied
+			}
+			tax := taxed * *rule.Rate
+			amounts[i] += tax
+			bases[i] += taxed
+			if !rule.IsInclusive() {
+				levied += tax
+			}
+		}
+	}
+
+	taxLines := []models.TaxLine{}
+	for i, rule := range rules {
+		if bases[i] == 0 {
+			continue
+		}
+		taxLines = append(taxLines, models.TaxLine{
+			TaxRuleID: rule.TaxRuleID,
+			Name:      *rule.Name,
+			Rate:      *rule.Rate,
+			Inclusive: rule.IsInclusive(),
+			Compound:  rule.IsCompound(),
+			Taxable:   models.NewMoney(int64(math.Round(bases[i])), p.Currency),
+			Amount:    models.NewMoney(int64(math.Round(amounts[i])), p.Currency),
+		})
+	}
+	return taxLines
+}
+
+// activeRules keeps the active rules in priority order. Without any, the flat
+// TaxRate is levied on everything, as before tax rules existed.
+func (p Pricing) activeRules(rules []models.TaxRule) []models.TaxRule {
+	active := []models.TaxRule{}
+	for _, rule := range rules {
+		if rule.IsActive() && rule.Name != nil && rule.Rate != nil {
+			active = append(active, rule)
+		}
+	}
+	if len(active) == 0 && p.TaxRate > 0 {
+		name, rate := "TAX", p.TaxRate
+		active = append(active, models.TaxRule{Name: &name, Rate: &rate})
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		return active[i].Rank() < active[j].Rank()
+	})
+	return active
+}
+
+// Allocate divides total into shares proportional to weights without losing
+// or inventing a minor unit: shares are rounded down and the units left over
+// go to the shares with the largest remainders, earlier shares first on ties.
+// Zero weights everywhere divide total evenly.
+func Allocate(total models.Money, weights []int64) []models.Money {
+	shares := make([]models.Money, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+	var sum int64
+	for _, w := range weights {
+		sum += w
+	}
+	if sum == 0 {
+		weights = make([]int64, len(weights))
+		for i := range weights {
+			weights[i] = 1
+		}
+		sum = int64(len(weights))
+	}
+
+	remainders := make([]int64, len(weights))
+	allocated := int64(0)
+	for i, w := range weights {
+		shares[i] = models.NewMoney(total.Minor*w/sum, total.Currency)
+		remainders[i] = total.Minor * w % sum
+		allocated += shares[i].Minor
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for i := int64(0); i < total.Minor-allocated; i++ {
+		shares[order[i%int64(len(order))]].Minor++
+	}
+	return shares
+}
@@ -0,0 +1,150 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/Micah-Shallom/modules/models"
+)
+
+func testItem(minor int64, units int, currency, category string) models.OrderItem {
+	price := models.NewMoney(minor, currency)
+	return models.OrderItem{UnitPrice: &price, Units: &units, Category: &category}
+}
+
+func testRule(name string, rate float64, priority int) models.TaxRule {
+	return models.TaxRule{TaxRuleID: name, Name: &name, Rate: &rate, Priority: &priority}
+}
+
+func TestTotals(t *testing.T) {
+	yes := true
+	drinksVAT := testRule("VAT", 0.1, 1)
+	drinksVAT.Categories = []string{"DRINK"}
+	inclusive := testRule("VAT", 0.15, 1)
+	inclusive.Inclusive = &yes
+	levy := testRule("LEVY", 0.1, 2)
+	compound := testRule("CITY", 0.05, 3)
+	compound.Compound = &yes
+	compound.ExemptTakeaway = &yes
+
+	tests := []struct {
+		name     string
+		pricing  Pricing
+		items    []models.OrderItem
+		discount models.Money
+		takeaway bool
+		rules    []models.TaxRule
+
+		subtotal, discounted, service, tax, inclusiveTax, grandTotal int64
+		taxes                                                        int
+	}{
+		{
+			name:     "discount shared out over the lines",
+			pricing:  Pricing{Currency: "NGN", ServiceChargeRate: 0.1},
+			items:    []models.OrderItem{testItem(3000, 2, "NGN", "FOOD"), testItem(4000, 1, "NGN", "DRINK")},
+			discount: models.NewMoney(1000, "NGN"),
+			rules:    []models.TaxRule{drinksVAT},
+			// the drink is 3600 of the 9000 left after the discount
+			subtotal: 10000, discounted: 1000, service: 900, tax: 360, grandTotal: 10260, taxes: 1,
+		},
+		{
+			name:     "discount larger than the subtotal",
+			pricing:  Pricing{Currency: "NGN", ServiceChargeRate: 0.1},
+			items:    []models.OrderItem{testItem(3000, 2, "NGN", "FOOD"), testItem(4000, 1, "NGN", "DRINK")},
+			discount: models.NewMoney(15000, "NGN"),
+			rules:    []models.TaxRule{drinksVAT},
+			subtotal: 10000, discounted: 10000, taxes: 0,
+		},
+		{
+			name:     "inclusive and compound rules",
+			pricing:  Pricing{Currency: "NGN"},
+			items:    []models.OrderItem{testItem(11500, 1, "NGN", "FOOD")},
+			rules:    []models.TaxRule{compound, levy, inclusive},
+			subtotal: 11500, tax: 1550, inclusiveTax: 1500, grandTotal: 13050, taxes: 3,
+		},
+		{
+			name:     "compound rule exempt for takeaway",
+			pricing:  Pricing{Currency: "NGN"},
+			items:    []models.OrderItem{testItem(11500, 1, "NGN", "FOOD")},
+			takeaway: true,
+			rules:    []models.TaxRule{compound, levy, inclusive},
+			subtotal: 11500, tax: 1000, inclusiveTax: 1500, grandTotal: 12500, taxes: 2,
+		},
+		{
+			name:     "flat rate rounded to the yen",
+			pricing:  Pricing{Currency: "JPY", TaxRate: 0.075},
+			items:    []models.OrderItem{testItem(1001, 1, "JPY", "FOOD")},
+			subtotal: 1001, tax: 75, grandTotal: 1076, taxes: 1,
+		},
+		{
+			name:     "service charge rounded to the fils",
+			pricing:  Pricing{Currency: "KWD", ServiceChargeRate: 0.125},
+			items:    []models.OrderItem{testItem(1234, 3, "KWD", "FOOD")},
+			subtotal: 3702, service: 463, grandTotal: 4165, taxes: 0,
+		},
+	}
+	for _, tt := range tests {
+		totals, err := tt.pricing.Totals(tt.items, tt.discount, tt.takeaway, tt.rules)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := []int64{totals.Subtotal.Minor, totals.Discount.Minor, totals.ServiceCharge.Minor, totals.Tax.Minor, totals.InclusiveTax.Minor, totals.GrandTotal.Minor}
+		want := []int64{tt.subtotal, tt.discounted, tt.service, tt.tax, tt.inclusiveTax, tt.grandTotal}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: got subtotal, discount, service, tax, inclusive tax and total %v, want %v", tt.name, got, want)
+				break
+			}
+		}
+		if len(totals.Taxes) != tt.taxes {
+			t.Errorf("%s: got %d tax lines, want %d", tt.name, len(totals.Taxes), tt.taxes)
+		}
+		if totals.GrandTotal.Currency != tt.pricing.Currency {
+			t.Errorf("%s: total in %q, want %s", tt.name, totals.GrandTotal.Currency, tt.pricing.Currency)
+		}
+	}
+
+	_, err := Pricing{Currency: "NGN"}.Totals([]models.OrderItem{testItem(1000, 1, "USD", "FOOD")}, models.Money{}, false, nil)
+	if err == nil {
+		t.Errorf("Totals of a line in another currency: got no error")
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		total   models.Money
+		weights []int64
+		want    []int64
+	}{
+		{models.NewMoney(10000, "NGN"), []int64{1, 1, 1}, []int64{3334, 3333, 3333}},
+		{models.NewMoney(1001, "NGN"), []int64{5, 3, 2}, []int64{501, 300, 200}},
+		{models.NewMoney(1000, "JPY"), []int64{7, 11, 13}, []int64{226, 355, 419}},
+		{models.NewMoney(10, "KWD"), []int64{1, 0, 2}, []int64{3, 0, 7}},
+		{models.NewMoney(5, "NGN"), []int64{0, 0}, []int64{3, 2}},
+		{models.NewMoney(5, "NGN"), []int64{}, []int64{}},
+	}
+	for _, tt := range tests {
+		shares := Allocate(tt.total, tt.weights)
+		var sum int64
+		got := make([]int64, len(shares))
+		for i, share := range shares {
+			got[i] = share.Minor
+			sum += share.Minor
+			if share.Currency != tt.total.Currency {
+				t.Errorf("Allocate(%v, %v): share %d in %q, want %s", tt.total, tt.weights, i, share.Currency, tt.total.Currency)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Allocate(%v, %v): got %v, want %v", tt.total, tt.weights, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Allocate(%v, %v): got %v, want %v", tt.total, tt.weights, got, tt.want)
+				break
+			}
+		}
+		if len(tt.weights) > 0 && sum != tt.total.Minor {
+			t.Errorf("Allocate(%v, %v): shares add up to %d, want %d", tt.total, tt.weights, sum, tt.total.Minor)
+		}
+	}
+}
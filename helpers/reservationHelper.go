@@ -0,0 +1,102 @@
+package helpers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+)
+
+// Booking holds the rules tables are reserved under. Opens and Closes are
+// offsets from local midnight in TimeZone between which bookings may start,
+// every SlotInterval.
+type Booking struct {
+	Opens        time.Duration
+	Closes       time.Duration
+	SlotInterval time.Duration
+	// TurnTimes is sorted by party size; a party keeps its table for the
+	// first turn time whose size it fits, or the last one.
+	TurnTimes   []TurnTime
+	NoShowAfter time.Duration
+	TimeZone    *time.Location
+}
+
+type TurnTime struct {
+	PartySize int
+	Duration  time.Duration
+}
+
+// TurnTime is how long a party of partySize keeps its table.
+func (b Booking) TurnTime(partySize int) time.Duration {
+	for _, turn := range b.TurnTimes {
+		if partySize <= turn.PartySize {
+			return turn.Duration
+		}
+	}
+	return b.TurnTimes[len(b.TurnTimes)-1].Duration
+}
+
+// Day parses a date such as 2026-10-20 as local midnight.
+func (b Booking) Day(date string) (time.Time, error) {
+	return time.ParseInLocation(time.DateOnly, date, b.TimeZone)
+}
+
+// Date is the local date of at, as accepted by Day.
+func (b Booking) Date(at time.Time) string {
+	return at.In(b.TimeZone).Format(time.DateOnly)
+}
+
+// Bookable reports whether a booking may start at at: within the hours of its
+// local day.
+func (b Booking) Bookable(at time.Time) bool {
+	local := at.In(b.TimeZone)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, b.TimeZone)
+	offset := local.Sub(midnight)
+	return offset >= b.Opens && offset <= b.Closes
+}
+
+// Slots lists the times bookings may start at on day, a local midnight as
+// returned by Day.
+func (b Booking) Slots(day time.Time) []time.Time {
+	var slots []time.Time
+	for offset := b.Opens; offset <= b.Closes; offset += b.SlotInterval {
+		// built from the wall clock so that slots keep their local time on
+		// days the clocks change
+		slots = append(slots, time.Date(day.Year(), day.Month(), day.Day(), 0, 0, int(offset/time.Second), 0, b.TimeZone))
+	}
+	return slots
+}
+
+// FreeTables lists the tables that seat partySize and that no reservation but
+// except holds between start and end, smallest first so that large tables are
+// kept for large parties. Tables merged into another are not offered on their
+// own.
+func FreeTables(tables []models.Table, reservations []models.Reservation, partySize int, start, end time.Time, except string) []models.Table {
+	held := map[string]bool{}
+	for _, reservation := range reservations {
+		if reservation.ReservationID != except && reservation.Holds(start, end) {
+			held[reservation.TableID] = true
+		}
+	}
+	free := []models.Table{}
+	for _, table := range tables {
+		if table.MergedInto != nil || table.NumberOfGuests == nil || *table.NumberOfGuests < partySize || held[table.TableID] {
+			continue
+		}
+		free = append(free, table)
+	}
+	sort.SliceStable(free, func(i, j int) bool {
+		if *free[i].NumberOfGuests != *free[j].NumberOfGuests {
+			return *free[i].NumberOfGuests < *free[j].NumberOfGuests
+		}
+		return tableNumber(free[i]) < tableNumber(free[j])
+	})
+	return free
+}
+
+func tableNumber(table models.Table) int {
+	if table.TableNumber == nil {
+		return 0
+	}
+	return *table.TableNumber
+}
@@ -1,17 +1,17 @@
 package helpers
 
 import (
-	"context"
-	"log"
-	"os"
-	"time"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"github.com/Micah-Shallom/modules/database"
+	"time"
+
 	"github.com/dgrijalva/jwt-go"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
 )
 
 type SignedDetails struct {
@@ -20,96 +20,112 @@ type SignedDetails struct {
 	LastName  string
 	Uid       string
 	UserType  string
+	SessionID string
+	TokenType string
 	jwt.StandardClaims
 }
 
-var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
+type TokenMaker struct {
+	keys            *KeySet
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+}
+
+func NewTokenMaker(keys *KeySet, accessTokenTTL, refreshTokenTTL time.Duration) *TokenMaker {
+	return &TokenMaker{
+		keys:            keys,
+		accessTokenTTL:  accessTokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+	}
+}
+
+// JWKS publishes the public keys tokens can be verified with.
+func (tm *TokenMaker) JWKS() map[string]interface{} {
+	return tm.keys.JWKS()
+}
 
-var SECRET_KEY = os.Getenv("SECRET_KEY")
+// RefreshTokenExpiry is the expiry of a refresh token issued now.
+func (tm *TokenMaker) RefreshTokenExpiry() time.Time {
+	return time.Now().Add(tm.refreshTokenTTL)
+}
 
-func GenerateAllTokens(email string, firstName string, lastName string, userType string, uid string) (signedToken string, signedRefreshToken string, err error) {
+// GenerateAllTokens signs an access token and a refresh token for the session
+// sessionID. refreshTokenID becomes the jti of the refresh token and is what
+// the session records as its current token.
+func (tm *TokenMaker) GenerateAllTokens(email string, firstName string, lastName string, userType string, uid string, sessionID string, refreshTokenID string) (signedToken string, signedRefreshToken string, err error) {
 	claims := &SignedDetails{
-		Email: email,
+		Email:     email,
 		FirstName: firstName,
-		LastName: lastName,
-		Uid: uid,
-		UserType: userType,
+		LastName:  lastName,
+		Uid:       uid,
+		UserType:  userType,
+		SessionID: sessionID,
+		TokenType: AccessToken,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(24)).Unix(),
+			ExpiresAt: time.Now().Local().Add(tm.accessTokenTTL).Unix(),
 		},
 	}
 
 	refreshClaims := &SignedDetails{
+		Uid:       uid,
+		SessionID: sessionID,
+		TokenType: RefreshToken,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(168)).Unix(),
+			Id:        refreshTokenID,
+			ExpiresAt: time.Now().Local().Add(tm.refreshTokenTTL).Unix(),
 		},
 	}
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256,claims).SignedString([]byte(SECRET_KEY))
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString([]byte(SECRET_KEY))
-
+	token, err := tm.keys.sign(claims)
 	if err != nil {
-		log.Panic(err)
-		return
+		return "", "", err
 	}
-	return token, refreshToken, err
+	refreshToken, err := tm.keys.sign(refreshClaims)
+	if err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
+func (tm *TokenMaker) ValidateToken(signedToken string) (claims *SignedDetails, msg string) {
+	return tm.validate(signedToken, AccessToken)
 }
 
-func ValidateToken(signedToken string)(claims *SignedDetails, msg string) {
+func (tm *TokenMaker) ValidateRefreshToken(signedToken string) (claims *SignedDetails, msg string) {
+	return tm.validate(signedToken, RefreshToken)
+}
+
+func (tm *TokenMaker) validate(signedToken string, tokenType string) (claims *SignedDetails, msg string) {
 	token, err := jwt.ParseWithClaims(
 		signedToken,
 		&SignedDetails{},
-		func(token *jwt.Token)(interface {}, error){
-			return []byte(SECRET_KEY), nil
-		},
+		tm.keys.keyFunc,
 	)
 	if err != nil {
 		msg = err.Error()
 		return
 	}
 	claims, ok := token.Claims.(*SignedDetails)
-	if !ok {
-		msg = fmt.Sprintf("the token is invalid")
-		msg = err.Error()
+	if !ok || !token.Valid {
+		msg = "the token is invalid"
 		return
 	}
-	if claims.ExpiresAt < time.Now().Local().Unix(){
-		msg = fmt.Sprintf("Token is expired")
-		msg = err.Error()
-		return
+	if claims.TokenType != tokenType {
+		msg = fmt.Sprintf("wrong token type, %s token expected", tokenType)
+		return nil, msg
+	}
+	if claims.ExpiresAt < time.Now().Local().Unix() {
+		msg = "Token is expired"
+		return nil, msg
 	}
 	return claims, msg
 }
 
-
-func UpdateAllTokens(signedToken string, signedRefreshToken string, userid string) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-	var updateObj primitive.D
-
-	updateObj = append(updateObj, bson.E{"token", signedToken})
-	updateObj = append(updateObj, bson.E{"refresh_token", signedRefreshToken})
-
-	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-	updateObj = append(updateObj, bson.E{"updatedAt", Updated_at})
-
-	upsert := true
-	filter := bson.M{"userid": userid}
-	opt := options.UpdateOptions{
-		Upsert: &upsert,
+// NewTokenID returns a random identifier for sessions and refresh tokens.
+func NewTokenID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
 	}
-	_, err := userCollection.UpdateOne(
-		ctx,
-		filter,
-		bson.D{
-			{"$set", updateObj},
-		},
-		&opt,
-	)
-	defer cancel()
-	if err != nil {
-		log.Panic(err)
-		return
-	}
-	// return
-}
\ No newline at end of file
+	return hex.EncodeToString(b)
+}
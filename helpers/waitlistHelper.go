@@ -0,0 +1,163 @@
+package helpers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+)
+
+const (
+	// minTurns is how many past parties of a size are needed before their
+	// average stay replaces the configured turn time.
+	minTurns = 3
+	// cleaningTime is how long a table takes to clear and reset after a
+	// party has left.
+	cleaningTime = 5 * time.Minute
+	// overdue is how soon a party that has outstayed its turn time is
+	// expected to leave.
+	overdue = 5 * time.Minute
+	// quoteStep is what waits are rounded up to, as hosts quote them.
+	quoteStep = 5 * time.Minute
+)
+
+// TurnHistory knows how long parties of each size keep their tables, from the
+// last turns recorded on the tables or, until there are enough of them, the
+// turn times of the booking rules.
+type TurnHistory struct {
+	booking  Booking
+	averages map[int]time.Duration
+}
+
+// NewTurnHistory averages the turns recorded on tables for each size of party
+// in the turn times of booking.
+func NewTurnHistory(booking Booking, tables []models.Table) TurnHistory {
+	totals, counts := map[int]time.Duration{}, map[int]int{}
+	for _, table := range tables {
+		for _, turn := range table.Turns {
+			if turn.Guests == nil || turn.Duration() <= 0 {
+				continue
+			}
+			size := booking.turnSize(*turn.Guests)
+			totals[size] += turn.Duration()
+			counts[size]++
+		}
+	}
+	averages := map[int]time.Duration{}
+	for size, count := range counts {
+		if count >= minTurns {
+			averages[size] = totals[size] / time.Duration(count)
+		}
+	}
+	return TurnHistory{booking: booking, averages: averages}
+}
+
+// TurnTime is how long a party of partySize is expected to keep its table.
+func (h TurnHistory) TurnTime(partySize int) time.Duration {
+	if average, ok := h.averages[h.booking.turnSize(partySize)]; ok {
+		return average
+	}
+	return h.booking.TurnTime(partySize)
+}
+
+// turnSize is the largest party size of the turn time a party of partySize
+// falls under.
+func (b Booking) turnSize(partySize int) int {
+	for _, turn := range b.TurnTimes {
+		if partySize <= turn.PartySize {
+			return turn.PartySize
+		}
+	}
+	return b.TurnTimes[len(b.TurnTimes)-1].PartySize
+}
+
+// EstimateWait estimates how long a party of partySize joining the waitlist
+// now waits for a table. Every table is expected to free up once its party has
+// stayed its turn time and the table has been cleaned, and to stay taken while
+// a reservation holds it. The parties ahead, oldest first, take the first
+// table that seats them before this one does. ok is false when no table in
+// service seats the party.
+func EstimateWait(tables []models.Table, reservations []models.Reservation, ahead []models.WaitlistEntry, partySize int, history TurnHistory, now time.Time) (wait time.Duration, ok bool) {
+	type freeTable struct {
+		table models.Table
+		at    time.Time
+	}
+	var free []freeTable
+	for _, table := range tables {
+		if table.MergedInto != nil || table.NumberOfGuests == nil {
+			continue
+		}
+		at := now
+		switch table.CurrentStatus() {
+		case models.TableStatusOutOfService:
+			continue
+		case models.TableStatusDirty:
+			at = now.Add(cleaningTime)
+		case models.TableStatusSeated, models.TableStatusOrdered, models.TableStatusBillRequested:
+			guests := *table.NumberOfGuests
+			if table.Guests != nil {
+				guests = *table.Guests
+			}
+			at = now.Add(overdue)
+			if table.SeatedAt != nil {
+				at = laterOf(at, table.SeatedAt.Add(history.TurnTime(guests)+cleaningTime))
+			}
+		}
+		free = append(free, freeTable{table: table, at: at})
+	}
+	sort.SliceStable(free, func(i, j int) bool {
+		return *free[i].table.NumberOfGuests < *free[j].table.NumberOfGuests
+	})
+
+	// start is when a party of size can sit at free[i] for its whole turn
+	// without running into a reservation.
+	start := func(i, size int) time.Time {
+		at, turn := free[i].at, history.TurnTime(size)
+		for moved := true; moved; {
+			moved = false
+			for _, reservation := range reservations {
+				if reservation.TableID == free[i].table.TableID && reservation.Holds(at, at.Add(turn)) {
+					at, moved = reservation.EndsAt.Add(cleaningTime), true
+				}
+			}
+		}
+		return at
+	}
+	// first finds the table a party of size gets soonest, smallest first.
+	first := func(size int) (int, time.Time) {
+		best, bestAt := -1, time.Time{}
+		for i := range free {
+			if *free[i].table.NumberOfGuests < size {
+				continue
+			}
+			if at := start(i, size); best < 0 || at.Before(bestAt) {
+				best, bestAt = i, at
+			}
+		}
+		return best, bestAt
+	}
+
+	for _, entry := range ahead {
+		if i, at := first(entry.PartySize); i >= 0 {
+			free[i].at = at.Add(history.TurnTime(entry.PartySize) + cleaningTime)
+		}
+	}
+	i, at := first(partySize)
+	if i < 0 {
+		return 0, false
+	}
+	return max(0, at.Sub(now)), true
+}
+
+// QuoteMinutes rounds a wait up to the minutes a host would quote.
+func QuoteMinutes(wait time.Duration) int {
+	steps := (wait + quoteStep - 1) / quoteStep
+	return int(steps * quoteStep / time.Minute)
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,106 @@
+// Package kds pushes kitchen ticket changes to the kitchen displays that are
+// watching their station.
+package kds
+
+import (
+	"sync"
+
+	"github.com/Micah-Shallom/modules/models"
+)
+
+// Types of event.
+const (
+	// TicketCreated tickets join the queue of their station.
+	TicketCreated = "ticket.created"
+	// TicketUpdated tickets changed: their dish was edited, or they were
+	// bumped or recalled.
+	TicketUpdated = "ticket.updated"
+	// TicketRemoved tickets leave their station for good.
+	TicketRemoved = "ticket.removed"
+)
+
+// buffer is how many events a display may fall behind before it is dropped.
+const buffer = 64
+
+type Event struct {
+	Type   string               `json:"type"`
+	Ticket models.KitchenTicket `json:"ticket"`
+}
+
+// Hub fans events out to the displays subscribed to the station of their
+// ticket. It lives in the memory of one process, so every display must be
+// connected to the instance that handles the changes.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+	closed      bool
+}
+
+type subscriber struct {
+	stationID string
+	events    chan Event
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: map[*subscriber]struct{}{}}
+}
+
+// Subscribe returns the events of a station and a function to stop receiving
+// them. The channel is closed when the subscriber falls too far behind, in
+// which case it should reload the queue of the station and subscribe again,
+// and when the hub is closed.
+func (h *Hub) Subscribe(stationID string) (<-chan Event, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := &subscriber{stationID: stationID, events: make(chan Event, buffer)}
+	if h.closed {
+		close(s.events)
+		return s.events, func() {}
+	}
+	h.subscribers[s] = struct{}{}
+	return s.events, func() { h.drop(s) }
+}
+
+// Publish delivers events without waiting for slow subscribers.
+func (h *Hub) Publish(events ...Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, event := range events {
+		for s := range h.subscribers {
+			if s.stationID != event.Ticket.StationID {
+				continue
+			}
+			select {
+			case s.events <- event:
+			default:
+				h.dropLocked(s)
+			}
+		}
+	}
+}
+
+// Close ends every subscription, so that streams let the server shut down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for s := range h.subscribers {
+		h.dropLocked(s)
+	}
+}
+
+func (h *Hub) drop(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dropLocked(s)
+}
+
+func (h *Hub) dropLocked(s *subscriber) {
+	if _, ok := h.subscribers[s]; ok {
+		delete(h.subscribers, s)
+		close(s.events)
+	}
+}
@@ -1,28 +1,30 @@
 package main
 
 import (
+	"context"
+	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/Micah-Shallom/modules/routes"
-	"github.com/gin-gonic/gin"
+	"github.com/Micah-Shallom/modules/app"
+	"github.com/Micah-Shallom/modules/config"
 )
 
 func main() {
-	port := os.Getenv("PORT")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if port == ""{
-		port = "8000"
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	router := gin.New()
-	router.Use(gin.Logger())
-	routes.UserRoutes(router)
-	routes.AuthRoutes(router)
-
-	routes.FoodRoutes(router)
-	routes.InvoiceRoutes(router)
-	routes.MenuRoutes(router)
-	routes.OrderRoutes(router)
-	routes.OrderItemsRoutes(router)
-	routes.TableRoutes(router)
-}
\ No newline at end of file
+	application, err := app.New(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := application.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
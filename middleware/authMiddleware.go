@@ -1,24 +1,37 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/Micah-Shallom/modules/helpers"
+	"github.com/Micah-Shallom/modules/repository"
 	"github.com/gin-gonic/gin"
 )
 
-func Authenticate() gin.HandlerFunc {
-	return func(c *gin.Context){
-		clientToken := c.Request.Header.Get("token")
-		if clientToken == ""{
-			c.JSON(http.StatusInternalServerError, gin.H{"error":fmt.Sprintf("No Authorization Header Provided")})
+// Authenticate validates the access token and checks that its session has not
+// been revoked by a logout or a detected refresh-token reuse.
+func Authenticate(tokens *helpers.TokenMaker, sessions repository.SessionRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientToken := bearerToken(c.Request.Header.Get("Authorization"))
+		if clientToken == "" {
+			// legacy header kept for existing clients
+			clientToken = c.Request.Header.Get("token")
+		}
+		if clientToken == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "No Authorization Header Provided"})
 			c.Abort()
 			return
 		}
-		claims, err := helpers.ValidateToken(clientToken)
+		claims, err := tokens.ValidateToken(clientToken)
 		if err != "" {
-			c.JSON(http.StatusInternalServerError, gin.H{"error":err})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err})
+			c.Abort()
+			return
+		}
+		session, sessionErr := sessions.FindByID(c.Request.Context(), claims.SessionID)
+		if sessionErr != nil || session.Revoked || session.UserID != claims.Uid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "session has ended, please log in again"})
 			c.Abort()
 			return
 		}
@@ -27,6 +40,33 @@ func Authenticate() gin.HandlerFunc {
 		c.Set("lastname", claims.LastName)
 		c.Set("uid", claims.Uid)
 		c.Set("userType", claims.UserType)
+		c.Set("sid", claims.SessionID)
+		c.Next()
+	}
+}
+
+func bearerToken(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Authorize is the policy check attached to each route: the request must come
+// from a user holding one of roles. It must run after Authenticate.
+func Authorize(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("userType"); !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			c.Abort()
+			return
+		}
+		if !helpers.HasRole(c, roles...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized to access this resource"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
-}
\ No newline at end of file
+}
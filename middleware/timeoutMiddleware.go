@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestTimeout bounds the request context so that every repository call made
+// by a handler is cancelled once the configured timeout elapses. The event
+// stream routes listed in streams, matched by their route pattern, are left
+// unbounded: they stay open for as long as the client listens.
+func RequestTimeout(timeout time.Duration, streams ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(streams, c.FullPath()) {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	TableShapeRound     = "ROUND"
+	TableShapeSquare    = "SQUARE"
+	TableShapeRectangle = "RECTANGLE"
+)
+
+// Area is a part of the floor such as the patio, the main hall or the bar,
+// drawn as a plan Width by Height units across that its tables are placed on.
+type Area struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	AreaID    string             `bson:"area_id" json:"area_id"`
+	Name      string             `bson:"name" json:"name" validate:"required,min=2,max=50"`
+	Width     float64            `bson:"width" json:"width" validate:"gt=0"`
+	Height    float64            `bson:"height" json:"height" validate:"gt=0"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// TableLayout places a table on the plan of its area: X and Y are its centre,
+// in the units of the area, and Rotation is in degrees clockwise.
+type TableLayout struct {
+	X        float64 `bson:"x" json:"x" validate:"min=0"`
+	Y        float64 `bson:"y" json:"y" validate:"min=0"`
+	Width    float64 `bson:"width" json:"width" validate:"gt=0"`
+	Height   float64 `bson:"height" json:"height" validate:"gt=0"`
+	Rotation float64 `bson:"rotation" json:"rotation" validate:"min=0,lt=360"`
+	Shape    string  `bson:"shape" json:"shape" validate:"required,oneof=ROUND SQUARE RECTANGLE"`
+}
+
+// Fits reports whether the centre of the table lies on the plan of area.
+func (l TableLayout) Fits(area Area) bool {
+	return l.X <= area.Width && l.Y <= area.Height
+}
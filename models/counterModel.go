@@ -0,0 +1,8 @@
+package models
+
+// Counter is a named sequence, such as the invoice numbers of one location
+// and fiscal year. Seq is the last value handed out.
+type Counter struct {
+	Name string `bson:"_id"`
+	Seq  int64  `bson:"seq"`
+}
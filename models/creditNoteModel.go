@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreditNote documents money credited back against an invoice, either by a
+// refund of one of its payments or by voiding the invoice. It is never edited
+// after it is issued.
+type CreditNote struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	CreditNoteID  string             `bson:"credit_note_id" json:"credit_note_id"`
+	InvoiceID     string             `bson:"invoice_id" json:"invoice_id"`
+	InvoiceNumber string             `bson:"invoice_number" json:"invoice_number"`
+	Kind          string             `bson:"kind" json:"kind"`
+	PaymentID     string             `bson:"payment_id" json:"payment_id"`
+	RefundID      string             `bson:"refund_id" json:"refund_id"`
+	Amount        Money              `bson:"amount" json:"amount"`
+	ReasonCode    string             `bson:"reason_code" json:"reason_code"`
+	Note          string             `bson:"note" json:"note"`
+	IssuedBy      string             `bson:"issued_by" json:"issued_by"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+}
+
+const (
+	CreditNoteRefund = "REFUND"
+	CreditNoteVoid   = "VOID"
+)
+
+// Reason codes every refund and void must give.
+const (
+	ReasonCustomerComplaint = "CUSTOMER_COMPLAINT"
+	ReasonOrderError        = "ORDER_ERROR"
+	ReasonDuplicatePayment  = "DUPLICATE_PAYMENT"
+	ReasonItemUnavailable   = "ITEM_UNAVAILABLE"
+	ReasonWalkout           = "WALKOUT"
+	ReasonOther             = "OTHER"
+)
+
+// Reversal records who reversed something, when and why.
+type Reversal struct {
+	ReasonCode string    `bson:"reason_code" json:"reason_code" validate:"required,oneof=CUSTOMER_COMPLAINT ORDER_ERROR DUPLICATE_PAYMENT ITEM_UNAVAILABLE WALKOUT OTHER"`
+	Note       string    `bson:"note" json:"note" validate:"max=500"`
+	By         string    `bson:"by" json:"by"`
+	At         time.Time `bson:"at" json:"at"`
+}
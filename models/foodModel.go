@@ -7,12 +7,13 @@ import (
 )
 
 type Food struct {
-	ID 				primitive.ObjectID			`bson:"_id"`
-	Name 			*string						`json:"name" validate:"required,min=2,max=100"`
-	Price 			*float64					`json:"price" validate:"required,min=2,max=100"`
-	FoodImage		*string						`json:"food_image" validate:"required"`
-	CreatedAt		time.Time					`json:"created_at"`
-	UpdatedAt		time.Time					`json:"updated_at"`
-	FoodID			string						`json:"food_id"`
-	MenuID			*string						`json:"menu_id"`
-}
\ No newline at end of file
+	ID        primitive.ObjectID `bson:"_id"`
+	Name      *string            `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	Price     *Money             `bson:"price" json:"price" validate:"required"`
+	FoodImage *string            `bson:"food_image" json:"food_image" validate:"required"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	FoodID    string             `bson:"food_id" json:"food_id"`
+	MenuID    *string            `bson:"menu_id" json:"menu_id"`
+	Modifiers []ModifierGroup    `bson:"modifiers" json:"modifiers" validate:"dive"`
+}
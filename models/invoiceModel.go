@@ -2,28 +2,157 @@ package models
 
 import (
 	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Invoice struct {
-	ID 						primitive.ObjectID	 `bson:"_id"`
-	InvoiceID				string				 `json:"invoice_id"`
-	OrderID					string				 `json:"order_id"`
-	PaymentMethod			*string				 `json:"payment_method" validate:"eq=CARD|eq=CASH|eq="`
-	PaymentStatus			*string				 `json:"payment_status" validate:"required,eq=PENDING|eq=PAID"`
-	PaymentDueDate			time.Time			 `json:"payment_due_date"`
-	CreatedAt				time.Time			 `json:"created_at"`
-	UpdatedAt				time.Time			 `json:"updated_at"`
-}		
+	ID             primitive.ObjectID `bson:"_id"`
+	InvoiceID      string             `bson:"invoice_id" json:"invoice_id"`
+	InvoiceNumber  string             `bson:"invoice_number" json:"invoice_number"`
+	OrderID        string             `bson:"order_id" json:"order_id"`
+	PaymentMethod  *string            `bson:"payment_method" json:"payment_method" validate:"eq=CARD|eq=CASH|eq="`
+	PaymentStatus  *string            `bson:"payment_status" json:"payment_status" validate:"required,eq=PENDING|eq=PARTIALLY_PAID|eq=PAID|eq=REFUNDED|eq=VOIDED"`
+	PaymentDueDate time.Time          `bson:"payment_due_date" json:"payment_due_date"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	Total          *Money             `bson:"total" json:"total"`
+	Paid           *Money             `bson:"paid" json:"paid"`
+	Balance        *Money             `bson:"balance" json:"balance"`
+	Refunded       *Money             `bson:"refunded" json:"refunded"`
+	Voided         *Reversal          `bson:"voided" json:"voided"`
+	SplitMode      string             `bson:"split_mode" json:"split_mode"`
+	Splits         []InvoiceSplit     `bson:"splits" json:"splits"`
+}
+
+// InvoiceSplit is one sub-bill of an invoice, paid by one guest or group of
+// guests. Its amount is a share of the invoice total; Paid, Balance and
+// Status are derived from its payments.
+type InvoiceSplit struct {
+	SplitID      string           `bson:"split_id" json:"split_id"`
+	Label        string           `bson:"label" json:"label"`
+	Seat         *int             `bson:"seat" json:"seat"`
+	OrderItemIDs []string         `bson:"order_item_ids" json:"order_item_ids"`
+	Amount       Money            `bson:"amount" json:"amount"`
+	Paid         Money            `bson:"paid" json:"paid"`
+	Balance      Money            `bson:"balance" json:"balance"`
+	Status       string           `bson:"status" json:"status"`
+	Payments     []InvoicePayment `bson:"payments" json:"payments"`
+}
+
+type InvoicePayment struct {
+	PaymentID  string    `bson:"payment_id" json:"payment_id"`
+	Method     string    `bson:"method" json:"method"`
+	Amount     Money     `bson:"amount" json:"amount"`
+	Refunded   Money     `bson:"refunded" json:"refunded"`
+	ReceivedBy string    `bson:"received_by" json:"received_by"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+}
+
+const (
+	PaymentStatusPending       = "PENDING"
+	PaymentStatusPartiallyPaid = "PARTIALLY_PAID"
+	PaymentStatusPaid          = "PAID"
+	PaymentStatusRefunded      = "REFUNDED"
+	PaymentStatusVoided        = "VOIDED"
+)
+
+const (
+	SplitFull = "FULL"
+	SplitEven = "EVEN"
+	SplitItem = "ITEM"
+	SplitSeat = "SEAT"
+)
+
+// Settle recomputes what has been paid and what is left on every split and on
+// the invoice as a whole, and the payment status that follows from it.
+// Refunds do not reopen the balance: the money credited back is reported as
+// refunded, and an invoice whose payments were all refunded is REFUNDED.
+func (i *Invoice) Settle() {
+	if i.Total == nil {
+		return
+	}
+	paid, refunded := NewMoney(0, i.Total.Currency), NewMoney(0, i.Total.Currency)
+	for s := range i.Splits {
+		split := &i.Splits[s]
+		split.Paid = NewMoney(0, split.Amount.Currency)
+		for _, payment := range split.Payments {
+			split.Paid = split.Paid.Add(payment.Amount)
+			refunded = refunded.Add(payment.Refunded)
+		}
+		split.Balance = split.Amount.Sub(split.Paid)
+		split.Status = paymentStatus(split.Paid, split.Balance)
+		paid = paid.Add(split.Paid)
+	}
+	balance := i.Total.Sub(paid)
+	status := paymentStatus(paid, balance)
+	switch {
+	case i.Voided != nil:
+		status = PaymentStatusVoided
+	case paid.Minor > 0 && refunded.Cmp(paid) >= 0:
+		status = PaymentStatusRefunded
+	}
+	i.Paid, i.Balance, i.Refunded, i.PaymentStatus = &paid, &balance, &refunded, &status
+}
+
+// HasUnrefundedPayments reports whether the invoice holds money that was not
+// given back.
+func (i *Invoice) HasUnrefundedPayments() bool {
+	for _, split := range i.Splits {
+		for _, payment := range split.Payments {
+			if payment.Refunded.Cmp(payment.Amount) < 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Number is the invoice number printed on receipts and credit notes. Invoices
+// created before numbering existed are known by their ID.
+func (i *Invoice) Number() string {
+	if i.InvoiceNumber == "" {
+		return i.InvoiceID
+	}
+	return i.InvoiceNumber
+}
+
+// HasPayments reports whether any payment was taken against the invoice.
+func (i *Invoice) HasPayments() bool {
+	for _, split := range i.Splits {
+		if len(split.Payments) > 0 {
+			return true
+		}
+	}
+	return false
+}
 
+func paymentStatus(paid, balance Money) string {
+	switch {
+	case balance.Minor <= 0:
+		return PaymentStatusPaid
+	case paid.Minor > 0:
+		return PaymentStatusPartiallyPaid
+	}
+	return PaymentStatusPending
+}
 
 type InvoiceViewFormat struct {
-	InvoiceID		string
-	PaymentMethod	string
-	OrderID			string
-	PaymentStatus	*string
-	PaymentDue		interface{}
-	TableNumber		interface{}
-	PaymentDueDate	time.Time
-	OrderDetails	interface{}
-}
\ No newline at end of file
+	InvoiceID      string
+	InvoiceNumber  string
+	PaymentMethod  string
+	OrderID        string
+	PaymentStatus  *string
+	PaymentDue     interface{}
+	Total          *Money
+	Paid           *Money
+	Balance        *Money
+	Refunded       *Money
+	Voided         *Reversal
+	SplitMode      string
+	Splits         []InvoiceSplit
+	Totals         *OrderTotals
+	TableNumber    interface{}
+	PaymentDueDate time.Time
+	OrderDetails   interface{}
+}
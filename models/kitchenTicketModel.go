@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KitchenTicket is one order line on the display of one station. The kitchen
+// bumps it off the queue once the dish is done and may recall it if it was
+// bumped by mistake.
+type KitchenTicket struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	TicketID    string             `bson:"ticket_id" json:"ticket_id"`
+	StationID   string             `bson:"station_id" json:"station_id"`
+	OrderID     string             `bson:"order_id" json:"order_id"`
+	OrderItemID string             `bson:"order_item_id" json:"order_item_id"`
+	TableNumber *int               `bson:"table_number" json:"table_number"`
+	Takeaway    bool               `bson:"takeaway" json:"takeaway"`
+	FoodName    string             `bson:"food_name" json:"food_name"`
+	Quantity    *string            `bson:"quantity" json:"quantity"`
+	Units       int                `bson:"units" json:"units"`
+	Seat        *int               `bson:"seat" json:"seat"`
+	Modifiers   []string           `bson:"modifiers" json:"modifiers"`
+	Notes       *string            `bson:"notes" json:"notes"`
+	Status      string             `bson:"status" json:"status"`
+	BumpedBy    string             `bson:"bumped_by" json:"bumped_by"`
+	BumpedAt    *time.Time         `bson:"bumped_at" json:"bumped_at"`
+	RecalledAt  *time.Time         `bson:"recalled_at" json:"recalled_at"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+const (
+	// TicketStatusNew tickets are in the queue of their station.
+	TicketStatusNew    = "NEW"
+	TicketStatusBumped = "BUMPED"
+	// TicketStatusVoided tickets belong to a cancelled order or to a line
+	// that no longer goes to their station.
+	TicketStatusVoided = "VOIDED"
+)
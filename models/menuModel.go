@@ -6,13 +6,31 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Days of the week menu windows are served on.
+var Weekdays = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// Menu is served between StartDate and EndDate, for a season, and within
+// those during its Windows of the week. A menu without dates is served all
+// year and one without windows all day. Times of day are in TimeZone, or the
+// time zone of the restaurant when it is empty.
 type Menu struct {
-	ID 				primitive.ObjectID		`bson:"_id"`
-	Name			string					`json:"name" validate:"required"`
-	Category		string					`json:"category" validate:"required"`	
-	StartDate		*time.Time				`json:"start_date"`
-	EndDate			*time.Time				`json:"end_date"`
-	CreatedAt		time.Time				`json:"created_at"`
-	UpdatedAt		time.Time				`json:"updated_at"`
-	MenuID			string					`json:"food_id"`
-}
\ No newline at end of file
+	ID        primitive.ObjectID `bson:"_id"`
+	Name      string             `bson:"name" json:"name" validate:"required"`
+	Category  string             `bson:"category" json:"category" validate:"required"`
+	StartDate *time.Time         `bson:"start_date" json:"start_date"`
+	EndDate   *time.Time         `bson:"end_date" json:"end_date"`
+	Windows   []MenuWindow       `bson:"windows" json:"windows" validate:"dive"`
+	TimeZone  *string            `bson:"time_zone" json:"time_zone"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	MenuID    string             `bson:"menu_id" json:"menu_id"`
+}
+
+// MenuWindow serves a menu on Days from Opens until Closes, written 07:00 and
+// 11:00. A window closing at or before it opens runs past midnight into the
+// next day.
+type MenuWindow struct {
+	Days   []string `bson:"days" json:"days" validate:"required,min=1,dive,oneof=SUN MON TUE WED THU FRI SAT"`
+	Opens  string   `bson:"opens" json:"opens" validate:"required"`
+	Closes string   `bson:"closes" json:"closes" validate:"required"`
+}
@@ -0,0 +1,125 @@
+package models
+
+import "fmt"
+
+// ModifierGroup is a choice offered with a food, such as how a steak is done
+// or which extras go on a burger. Guests pick between MinSelections and
+// MaxSelections of its options; a required group needs at least one.
+type ModifierGroup struct {
+	GroupID       string           `bson:"group_id" json:"group_id"`
+	Name          string           `bson:"name" json:"name" validate:"required,max=50"`
+	Required      bool             `bson:"required" json:"required"`
+	MinSelections int              `bson:"min_selections" json:"min_selections" validate:"min=0"`
+	MaxSelections int              `bson:"max_selections" json:"max_selections" validate:"min=0"`
+	Options       []ModifierOption `bson:"options" json:"options" validate:"required,min=1,dive"`
+}
+
+// ModifierOption is one pick of a group. Its price delta is added to the unit
+// price of the food, or taken off it when negative.
+type ModifierOption struct {
+	OptionID   string `bson:"option_id" json:"option_id"`
+	Name       string `bson:"name" json:"name" validate:"required,max=50"`
+	PriceDelta Money  `bson:"price_delta" json:"price_delta"`
+}
+
+// SelectedModifier is an option chosen for an order item. Clients send the
+// group and option IDs; names and price are copied from the food when the
+// item is priced, so later menu edits do not change the order.
+type SelectedModifier struct {
+	GroupID    string `bson:"group_id" json:"group_id" validate:"required"`
+	OptionID   string `bson:"option_id" json:"option_id" validate:"required"`
+	GroupName  string `bson:"group_name" json:"group_name"`
+	OptionName string `bson:"option_name" json:"option_name"`
+	PriceDelta Money  `bson:"price_delta" json:"price_delta"`
+}
+
+// Bounds are the least and most options of the group a guest may pick. A
+// maximum of 0 means no more than there are options.
+func (g ModifierGroup) Bounds() (int, int) {
+	least, most := g.MinSelections, g.MaxSelections
+	if g.Required && least == 0 {
+		least = 1
+	}
+	if most == 0 || most > len(g.Options) {
+		most = len(g.Options)
+	}
+	return least, most
+}
+
+// CheckModifierGroups reports the first inconsistency in groups: duplicate
+// IDs or selection bounds no guest could meet.
+func CheckModifierGroups(groups []ModifierGroup) error {
+	groupIDs, optionIDs := map[string]bool{}, map[string]bool{}
+	for _, group := range groups {
+		if groupIDs[group.GroupID] {
+			return fmt.Errorf("modifier group %s appears twice", group.GroupID)
+		}
+		groupIDs[group.GroupID] = true
+		for _, option := range group.Options {
+			if optionIDs[option.OptionID] {
+				return fmt.Errorf("modifier option %s appears twice", option.OptionID)
+			}
+			optionIDs[option.OptionID] = true
+		}
+		least, most := group.Bounds()
+		if group.MaxSelections > len(group.Options) {
+			return fmt.Errorf("modifier group %q allows %d selections but has %d options", group.Name, group.MaxSelections, len(group.Options))
+		}
+		if least > most {
+			return fmt.Errorf("modifier group %q requires at least %d selections but allows at most %d", group.Name, least, most)
+		}
+	}
+	return nil
+}
+
+// SelectModifiers checks that selected is a legal choice of the modifiers of
+// the food and returns it with the names and price deltas filled in from the
+// food. Every option must belong to the group given with it, an option may be
+// picked once, and every group must get between its least and most picks.
+func (f Food) SelectModifiers(selected []SelectedModifier) ([]SelectedModifier, error) {
+	picked := map[string]int{}
+	chosen := map[string]bool{}
+	filled := make([]SelectedModifier, 0, len(selected))
+	for _, selection := range selected {
+		group, option, ok := f.findModifier(selection.GroupID, selection.OptionID)
+		if !ok {
+			return nil, fmt.Errorf("option %s of modifier group %s is not offered with this food", selection.OptionID, selection.GroupID)
+		}
+		if chosen[option.OptionID] {
+			return nil, fmt.Errorf("option %q is selected twice", option.Name)
+		}
+		chosen[option.OptionID] = true
+		picked[group.GroupID]++
+		filled = append(filled, SelectedModifier{
+			GroupID:    group.GroupID,
+			OptionID:   option.OptionID,
+			GroupName:  group.Name,
+			OptionName: option.Name,
+			PriceDelta: option.PriceDelta,
+		})
+	}
+	for _, group := range f.Modifiers {
+		least, most := group.Bounds()
+		if n := picked[group.GroupID]; n < least || n > most {
+			if least == most {
+				return nil, fmt.Errorf("modifier group %q takes exactly %d of its options, got %d", group.Name, least, n)
+			}
+			return nil, fmt.Errorf("modifier group %q takes between %d and %d of its options, got %d", group.Name, least, most, n)
+		}
+	}
+	return filled, nil
+}
+
+func (f Food) findModifier(groupID, optionID string) (ModifierGroup, ModifierOption, bool) {
+	for _, group := range f.Modifiers {
+		if group.GroupID != groupID {
+			continue
+		}
+		for _, option := range group.Options {
+			if option.OptionID == optionID {
+				return group, option, true
+			}
+		}
+	}
+	return ModifierGroup{}, ModifierOption{}, false
+}
@@ -0,0 +1,280 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// Money is an amount in the minor units of its currency, so 12.50 NGN is
+// stored as 1250 kobo and sums never drift the way float64 prices do. It is
+// stored in MongoDB as {minor, currency} and rendered in JSON as
+// {"amount": "12.50", "currency": "NGN"}. An amount without a currency counts
+// its minor units in hundredths until InCurrency gives it one.
+type Money struct {
+	Minor    int64  `bson:"minor" json:"-"`
+	Currency string `bson:"currency" json:"currency"`
+	// amount is the decimal amount read for a money without a currency, kept
+	// to be parsed in the minor unit of the currency it is given later
+	amount string
+}
+
+// currencyExponents lists the currencies whose minor unit is not a hundredth.
+var currencyExponents = map[string]int{
+	"BHD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"OMR": 3,
+	"XAF": 0,
+	"XOF": 0,
+}
+
+func NewMoney(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// ParseMoney reads a decimal amount such as "12.50" in the major units of
+// currency. Amounts more precise than the minor unit are rejected.
+func ParseMoney(amount, currency string) (Money, error) {
+	amount = strings.TrimSpace(amount)
+	negative, whole, fraction, err := splitAmount(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	exponent := currencyExponent(currency)
+	if len(fraction) > exponent {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places", amount, exponent)
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func splitAmount(amount string) (negative bool, whole, fraction string, err error) {
+	negative = strings.HasPrefix(amount, "-")
+	whole, fraction, _ = strings.Cut(strings.TrimPrefix(amount, "-"), ".")
+	if whole == "" || strings.Trim(whole, "0123456789") != "" || strings.Trim(fraction, "0123456789") != "" {
+		return false, "", "", fmt.Errorf("invalid amount %q", amount)
+	}
+	return negative, whole, fraction, nil
+}
+
+// bareMoney keeps amount, read without a currency, for InCurrency and counts
+// it in hundredths meanwhile.
+func bareMoney(amount string) Money {
+	value, _ := strconv.ParseFloat(amount, 64)
+	return Money{Minor: int64(math.Round(value * float64(pow10(currencyExponent(""))))), amount: amount}
+}
+
+func currencyExponent(currency string) int {
+	if exponent, ok := currencyExponents[currency]; ok {
+		return exponent
+	}
+	return 2
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+// String formats the amount in major units without the currency, e.g. 12.50.
+func (m Money) String() string {
+	exponent := currencyExponent(m.Currency)
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	if exponent == 0 {
+		return sign + strconv.FormatInt(minor, 10)
+	}
+	unit := pow10(exponent)
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/unit, exponent, minor%unit)
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+// InCurrency gives currency to an amount that has none, such as a bare number
+// sent by a client or a price stored before Money was introduced, in the minor
+// unit of currency. Amounts more precise than that unit are rejected.
+func (m Money) InCurrency(currency string) (Money, error) {
+	if m.Currency != "" {
+		return m, nil
+	}
+	if m.amount != "" {
+		return ParseMoney(m.amount, currency)
+	}
+	shift := currencyExponent(currency) - currencyExponent("")
+	if shift >= 0 {
+		return Money{Minor: m.Minor * pow10(shift), Currency: currency}, nil
+	}
+	if m.Minor%pow10(-shift) != 0 {
+		return Money{}, fmt.Errorf("amount %s has more than %d decimal places", m, currencyExponent(currency))
+	}
+	return Money{Minor: m.Minor / pow10(-shift), Currency: currency}, nil
+}
+
+// WithCurrency is InCurrency for amounts already stored, which are rounded
+// half away from zero when they are more precise than the minor unit.
+func (m Money) WithCurrency(currency string) Money {
+	if converted, err := m.InCurrency(currency); err == nil {
+		return converted
+	}
+	shift := currencyExponent(currency) - currencyExponent("")
+	return Money{Minor: int64(math.Round(float64(m.Minor) * math.Pow10(shift))), Currency: currency}
+}
+
+// Add sums two amounts. An amount without a currency takes the currency of
+// the other; callers make sure the currencies otherwise agree.
+func (m Money) Add(other Money) Money {
+	return Money{Minor: m.Minor + other.Minor, Currency: m.mergeCurrency(other)}
+}
+
+func (m Money) Sub(other Money) Money {
+	return Money{Minor: m.Minor - other.Minor, Currency: m.mergeCurrency(other)}
+}
+
+func (m Money) Mul(n int64) Money {
+	return Money{Minor: m.Minor * n, Currency: m.Currency}
+}
+
+// MulRate applies a rate such as 0.075 and rounds half away from zero to the
+// minor unit.
+func (m Money) MulRate(rate float64) Money {
+	return Money{Minor: int64(math.Round(float64(m.Minor) * rate)), Currency: m.Currency}
+}
+
+func (m Money) Cmp(other Money) int {
+	switch {
+	case m.Minor < other.Minor:
+		return -1
+	case m.Minor > other.Minor:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) mergeCurrency(other Money) string {
+	if m.Currency == "" {
+		return other.Currency
+	}
+	return m.Currency
+}
+
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.String(), m.Currency})
+}
+
+// UnmarshalJSON accepts {"amount": "12.50", "currency": "NGN"} as well as a
+// bare amount, either as a string or a number, which leaves the currency
+// empty for the server to fill in with InCurrency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	currency := ""
+	if len(data) > 0 && data[0] == '{' {
+		var raw moneyJSON
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		if raw.Amount == nil {
+			return errors.New("money: amount is required")
+		}
+		data, currency = bytes.TrimSpace(raw.Amount), strings.ToUpper(strings.TrimSpace(raw.Currency))
+	}
+
+	amount := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &amount); err != nil {
+			return err
+		}
+	}
+	if currency == "" {
+		amount = strings.TrimSpace(amount)
+		if _, _, _, err := splitAmount(amount); err != nil {
+			return fmt.Errorf("money: %w", err)
+		}
+		*m = bareMoney(amount)
+		return nil
+	}
+	parsed, err := ParseMoney(amount, currency)
+	if err != nil {
+		return fmt.Errorf("money: %w", err)
+	}
+	*m = parsed
+	return nil
+}
+
+// UnmarshalBSONValue reads the {minor, currency} document, and also the plain
+// numbers prices were stored as before, which it takes as major units of the
+// currency they are later given.
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.EmbeddedDocument:
+		type plain Money
+		var decoded plain
+		if err := bson.Unmarshal(data, &decoded); err != nil {
+			return err
+		}
+		*m = Money(decoded)
+	case bsontype.Double:
+		value, _, ok := bsoncore.ReadDouble(data)
+		if !ok {
+			return errors.New("money: malformed double")
+		}
+		// doubles are read to the thousandth, the smallest minor unit, so
+		// that float noise does not make them look too precise
+		amount := strings.TrimRight(strconv.FormatFloat(value, 'f', 3, 64), "0")
+		*m = bareMoney(strings.TrimSuffix(amount, "."))
+	case bsontype.Int32:
+		value, _, ok := bsoncore.ReadInt32(data)
+		if !ok {
+			return errors.New("money: malformed int32")
+		}
+		*m = bareMoney(strconv.FormatInt(int64(value), 10))
+	case bsontype.Int64:
+		value, _, ok := bsoncore.ReadInt64(data)
+		if !ok {
+			return errors.New("money: malformed int64")
+		}
+		*m = bareMoney(strconv.FormatInt(value, 10))
+	case bsontype.Null:
+		*m = Money{}
+	default:
+		return fmt.Errorf("money: cannot decode BSON %s", t)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount, currency string
+		minor            int64
+		ok               bool
+	}{
+		{"12.50", "NGN", 1250, true},
+		{"12.5", "NGN", 1250, true},
+		{"-3", "NGN", -300, true},
+		{"12.505", "NGN", 0, false},
+		{"1500", "JPY", 1500, true},
+		{"12.5", "JPY", 0, false},
+		{"1.234", "KWD", 1234, true},
+		{"1.2", "KWD", 1200, true},
+		{"1.2345", "KWD", 0, false},
+		{"1e3", "NGN", 0, false},
+		{".5", "NGN", 0, false},
+	}
+	for _, tt := range tests {
+		money, err := ParseMoney(tt.amount, tt.currency)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseMoney(%q, %s): got error %v, want ok %v", tt.amount, tt.currency, err, tt.ok)
+			continue
+		}
+		if tt.ok && (money.Minor != tt.minor || money.Currency != tt.currency) {
+			t.Errorf("ParseMoney(%q, %s): got %d %s, want %d", tt.amount, tt.currency, money.Minor, money.Currency, tt.minor)
+		}
+	}
+}
+
+func TestBareMoneyInCurrency(t *testing.T) {
+	tests := []struct {
+		json, currency string
+		minor          int64
+		ok             bool
+	}{
+		{`"12.50"`, "NGN", 1250, true},
+		{`12.5`, "NGN", 1250, true},
+		{`"1500"`, "JPY", 1500, true},
+		{`1500`, "XOF", 1500, true},
+		{`"12.5"`, "JPY", 0, false},
+		{`12.5`, "XAF", 0, false},
+		{`"1.234"`, "KWD", 1234, true},
+		{`1.5`, "KWD", 1500, true},
+		{`{"amount": "12.50"}`, "NGN", 1250, true},
+		// an amount that names its currency keeps it
+		{`{"amount": "12.50", "currency": "usd"}`, "NGN", 1250, true},
+	}
+	for _, tt := range tests {
+		var money Money
+		if err := json.Unmarshal([]byte(tt.json), &money); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", tt.json, err)
+		}
+		converted, err := money.InCurrency(tt.currency)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s in %s: got error %v, want ok %v", tt.json, tt.currency, err, tt.ok)
+			continue
+		}
+		if tt.ok && converted.Minor != tt.minor {
+			t.Errorf("%s in %s: got %d, want %d", tt.json, tt.currency, converted.Minor, tt.minor)
+		}
+	}
+
+	var money Money
+	if err := json.Unmarshal([]byte(`"12.x"`), &money); err == nil {
+		t.Errorf("Unmarshal of an invalid amount: got %+v, want an error", money)
+	}
+}
+
+func TestLegacyMoneyInCurrency(t *testing.T) {
+	tests := []struct {
+		stored   interface{}
+		currency string
+		minor    int64
+		ok       bool
+	}{
+		{12.5, "NGN", 1250, true},
+		{0.1 + 0.2, "NGN", 30, true},
+		{int32(1500), "JPY", 1500, true},
+		{int64(1500), "XOF", 1500, true},
+		{1.234, "KWD", 1234, true},
+		{int32(2), "KWD", 2000, true},
+		{12.5, "JPY", 0, false},
+	}
+	for _, tt := range tests {
+		data, err := bson.Marshal(bson.M{"price": tt.stored})
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		var decoded struct {
+			Price Money `bson:"price"`
+		}
+		if err := bson.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal(%v): %v", tt.stored, err)
+		}
+		money, err := decoded.Price.InCurrency(tt.currency)
+		if (err == nil) != tt.ok {
+			t.Errorf("stored %v in %s: got error %v, want ok %v", tt.stored, tt.currency, err, tt.ok)
+			continue
+		}
+		if tt.ok && money.Minor != tt.minor {
+			t.Errorf("stored %v in %s: got %d, want %d", tt.stored, tt.currency, money.Minor, tt.minor)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{NewMoney(1250, "NGN"), "12.50"},
+		{NewMoney(-5, "NGN"), "-0.05"},
+		{NewMoney(1500, "JPY"), "1500"},
+		{NewMoney(1234, "KWD"), "1.234"},
+		{NewMoney(5, "KWD"), "0.005"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("%d %s: got %q, want %q", tt.money.Minor, tt.money.Currency, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulRate(t *testing.T) {
+	tests := []struct {
+		money Money
+		rate  float64
+		want  int64
+	}{
+		{NewMoney(1001, "JPY"), 0.075, 75},
+		{NewMoney(1000, "JPY"), 0.075, 75},
+		{NewMoney(1234, "KWD"), 0.05, 62},
+		{NewMoney(-1000, "NGN"), 0.075, -75},
+		{NewMoney(2, "NGN"), 0.25, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.money.MulRate(tt.rate); got.Minor != tt.want || got.Currency != tt.money.Currency {
+			t.Errorf("%v %s at %v: got %d %s, want %d", tt.money, tt.money.Currency, tt.rate, got.Minor, got.Currency, tt.want)
+		}
+	}
+}
@@ -7,17 +7,52 @@ import (
 )
 
 type OrderItem struct {
-	ID 			primitive.ObjectID	`bson:"_id"`
-	Quantity	*string				`json:"quantity" validate:"required,eq=S|eq=M|eq=L"`
-	UnitPrice	*float64			`json:"unit_price" validate:"required"`
-	CreatedAt	time.Time			`json:"created_at"`
-	UpdatedAt	time.Time			`json:"updated_at"`
-	FoodID		*string				`json:"food_id" validate:"required"`
-	OrderItemID	string				`json:"order_item_id"`
-	OrderID		string				`json:"order_id" vaidate:"required"`
+	ID          primitive.ObjectID `bson:"_id"`
+	Quantity    *string            `bson:"quantity" json:"quantity" validate:"required,eq=S|eq=M|eq=L"`
+	Units       *int               `bson:"units" json:"units" validate:"omitempty,min=1,max=999"`
+	UnitPrice   *Money             `bson:"unit_price" json:"unit_price" validate:"required"`
+	LineTotal   *Money             `bson:"line_total" json:"line_total"`
+	Category    *string            `bson:"category" json:"category"`
+	Seat        *int               `bson:"seat" json:"seat" validate:"omitempty,min=1"`
+	Modifiers   []SelectedModifier `bson:"modifiers" json:"modifiers" validate:"dive"`
+	Notes       *string            `bson:"notes" json:"notes" validate:"omitempty,max=200"`
+	PrepStatus  string             `bson:"prep_status" json:"prep_status"`
+	FiredAt     *time.Time         `bson:"fired_at" json:"fired_at"`
+	CookingAt   *time.Time         `bson:"cooking_at" json:"cooking_at"`
+	ReadyAt     *time.Time         `bson:"ready_at" json:"ready_at"`
+	ServedAt    *time.Time         `bson:"served_at" json:"served_at"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	FoodID      *string            `bson:"food_id" json:"food_id" validate:"required"`
+	OrderItemID string             `bson:"order_item_id" json:"order_item_id"`
+	OrderID     string             `bson:"order_id" json:"order_id" validate:"required"`
 }
 
 type OrderItemPack struct {
-	TableID		*string
-	OrderItems	[]OrderItem
-}
\ No newline at end of file
+	TableID    *string
+	OrderItems []OrderItem
+}
+
+// OrderSummary is one order with its lines joined to their food and table,
+// as produced by OrderItemRepository.ItemsByOrder. TableNumbers lists the
+// table of the order followed by the tables merged into it.
+type OrderSummary struct {
+	PaymentDue   Money              `bson:"payment_due" json:"payment_due"`
+	TotalCount   int                `bson:"total_count" json:"total_count"`
+	TableNumber  *int               `bson:"table_number" json:"table_number"`
+	TableNumbers []int              `bson:"table_numbers" json:"table_numbers"`
+	OrderItems   []OrderSummaryLine `bson:"order_items" json:"order_items"`
+}
+
+type OrderSummaryLine struct {
+	Amount      *Money             `bson:"amount" json:"amount"`
+	Price       *Money             `bson:"price" json:"price"`
+	Quantity    int                `bson:"quantity" json:"quantity"`
+	FoodName    *string            `bson:"food_name" json:"food_name"`
+	FoodImage   *string            `bson:"food_image" json:"food_image"`
+	OrderID     string             `bson:"order_id" json:"order_id"`
+	TableID     string             `bson:"table_id" json:"table_id"`
+	TableNumber *int               `bson:"table_number" json:"table_number"`
+	Modifiers   []SelectedModifier `bson:"modifiers" json:"modifiers"`
+	Notes       *string            `bson:"notes" json:"notes"`
+}
@@ -7,10 +7,38 @@ import (
 )
 
 type Order struct {
-	ID 				primitive.ObjectID		`bson:"_id"`
-	OrderDate		time.Time				`json:"order_date" validate:"required"`	
-	CreatedAt		time.Time				`json:"created_at"`
-	UpdatedAt		time.Time				`json:"updated_at"`
-	OrderID			string					`json:"order_id"`
-	TableID			*string					`json:"table_id" validate:"required"`
-} 
\ No newline at end of file
+	ID            primitive.ObjectID `bson:"_id"`
+	OrderDate     time.Time          `bson:"order_date" json:"order_date" validate:"required"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+	OrderID       string             `bson:"order_id" json:"order_id"`
+	TableID       *string            `bson:"table_id" json:"table_id" validate:"required"`
+	Status        string             `bson:"status" json:"status"`
+	StatusHistory []OrderTransition  `bson:"status_history" json:"status_history"`
+	Takeaway      bool               `bson:"takeaway" json:"takeaway"`
+	Discount      *Money             `bson:"discount" json:"discount"`
+	Totals        *OrderTotals       `bson:"totals" json:"totals"`
+}
+
+// OrderTotals are computed by the server from the order lines whenever they
+// change. Orders stored before totals were introduced have none.
+type OrderTotals struct {
+	Subtotal      Money `bson:"subtotal" json:"subtotal"`
+	Discount      Money `bson:"discount" json:"discount"`
+	ServiceCharge Money `bson:"service_charge" json:"service_charge"`
+	Tax           Money `bson:"tax" json:"tax"`
+	InclusiveTax  Money `bson:"inclusive_tax" json:"inclusive_tax"`
+	GrandTotal    Money `bson:"grand_total" json:"grand_total"`
+	// Taxes itemizes Tax, which is added to the bill, and InclusiveTax, which
+	// is already part of the prices, per tax rule.
+	Taxes []TaxLine `bson:"taxes" json:"taxes"`
+}
+
+// CurrentStatus is the status of the order. Orders stored before statuses
+// were introduced have none and count as placed.
+func (o Order) CurrentStatus() string {
+	if o.Status == "" {
+		return OrderStatusPlaced
+	}
+	return o.Status
+}
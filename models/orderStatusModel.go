@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+const (
+	OrderStatusDraft     = "DRAFT"
+	OrderStatusPlaced    = "PLACED"
+	OrderStatusInKitchen = "IN_KITCHEN"
+	OrderStatusReady     = "READY"
+	OrderStatusServed    = "SERVED"
+	OrderStatusClosed    = "CLOSED"
+	OrderStatusCancelled = "CANCELLED"
+	OrderStatusVoided    = "VOIDED"
+)
+
+// orderTransitions lists the statuses each status may move to. An order is
+// cancelled before the kitchen has started on it and voided afterwards.
+// CLOSED, CANCELLED and VOIDED are terminal.
+var orderTransitions = map[string][]string{
+	OrderStatusDraft:     {OrderStatusPlaced, OrderStatusCancelled},
+	OrderStatusPlaced:    {OrderStatusInKitchen, OrderStatusCancelled},
+	OrderStatusInKitchen: {OrderStatusReady, OrderStatusVoided},
+	OrderStatusReady:     {OrderStatusServed, OrderStatusVoided},
+	OrderStatusServed:    {OrderStatusClosed, OrderStatusVoided},
+	OrderStatusClosed:    {},
+	OrderStatusCancelled: {},
+	OrderStatusVoided:    {},
+}
+
+// OrderTransitionRoles restricts who may move an order into a status. Statuses
+// not listed are open to every role allowed on the transitions route.
+var OrderTransitionRoles = map[string][]string{
+	OrderStatusVoided: {RoleManager},
+}
+
+// OrderTransition records one status change of an order.
+type OrderTransition struct {
+	From      string    `bson:"from" json:"from"`
+	To        string    `bson:"to" json:"to"`
+	ChangedBy string    `bson:"changed_by" json:"changed_by"`
+	Role      string    `bson:"role" json:"role"`
+	Reason    *string   `bson:"reason" json:"reason"`
+	ChangedAt time.Time `bson:"changed_at" json:"changed_at"`
+}
+
+func IsOrderStatus(status string) bool {
+	_, ok := orderTransitions[status]
+	return ok
+}
+
+// CanTransition reports whether an order in status from may move to status to.
+func CanTransition(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOrderStatuses returns the statuses an order in status may move to.
+func NextOrderStatuses(status string) []string {
+	return append([]string{}, orderTransitions[status]...)
+}
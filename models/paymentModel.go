@@ -0,0 +1,128 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Payment is the record of money taken against one split of an invoice. Card
+// payments go through a payment gateway and keep its references; cash
+// payments are captured as soon as they are recorded.
+type Payment struct {
+	ID               primitive.ObjectID `bson:"_id"`
+	PaymentID        string             `bson:"payment_id" json:"payment_id"`
+	InvoiceID        string             `bson:"invoice_id" json:"invoice_id"`
+	SplitID          string             `bson:"split_id" json:"split_id"`
+	Method           string             `bson:"method" json:"method"`
+	Amount           Money              `bson:"amount" json:"amount"`
+	Status           string             `bson:"status" json:"status"`
+	Provider         string             `bson:"provider" json:"provider"`
+	AuthorizationRef string             `bson:"authorization_ref" json:"authorization_ref"`
+	CaptureRef       string             `bson:"capture_ref" json:"capture_ref"`
+	DeclineCode      string             `bson:"decline_code" json:"decline_code"`
+	Refunded         Money              `bson:"refunded" json:"refunded"`
+	Refunds          []PaymentRefund    `bson:"refunds" json:"refunds"`
+	Voided           *Reversal          `bson:"voided" json:"voided"`
+	IdempotencyKey   string             `bson:"idempotency_key" json:"idempotency_key"`
+	CreatedBy        string             `bson:"created_by" json:"created_by"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+const (
+	PaymentMethodCard = "CARD"
+	PaymentMethodCash = "CASH"
+)
+
+// A payment is PENDING from the moment it is recorded until the gateway
+// answers; it then ends up FAILED, or AUTHORIZED and later CAPTURED. An
+// authorization that is not captured can be VOIDED, and a captured payment
+// becomes PARTIALLY_REFUNDED and then REFUNDED as it is refunded.
+const (
+	PaymentPending           = "PENDING"
+	PaymentAuthorized        = "AUTHORIZED"
+	PaymentCaptured          = "CAPTURED"
+	PaymentFailed            = "FAILED"
+	PaymentVoided            = "VOIDED"
+	PaymentPartiallyRefunded = "PARTIALLY_REFUNDED"
+	PaymentRefunded          = "REFUNDED"
+)
+
+// PaymentRefund is money given back from a captured payment. Card refunds are
+// PENDING until the gateway answers, then COMPLETED or FAILED.
+type PaymentRefund struct {
+	RefundID    string   `bson:"refund_id" json:"refund_id"`
+	Amount      Money    `bson:"amount" json:"amount"`
+	Status      string   `bson:"status" json:"status"`
+	Ref         string   `bson:"ref" json:"ref"`
+	DeclineCode string   `bson:"decline_code" json:"decline_code"`
+	Reason      Reversal `bson:"reason" json:"reason"`
+}
+
+const (
+	RefundPending   = "PENDING"
+	RefundCompleted = "COMPLETED"
+	RefundFailed    = "FAILED"
+)
+
+// IsCaptured reports whether the payment took money, whether or not some of
+// it was refunded since.
+func (p Payment) IsCaptured() bool {
+	return p.Status == PaymentCaptured || p.Status == PaymentPartiallyRefunded || p.Status == PaymentRefunded
+}
+
+// Refundable is what is left of a captured payment once its refunds, pending
+// ones included, are taken off.
+func (p Payment) Refundable() Money {
+	if !p.IsCaptured() {
+		return NewMoney(0, p.Amount.Currency)
+	}
+	left := p.Amount
+	for _, refund := range p.Refunds {
+		if refund.Status != RefundFailed {
+			left = left.Sub(refund.Amount)
+		}
+	}
+	return left
+}
+
+// SettleRefunds recomputes the refunded amount and the status that follows
+// from the completed refunds.
+func (p *Payment) SettleRefunds() {
+	p.Refunded = NewMoney(0, p.Amount.Currency)
+	for _, refund := range p.Refunds {
+		if refund.Status == RefundCompleted {
+			p.Refunded = p.Refunded.Add(refund.Amount)
+		}
+	}
+	switch {
+	case !p.IsCaptured():
+	case p.Refunded.Cmp(p.Amount) >= 0:
+		p.Status = PaymentRefunded
+	case p.Refunded.Minor > 0:
+		p.Status = PaymentPartiallyRefunded
+	default:
+		p.Status = PaymentCaptured
+	}
+}
+
+// IsOpen reports whether the payment still holds part of the split balance
+// without having been captured.
+func (p Payment) IsOpen() bool {
+	return p.Status == PaymentPending || p.Status == PaymentAuthorized
+}
+
+// IdempotencyKey claims a key sent by a client for the payment it first came
+// with. It is stored under the key itself, so the database refuses a second
+// claim even from concurrent requests.
+type IdempotencyKey struct {
+	Key string `bson:"_id" json:"key"`
+	// Fingerprint summarises the request, so that reusing a key for a
+	// different request is detected rather than answered with the wrong
+	// payment.
+	Fingerprint string    `bson:"fingerprint" json:"fingerprint"`
+	PaymentID   string    `bson:"payment_id" json:"payment_id"`
+	RefundID    string    `bson:"refund_id" json:"refund_id"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+}
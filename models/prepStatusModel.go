@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// Preparation stages of an order item, in order. An item is fired when the
+// kitchen is told to start on it.
+const (
+	PrepStatusPending = "PENDING"
+	PrepStatusFired   = "FIRED"
+	PrepStatusCooking = "COOKING"
+	PrepStatusReady   = "READY"
+	PrepStatusServed  = "SERVED"
+)
+
+var prepStages = []string{PrepStatusPending, PrepStatusFired, PrepStatusCooking, PrepStatusReady, PrepStatusServed}
+
+func prepStage(status string) int {
+	for i, stage := range prepStages {
+		if stage == status {
+			return i
+		}
+	}
+	return -1
+}
+
+func IsPrepStatus(status string) bool {
+	return prepStage(status) >= 0
+}
+
+// CurrentPrepStatus is the preparation stage of the item. Items stored before
+// stages were introduced have none and count as pending.
+func (i OrderItem) CurrentPrepStatus() string {
+	if i.PrepStatus == "" {
+		return PrepStatusPending
+	}
+	return i.PrepStatus
+}
+
+// CanAdvancePrep reports whether the item may move to status. Items only move
+// forward, but may skip stages, as drinks that go straight to READY do.
+func (i OrderItem) CanAdvancePrep(status string) bool {
+	return prepStage(status) > prepStage(i.CurrentPrepStatus())
+}
+
+// AdvancePrep moves the item to status and records when it got there.
+func (i *OrderItem) AdvancePrep(status string, at time.Time) {
+	i.PrepStatus = status
+	switch status {
+	case PrepStatusFired:
+		i.FiredAt = &at
+	case PrepStatusCooking:
+		i.CookingAt = &at
+	case PrepStatusReady:
+		i.ReadyAt = &at
+	case PrepStatusServed:
+		i.ServedAt = &at
+	}
+}
+
+// RollUpOrderStatus is the status the items of an order put it in: SERVED once
+// every item is served, READY once every item is ready or served and
+// IN_KITCHEN once any item is fired. It returns "" while nothing was fired.
+func RollUpOrderStatus(items []OrderItem) string {
+	if len(items) == 0 {
+		return ""
+	}
+	lowest, highest := len(prepStages), -1
+	for _, item := range items {
+		stage := prepStage(item.CurrentPrepStatus())
+		lowest, highest = min(lowest, stage), max(highest, stage)
+	}
+	switch {
+	case lowest >= prepStage(PrepStatusServed):
+		return OrderStatusServed
+	case lowest >= prepStage(PrepStatusReady):
+		return OrderStatusReady
+	case highest >= prepStage(PrepStatusFired):
+		return OrderStatusInKitchen
+	}
+	return ""
+}
+
+// PrepTimes summarizes how long one food took to prepare. Prep runs from when
+// an item was fired, or ordered if it never was, to when it was ready; Cook
+// from when cooking started to ready; Pass from ready to served.
+type PrepTimes struct {
+	FoodID   string        `json:"food_id"`
+	FoodName *string       `json:"food_name"`
+	Items    int           `json:"items"`
+	Prep     DurationStats `json:"prep"`
+	Cook     DurationStats `json:"cook"`
+	Pass     DurationStats `json:"pass"`
+}
+
+// DurationStats describes Count durations, in seconds.
+type DurationStats struct {
+	Count          int   `json:"count"`
+	AverageSeconds int64 `json:"average_seconds"`
+	MedianSeconds  int64 `json:"median_seconds"`
+	P90Seconds     int64 `json:"p90_seconds"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ReservationStatusBooked    = "BOOKED"
+	ReservationStatusSeated    = "SEATED"
+	ReservationStatusCompleted = "COMPLETED"
+	ReservationStatusCancelled = "CANCELLED"
+	ReservationStatusNoShow    = "NO_SHOW"
+)
+
+// reservationTransitions lists the statuses each status may move to.
+// COMPLETED, CANCELLED and NO_SHOW are terminal.
+var reservationTransitions = map[string][]string{
+	ReservationStatusBooked:    {ReservationStatusSeated, ReservationStatusCancelled, ReservationStatusNoShow},
+	ReservationStatusSeated:    {ReservationStatusCompleted},
+	ReservationStatusCompleted: {},
+	ReservationStatusCancelled: {},
+	ReservationStatusNoShow:    {},
+}
+
+// Reservation books a table for a party from StartsAt until EndsAt, which is
+// StartsAt plus the turn time for the party size.
+type Reservation struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	ReservationID string             `bson:"reservation_id" json:"reservation_id"`
+	GuestName     string             `bson:"guest_name" json:"guest_name" validate:"required,max=100"`
+	Phone         string             `bson:"phone" json:"phone" validate:"required,max=20"`
+	Email         *string            `bson:"email" json:"email" validate:"omitempty,email"`
+	PartySize     int                `bson:"party_size" json:"party_size" validate:"required,min=1"`
+	StartsAt      time.Time          `bson:"starts_at" json:"starts_at" validate:"required"`
+	EndsAt        time.Time          `bson:"ends_at" json:"ends_at"`
+	TableID       string             `bson:"table_id" json:"table_id"`
+	TableNumber   *int               `bson:"table_number" json:"table_number"`
+	Notes         *string            `bson:"notes" json:"notes" validate:"omitempty,max=200"`
+	Status        string             `bson:"status" json:"status"`
+	BookedBy      string             `bson:"booked_by" json:"booked_by"`
+	SeatedAt      *time.Time         `bson:"seated_at" json:"seated_at"`
+	CompletedAt   *time.Time         `bson:"completed_at" json:"completed_at"`
+	CancelledAt   *time.Time         `bson:"cancelled_at" json:"cancelled_at"`
+	NoShowAt      *time.Time         `bson:"no_show_at" json:"no_show_at"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Holds reports whether the reservation keeps its table from other bookings
+// between start and end.
+func (r Reservation) Holds(start, end time.Time) bool {
+	if r.Status != ReservationStatusBooked && r.Status != ReservationStatusSeated {
+		return false
+	}
+	return r.StartsAt.Before(end) && start.Before(r.EndsAt)
+}
+
+// CanMoveReservation reports whether a reservation in status from may move to
+// status to.
+func CanMoveReservation(from, to string) bool {
+	for _, next := range reservationTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Slot is a time a party may be booked at, with the tables free for it.
+type Slot struct {
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	TableNumbers []int     `json:"table_numbers"`
+}
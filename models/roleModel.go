@@ -0,0 +1,15 @@
+package models
+
+const (
+	RoleAdmin   = "ADMIN"
+	RoleUser    = "USER"
+	RoleWaiter  = "WAITER"
+	RoleChef    = "CHEF"
+	RoleCashier = "CASHIER"
+	RoleManager = "MANAGER"
+)
+
+var (
+	AllRoles   = []string{RoleAdmin, RoleUser, RoleWaiter, RoleChef, RoleCashier, RoleManager}
+	StaffRoles = []string{RoleWaiter, RoleChef, RoleCashier, RoleManager}
+)
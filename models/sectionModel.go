@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Section is a group of tables one waiter looks after during a shift. A table
+// belongs to one section at most.
+type Section struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	SectionID string             `bson:"section_id" json:"section_id"`
+	Name      string             `bson:"name" json:"name" validate:"required,min=2,max=50"`
+	TableIDs  []string           `bson:"table_ids" json:"table_ids" validate:"dive,required"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Shift assigns a waiter to a section from StartsAt until EndsAt. Cancelled
+// shifts are kept for the record.
+type Shift struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	ShiftID     string             `bson:"shift_id" json:"shift_id"`
+	SectionID   string             `bson:"section_id" json:"section_id" validate:"required"`
+	WaiterID    string             `bson:"waiter_id" json:"waiter_id" validate:"required"`
+	StartsAt    time.Time          `bson:"starts_at" json:"starts_at"`
+	EndsAt      time.Time          `bson:"ends_at" json:"ends_at"`
+	CreatedBy   string             `bson:"created_by" json:"created_by"`
+	CancelledAt *time.Time         `bson:"cancelled_at" json:"cancelled_at"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Covers reports whether the shift is on at at.
+func (s Shift) Covers(at time.Time) bool {
+	return s.CancelledAt == nil && !at.Before(s.StartsAt) && at.Before(s.EndsAt)
+}
+
+// Overlaps reports whether the shift is on at any time between start and end.
+func (s Shift) Overlaps(start, end time.Time) bool {
+	return s.CancelledAt == nil && s.StartsAt.Before(end) && start.Before(s.EndsAt)
+}
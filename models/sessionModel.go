@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is one login of a user. Every refresh token issued for it belongs
+// to the same family; only CurrentTokenID may be exchanged, so presenting an
+// older token of the family means it was stolen and the session is revoked.
+type Session struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	SessionID      string             `bson:"session_id" json:"session_id"`
+	UserID         string             `bson:"user_id" json:"user_id"`
+	CurrentTokenID string             `bson:"current_token_id" json:"-"`
+	Revoked        bool               `bson:"revoked" json:"revoked"`
+	RevokedAt      *time.Time         `bson:"revoked_at" json:"revoked_at"`
+	RevokedReason  string             `bson:"revoked_reason" json:"revoked_reason"`
+	ExpiresAt      time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Station is a section of the kitchen with its own display, such as the grill,
+// the fryer or the bar. Dishes are sent to the stations that prepare the menu
+// category they belong to.
+type Station struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	StationID  string             `bson:"station_id" json:"station_id"`
+	Name       string             `bson:"name" json:"name" validate:"required,min=2,max=50"`
+	Categories []string           `bson:"categories" json:"categories" validate:"dive,required"`
+	// Default stations also receive the dishes no other station prepares.
+	Default   bool      `bson:"default" json:"default"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Prepares reports whether the station prepares dishes of menu category.
+func (s Station) Prepares(category string) bool {
+	for _, c := range s.Categories {
+		if strings.EqualFold(c, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteStations returns the stations a dish of category goes to: every
+// station that prepares the category or, when there is none, the default
+// stations. Dishes without a category go to the default stations.
+func RouteStations(stations []Station, category *string) []Station {
+	routed := []Station{}
+	if category != nil {
+		for _, station := range stations {
+			if station.Prepares(*category) {
+				routed = append(routed, station)
+			}
+		}
+	}
+	if len(routed) > 0 {
+		return routed
+	}
+	for _, station := range stations {
+		if station.Default {
+			routed = append(routed, station)
+		}
+	}
+	return routed
+}
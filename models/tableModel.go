@@ -7,12 +7,38 @@ import (
 )
 
 type Table struct {
-	ID 					primitive.ObjectID			`bson:"_id"`
-	NumberOfGuests		*int				`json:"number_of_guests" validate="required"`
-	TableNumber			*int				`json:"table_number" validate="required"`
-	CreatedAt			time.Time			`json:"created_at"`		
-	UpdatedAt			time.Time			`json:"updated_at"`		
-	TableID				string				`json:"table_id"`
+	ID             primitive.ObjectID `bson:"_id"`
+	NumberOfGuests *int               `bson:"number_of_guests" json:"number_of_guests" validate:"required"`
+	TableNumber    *int               `bson:"table_number" json:"table_number" validate:"required"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	TableID        string             `bson:"table_id" json:"table_id"`
+	// Status is empty on tables stored before tables had one; read it
+	// through CurrentStatus.
+	Status          string     `bson:"status" json:"status"`
+	StatusChangedAt *time.Time `bson:"status_changed_at" json:"status_changed_at"`
+	Guests          *int       `bson:"guests" json:"guests"`
+	SeatedAt        *time.Time `bson:"seated_at" json:"seated_at"`
+	// OrderIDs are the orders of the party at the table that are not paid
+	// yet.
+	OrderIDs []string     `bson:"order_ids" json:"order_ids"`
+	AreaID   *string      `bson:"area_id" json:"area_id"`
+	Layout   *TableLayout `bson:"layout" json:"layout"`
+	// MergedInto is the table this one has been pushed against for a large
+	// party. The party, its status and its orders are kept on that table.
+	MergedInto *string `bson:"merged_into" json:"merged_into"`
+	// Turns are the last parties that sat at the table, oldest first.
+	Turns []TableTurn `bson:"turns" json:"turns"`
 }
 
+// TableTurn is one party's stay at a table, from being seated until it left.
+type TableTurn struct {
+	Guests   *int      `bson:"guests" json:"guests"`
+	SeatedAt time.Time `bson:"seated_at" json:"seated_at"`
+	LeftAt   time.Time `bson:"left_at" json:"left_at"`
+}
 
+// Duration is how long the party kept the table.
+func (t TableTurn) Duration() time.Duration {
+	return t.LeftAt.Sub(t.SeatedAt)
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	TableStatusAvailable     = "AVAILABLE"
+	TableStatusSeated        = "SEATED"
+	TableStatusOrdered       = "ORDERED"
+	TableStatusBillRequested = "BILL_REQUESTED"
+	TableStatusDirty         = "DIRTY"
+	TableStatusOutOfService  = "OUT_OF_SERVICE"
+)
+
+// tableTransitions lists the statuses each status may move to. A party is
+// seated, orders, asks for the bill and leaves the table to be cleaned.
+var tableTransitions = map[string][]string{
+	TableStatusAvailable:     {TableStatusSeated, TableStatusOrdered, TableStatusOutOfService},
+	TableStatusSeated:        {TableStatusOrdered, TableStatusBillRequested, TableStatusAvailable, TableStatusDirty},
+	TableStatusOrdered:       {TableStatusBillRequested, TableStatusSeated, TableStatusDirty},
+	TableStatusBillRequested: {TableStatusOrdered, TableStatusSeated, TableStatusDirty},
+	TableStatusDirty:         {TableStatusAvailable, TableStatusOutOfService},
+	TableStatusOutOfService:  {TableStatusAvailable},
+}
+
+// CurrentStatus is the status of the table, AVAILABLE for tables stored
+// before tables had one.
+func (t Table) CurrentStatus() string {
+	if t.Status == "" {
+		return TableStatusAvailable
+	}
+	return t.Status
+}
+
+// Occupied reports whether a party sits at the table.
+func (t Table) Occupied() bool {
+	switch t.CurrentStatus() {
+	case TableStatusSeated, TableStatusOrdered, TableStatusBillRequested:
+		return true
+	}
+	return false
+}
+
+func IsTableStatus(status string) bool {
+	_, ok := tableTransitions[status]
+	return ok
+}
+
+// CanMoveTable reports whether a table in status from may move to status to.
+func CanMoveTable(from, to string) bool {
+	return slices.Contains(tableTransitions[from], to)
+}
+
+// NextTableStatuses returns the statuses a table in status may move to.
+func NextTableStatuses(status string) []string {
+	return append([]string{}, tableTransitions[status]...)
+}
+
+// keptTurns is how many past parties a table remembers.
+const keptTurns = 20
+
+// MoveTo puts the table in status at at. A party sitting down starts the
+// seating clock; once it has left the table records its turn and forgets its
+// guests and orders.
+func (t *Table) MoveTo(status string, at time.Time) {
+	if !t.Occupied() {
+		t.SeatedAt = &at
+	}
+	t.Status = status
+	t.StatusChangedAt = &at
+	if !t.Occupied() {
+		if t.SeatedAt != nil && t.SeatedAt.Before(at) {
+			t.Turns = append(t.Turns, TableTurn{Guests: t.Guests, SeatedAt: *t.SeatedAt, LeftAt: at})
+			t.Turns = t.Turns[max(0, len(t.Turns)-keptTurns):]
+		}
+		t.Guests, t.SeatedAt, t.OrderIDs = nil, nil, nil
+	}
+}
+
+// FloorPlanTable is a table as shown on the floor plan, with the orders of
+// the party at it and how long it has been seated, where it stands and who
+// waits on it. A table merged into another shows the status of that one;
+// MergedTables lists the numbers of the tables merged into this one.
+type FloorPlanTable struct {
+	TableID         string           `json:"table_id"`
+	TableNumber     *int             `json:"table_number"`
+	Capacity        *int             `json:"capacity"`
+	AreaID          *string          `json:"area_id"`
+	Layout          *TableLayout     `json:"layout"`
+	MergedInto      *string          `json:"merged_into"`
+	MergedTables    []int            `json:"merged_tables"`
+	SectionID       *string          `json:"section_id"`
+	WaiterID        *string          `json:"waiter_id"`
+	Status          string           `json:"status"`
+	StatusChangedAt *time.Time       `json:"status_changed_at"`
+	Guests          *int             `json:"guests"`
+	SeatedAt        *time.Time       `json:"seated_at"`
+	SeatedMinutes   *int             `json:"seated_minutes"`
+	Orders          []FloorPlanOrder `json:"orders"`
+}
+
+type FloorPlanOrder struct {
+	OrderID    string    `json:"order_id"`
+	Status     string    `json:"status"`
+	GrandTotal *Money    `json:"grand_total"`
+	CreatedAt  time.Time `json:"created_at"`
+}
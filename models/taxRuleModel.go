@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaxServiceCharge is the category a tax rule lists to apply to the service
+// charge. Rules without categories apply to every line and the service charge.
+const TaxServiceCharge = "SERVICE_CHARGE"
+
+// TaxRule is one tax levied when orders are priced. Inclusive taxes are part
+// of the menu price and are carved out of it; exclusive taxes are added on
+// top. A compound tax is levied on the amount plus the exclusive taxes of
+// lower priority.
+type TaxRule struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	Name           *string            `bson:"name" json:"name" validate:"required,min=2,max=50"`
+	Rate           *float64           `bson:"rate" json:"rate" validate:"required,gt=0,lte=1"`
+	Inclusive      *bool              `bson:"inclusive" json:"inclusive"`
+	Compound       *bool              `bson:"compound" json:"compound"`
+	Priority       *int               `bson:"priority" json:"priority"`
+	Categories     []string           `bson:"categories" json:"categories"`
+	ExemptTakeaway *bool              `bson:"exempt_takeaway" json:"exempt_takeaway"`
+	Active         *bool              `bson:"active" json:"active"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	TaxRuleID      string             `bson:"tax_rule_id" json:"tax_rule_id"`
+}
+
+func (t TaxRule) IsInclusive() bool {
+	return t.Inclusive != nil && *t.Inclusive
+}
+
+func (t TaxRule) IsCompound() bool {
+	return t.Compound != nil && *t.Compound
+}
+
+// Rank orders rules for evaluation; rules without a priority come first.
+func (t TaxRule) Rank() int {
+	if t.Priority == nil {
+		return 0
+	}
+	return *t.Priority
+}
+
+func (t TaxRule) IsActive() bool {
+	return t.Active == nil || *t.Active
+}
+
+// AppliesTo reports whether the rule taxes an amount of category on an order
+// that is or is not a takeaway.
+func (t TaxRule) AppliesTo(category string, takeaway bool) bool {
+	if takeaway && t.ExemptTakeaway != nil && *t.ExemptTakeaway {
+		return false
+	}
+	if len(t.Categories) == 0 {
+		return true
+	}
+	for _, c := range t.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// TaxLine is the amount one tax rule contributes to an order.
+type TaxLine struct {
+	TaxRuleID string  `bson:"tax_rule_id" json:"tax_rule_id"`
+	Name      string  `bson:"name" json:"name"`
+	Rate      float64 `bson:"rate" json:"rate"`
+	Inclusive bool    `bson:"inclusive" json:"inclusive"`
+	Compound  bool    `bson:"compound" json:"compound"`
+	Taxable   Money   `bson:"taxable" json:"taxable"`
+	Amount    Money   `bson:"amount" json:"amount"`
+}
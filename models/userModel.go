@@ -7,16 +7,16 @@ import (
 )
 
 type User struct {
-	ID 				primitive.ObjectID 		`bson:"_id"`
-	FirstName		*string					`json:"firstname" validate:"required,min=2,max=100"`
-	LastName		*string					`json:"lastname" validate:"required,min=2,max=100"`
-	Password		*string					`json:"password" validate:"required,min=6"`
-	Email			*string					`json:"email" validate:"email,required"`
-	Phone			*string					`json:"phone" validate:"required"`
-	Token			*string					`json:"token"`
-	UserType		*string					`json:"usertype" validate:"required,eq=ADMIN|eq=USER"`
-	RefreshToken	*string					`json:"refreshtoken"`
-	CreatedAt		time.Time				`json:"created_at"`
-	UpdatedAt		time.Time				`json:"updated_at"`
-	UserID			string					`json:"userid"`
-} 
\ No newline at end of file
+	ID           primitive.ObjectID `bson:"_id"`
+	FirstName    *string            `bson:"firstname" json:"firstname" validate:"required,min=2,max=100"`
+	LastName     *string            `bson:"lastname" json:"lastname" validate:"required,min=2,max=100"`
+	Password     *string            `bson:"password" json:"password" validate:"required,min=6"`
+	Email        *string            `bson:"email" json:"email" validate:"email,required"`
+	Phone        *string            `bson:"phone" json:"phone" validate:"required"`
+	Token        *string            `bson:"token" json:"token"`
+	UserType     *string            `bson:"usertype" json:"usertype" validate:"required,oneof=ADMIN USER WAITER CHEF CASHIER MANAGER"`
+	RefreshToken *string            `bson:"refresh_token" json:"refreshtoken"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	UserID       string             `bson:"userid" json:"userid"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	WaitlistStatusWaiting   = "WAITING"
+	WaitlistStatusNotified  = "NOTIFIED"
+	WaitlistStatusSeated    = "SEATED"
+	WaitlistStatusCancelled = "CANCELLED"
+)
+
+// waitlistTransitions lists the statuses each status may move to. A party is
+// told when its table is ready and then seated, or leaves the list before.
+// SEATED and CANCELLED are terminal.
+var waitlistTransitions = map[string][]string{
+	WaitlistStatusWaiting:   {WaitlistStatusNotified, WaitlistStatusSeated, WaitlistStatusCancelled},
+	WaitlistStatusNotified:  {WaitlistStatusNotified, WaitlistStatusSeated, WaitlistStatusCancelled},
+	WaitlistStatusSeated:    {},
+	WaitlistStatusCancelled: {},
+}
+
+// WaitlistEntry is a walk-in party waiting for a table. QuotedMinutes is the
+// wait the party was told when it joined the list.
+type WaitlistEntry struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	EntryID       string             `bson:"entry_id" json:"entry_id"`
+	GuestName     string             `bson:"guest_name" json:"guest_name" validate:"required,max=100"`
+	Phone         *string            `bson:"phone" json:"phone" validate:"omitempty,max=20"`
+	PartySize     int                `bson:"party_size" json:"party_size" validate:"required,min=1"`
+	Notes         *string            `bson:"notes" json:"notes" validate:"omitempty,max=200"`
+	QuotedMinutes int                `bson:"quoted_minutes" json:"quoted_minutes"`
+	Status        string             `bson:"status" json:"status"`
+	AddedBy       string             `bson:"added_by" json:"added_by"`
+	TableID       *string            `bson:"table_id" json:"table_id"`
+	TableNumber   *int               `bson:"table_number" json:"table_number"`
+	NotifiedAt    *time.Time         `bson:"notified_at" json:"notified_at"`
+	SeatedAt      *time.Time         `bson:"seated_at" json:"seated_at"`
+	CancelledAt   *time.Time         `bson:"cancelled_at" json:"cancelled_at"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Waiting reports whether the party is still on the list.
+func (e WaitlistEntry) Waiting() bool {
+	return e.Status == WaitlistStatusWaiting || e.Status == WaitlistStatusNotified
+}
+
+// CanMoveWaitlistEntry reports whether an entry in status from may move to
+// status to. NOTIFIED may be repeated to remind the party.
+func CanMoveWaitlistEntry(from, to string) bool {
+	for _, next := range waitlistTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// WaitlistPosition is an entry as shown on the list, with its place in the
+// queue and how long it has waited and is expected to wait still.
+type WaitlistPosition struct {
+	WaitlistEntry
+	Position         int `json:"position"`
+	WaitedMinutes    int `json:"waited_minutes"`
+	EstimatedMinutes int `json:"estimated_minutes"`
+}
+
+// WaitEstimate is the wait a party of PartySize would be quoted now.
+type WaitEstimate struct {
+	PartySize        int `json:"party_size"`
+	Ahead            int `json:"ahead"`
+	EstimatedMinutes int `json:"estimated_minutes"`
+}
@@ -0,0 +1,23 @@
+package notifications
+
+import (
+	"context"
+	"log"
+)
+
+// LogNotifier writes messages to the log instead of sending them, for local
+// development.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Name() string {
+	return "log"
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, message Message) error {
+	log.Printf("notification to %s: %s", message.To, message.Body)
+	return nil
+}
@@ -0,0 +1,19 @@
+// Package notifications sends guests short messages, such as a text telling
+// them their table is ready.
+package notifications
+
+import "context"
+
+// Notifier delivers messages to guests through a provider such as an SMS
+// gateway.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, message Message) error
+}
+
+// Message is a text for one guest. To is where the provider delivers it, a
+// phone number for SMS.
+type Message struct {
+	To   string
+	Body string
+}
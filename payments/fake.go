@@ -0,0 +1,148 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Micah-Shallom/modules/models"
+)
+
+// Card tokens the fake gateway understands. Any other source is approved.
+const (
+	FakeSourceDeclined          = "tok_declined"
+	FakeSourceInsufficientFunds = "tok_insufficient_funds"
+)
+
+// FakeGateway is an in-memory Gateway for tests and local development. It is
+// deterministic: references are numbered in the order operations happen, and
+// the outcome of an authorization depends only on its source. It enforces the
+// rules a real provider does, such as not capturing more than was authorized.
+type FakeGateway struct {
+	mu             sync.Mutex
+	sequence       int
+	authorizations map[string]*fakeAuthorization
+	captures       map[string]*fakeCapture
+	replies        map[string]fakeReply
+}
+
+type fakeAuthorization struct {
+	amount   models.Money
+	captured bool
+	voided   bool
+}
+
+type fakeCapture struct {
+	amount   models.Money
+	refunded models.Money
+}
+
+// fakeReply is the outcome of a call, replayed when its idempotency key is
+// seen again.
+type fakeReply struct {
+	result Result
+	err    error
+}
+
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{
+		authorizations: map[string]*fakeAuthorization{},
+		captures:       map[string]*fakeCapture{},
+		replies:        map[string]fakeReply{},
+	}
+}
+
+func (g *FakeGateway) Name() string {
+	return "fake"
+}
+
+func (g *FakeGateway) Authorize(ctx context.Context, request AuthorizeRequest) (Result, error) {
+	return g.once(ctx, "authorize", request.IdempotencyKey, func() (Result, error) {
+		switch request.Source {
+		case FakeSourceDeclined:
+			return Result{}, &DeclinedError{Code: "card_declined"}
+		case FakeSourceInsufficientFunds:
+			return Result{}, &DeclinedError{Code: "insufficient_funds"}
+		}
+		if request.Amount.Minor <= 0 {
+			return Result{}, &DeclinedError{Code: "invalid_amount"}
+		}
+		ref := g.nextRef("auth")
+		g.authorizations[ref] = &fakeAuthorization{amount: request.Amount}
+		return Result{Ref: ref, Amount: request.Amount}, nil
+	})
+}
+
+func (g *FakeGateway) Capture(ctx context.Context, authorizationRef string, amount models.Money, idempotencyKey string) (Result, error) {
+	return g.once(ctx, "capture", idempotencyKey, func() (Result, error) {
+		authorization, ok := g.authorizations[authorizationRef]
+		if !ok {
+			return Result{}, ErrUnknownReference
+		}
+		switch {
+		case authorization.captured:
+			return Result{}, &DeclinedError{Code: "already_captured"}
+		case authorization.voided:
+			return Result{}, &DeclinedError{Code: "authorization_voided"}
+		case amount.Minor <= 0 || amount.Cmp(authorization.amount) > 0:
+			return Result{}, &DeclinedError{Code: "invalid_amount"}
+		}
+		authorization.captured = true
+		ref := g.nextRef("cap")
+		g.captures[ref] = &fakeCapture{amount: amount, refunded: models.NewMoney(0, amount.Currency)}
+		return Result{Ref: ref, Amount: amount}, nil
+	})
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, captureRef string, amount models.Money, idempotencyKey string) (Result, error) {
+	return g.once(ctx, "refund", idempotencyKey, func() (Result, error) {
+		capture, ok := g.captures[captureRef]
+		if !ok {
+			return Result{}, ErrUnknownReference
+		}
+		if amount.Minor <= 0 || capture.refunded.Add(amount).Cmp(capture.amount) > 0 {
+			return Result{}, &DeclinedError{Code: "invalid_amount"}
+		}
+		capture.refunded = capture.refunded.Add(amount)
+		return Result{Ref: g.nextRef("ref"), Amount: amount}, nil
+	})
+}
+
+func (g *FakeGateway) Void(ctx context.Context, authorizationRef string, idempotencyKey string) (Result, error) {
+	return g.once(ctx, "void", idempotencyKey, func() (Result, error) {
+		authorization, ok := g.authorizations[authorizationRef]
+		if !ok {
+			return Result{}, ErrUnknownReference
+		}
+		if authorization.captured {
+			return Result{}, &DeclinedError{Code: "already_captured"}
+		}
+		authorization.voided = true
+		return Result{Ref: g.nextRef("void"), Amount: authorization.amount}, nil
+	})
+}
+
+// once runs op under the lock unless a call of the same kind with the same
+// idempotency key already ran, in which case it replays that call's reply.
+func (g *FakeGateway) once(ctx context.Context, kind, idempotencyKey string, op func() (Result, error)) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := kind + ":" + idempotencyKey
+	if reply, ok := g.replies[key]; ok && idempotencyKey != "" {
+		return reply.result, reply.err
+	}
+	result, err := op()
+	if idempotencyKey != "" {
+		g.replies[key] = fakeReply{result: result, err: err}
+	}
+	return result, err
+}
+
+func (g *FakeGateway) nextRef(prefix string) string {
+	g.sequence++
+	return fmt.Sprintf("fake_%s_%06d", prefix, g.sequence)
+}
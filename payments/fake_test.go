@@ -0,0 +1,150 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Micah-Shallom/modules/models"
+)
+
+func declineCode(err error) string {
+	var declined *DeclinedError
+	if !errors.As(err, &declined) {
+		return ""
+	}
+	return declined.Code
+}
+
+func TestFakeGatewayAuthorizeAndCapture(t *testing.T) {
+	ctx := context.Background()
+	gateway := NewFakeGateway()
+	amount := models.NewMoney(2500, "NGN")
+
+	auth, err := gateway.Authorize(ctx, AuthorizeRequest{Amount: amount, Source: "tok_visa", IdempotencyKey: "p1:authorize"})
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if auth.Ref == "" || auth.Amount != amount {
+		t.Fatalf("Authorize: got %+v, want a reference for %v", auth, amount)
+	}
+
+	over := amount.Add(models.NewMoney(1, "NGN"))
+	if _, err := gateway.Capture(ctx, auth.Ref, over, "p1:capture-over"); declineCode(err) != "invalid_amount" {
+		t.Errorf("Capture of more than was authorized: got %v, want invalid_amount", err)
+	}
+	capture, err := gateway.Capture(ctx, auth.Ref, amount, "p1:capture")
+	if err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+	if capture.Ref == "" || capture.Ref == auth.Ref || capture.Amount != amount {
+		t.Errorf("Capture: got %+v, want a new reference for %v", capture, amount)
+	}
+	if _, err := gateway.Capture(ctx, auth.Ref, amount, "p1:capture-again"); declineCode(err) != "already_captured" {
+		t.Errorf("second Capture: got %v, want already_captured", err)
+	}
+	if _, err := gateway.Void(ctx, auth.Ref, "p1:void"); declineCode(err) != "already_captured" {
+		t.Errorf("Void of a captured authorization: got %v, want already_captured", err)
+	}
+	if _, err := gateway.Capture(ctx, "fake_auth_unknown", amount, "p2:capture"); !errors.Is(err, ErrUnknownReference) {
+		t.Errorf("Capture of an unknown authorization: got %v, want ErrUnknownReference", err)
+	}
+}
+
+func TestFakeGatewayDecline(t *testing.T) {
+	ctx := context.Background()
+	gateway := NewFakeGateway()
+	amount := models.NewMoney(2500, "NGN")
+
+	tests := []struct {
+		source string
+		amount models.Money
+		code   string
+	}{
+		{FakeSourceDeclined, amount, "card_declined"},
+		{FakeSourceInsufficientFunds, amount, "insufficient_funds"},
+		{"tok_visa", models.NewMoney(0, "NGN"), "invalid_amount"},
+	}
+	for _, tt := range tests {
+		_, err := gateway.Authorize(ctx, AuthorizeRequest{Amount: tt.amount, Source: tt.source, IdempotencyKey: tt.source + ":authorize"})
+		if got := declineCode(err); got != tt.code {
+			t.Errorf("Authorize from %s: got %v, want %s", tt.source, err, tt.code)
+		}
+	}
+
+	auth, err := gateway.Authorize(ctx, AuthorizeRequest{Amount: amount, Source: "tok_visa", IdempotencyKey: "p1:authorize"})
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if _, err := gateway.Void(ctx, auth.Ref, "p1:void"); err != nil {
+		t.Fatalf("Void: %v", err)
+	}
+	if _, err := gateway.Capture(ctx, auth.Ref, amount, "p1:capture"); declineCode(err) != "authorization_voided" {
+		t.Errorf("Capture of a voided authorization: got %v, want authorization_voided", err)
+	}
+}
+
+func TestFakeGatewayIdempotentRetry(t *testing.T) {
+	ctx := context.Background()
+	gateway := NewFakeGateway()
+	amount := models.NewMoney(2500, "NGN")
+	request := AuthorizeRequest{Amount: amount, Source: "tok_visa", IdempotencyKey: "p1:authorize"}
+
+	first, err := gateway.Authorize(ctx, request)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	retried, err := gateway.Authorize(ctx, request)
+	if err != nil {
+		t.Fatalf("retried Authorize: %v", err)
+	}
+	if retried != first {
+		t.Errorf("retried Authorize: got %+v, want the first result %+v", retried, first)
+	}
+	if len(gateway.authorizations) != 1 {
+		t.Errorf("authorizations after a retry: got %d, want 1", len(gateway.authorizations))
+	}
+
+	// the same key on another kind of call is a different call
+	capture, err := gateway.Capture(ctx, first.Ref, amount, "p1:authorize")
+	if err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+	retriedCapture, err := gateway.Capture(ctx, first.Ref, amount, "p1:authorize")
+	if err != nil || retriedCapture != capture {
+		t.Errorf("retried Capture: got %+v, %v, want the first result %+v", retriedCapture, err, capture)
+	}
+
+	// refunds in parts, each retried once, never credit more than asked
+	half := models.NewMoney(1250, "NGN")
+	for _, key := range []string{"r1", "r1", "r2", "r2"} {
+		if _, err := gateway.Refund(ctx, capture.Ref, half, key); err != nil {
+			t.Fatalf("Refund %s: %v", key, err)
+		}
+	}
+	if _, err := gateway.Refund(ctx, capture.Ref, models.NewMoney(1, "NGN"), "r3"); declineCode(err) != "invalid_amount" {
+		t.Errorf("Refund beyond the capture: got %v, want invalid_amount", err)
+	}
+
+	// a declined call is replayed as declined
+	declined := AuthorizeRequest{Amount: amount, Source: FakeSourceDeclined, IdempotencyKey: "p2:authorize"}
+	_, err = gateway.Authorize(ctx, declined)
+	_, retriedErr := gateway.Authorize(ctx, declined)
+	if declineCode(err) != "card_declined" || declineCode(retriedErr) != "card_declined" {
+		t.Errorf("retried decline: got %v then %v, want card_declined twice", err, retriedErr)
+	}
+}
+
+func TestFakeGatewayCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	gateway := NewFakeGateway()
+
+	_, err := gateway.Authorize(ctx, AuthorizeRequest{Amount: models.NewMoney(100, "NGN"), Source: "tok_visa", IdempotencyKey: "p1:authorize"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Authorize: got %v, want context.Canceled", err)
+	}
+	if len(gateway.replies) != 0 {
+		t.Errorf("a cancelled call was remembered for its key")
+	}
+}
@@ -0,0 +1,59 @@
+// Package payments talks to the card payment providers the restaurant takes
+// payments through.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Micah-Shallom/modules/models"
+)
+
+// Gateway is a card payment provider. Money is first authorized, which holds
+// it on the card, then captured, which charges it. An authorization that is
+// not captured can be voided, and a capture can be refunded in one or more
+// parts.
+//
+// Every call carries an idempotency key. A provider that sees a key again
+// returns the result of the first call with that key instead of repeating it,
+// so a request retried after a timeout never charges twice.
+type Gateway interface {
+	Name() string
+	Authorize(ctx context.Context, request AuthorizeRequest) (Result, error)
+	Capture(ctx context.Context, authorizationRef string, amount models.Money, idempotencyKey string) (Result, error)
+	Refund(ctx context.Context, captureRef string, amount models.Money, idempotencyKey string) (Result, error)
+	Void(ctx context.Context, authorizationRef string, idempotencyKey string) (Result, error)
+}
+
+type AuthorizeRequest struct {
+	Amount models.Money
+	// Source is the provider's token for the card, as collected by the
+	// terminal or the payment page.
+	Source string
+	// Reference identifies what is being paid for in the provider's records.
+	Reference      string
+	IdempotencyKey string
+}
+
+// Result is the provider's record of an operation: Ref identifies it for
+// later calls, such as capturing an authorization.
+type Result struct {
+	Ref    string
+	Amount models.Money
+}
+
+// ErrUnknownReference is returned for operations on an authorization or
+// capture the provider has no record of.
+var ErrUnknownReference = errors.New("payments: unknown reference")
+
+// DeclinedError is returned when the provider refuses an operation, for
+// example because the card has insufficient funds. Code is the provider's
+// reason, such as "card_declined".
+type DeclinedError struct {
+	Code string
+}
+
+func (e *DeclinedError) Error() string {
+	return fmt.Sprintf("payments: declined (%s)", e.Code)
+}
@@ -0,0 +1,138 @@
+// Package printing encodes kitchen tickets and receipts for ESC/POS thermal
+// printers and sends them to network printers through a job queue.
+package printing
+
+import (
+	"bytes"
+	"strings"
+)
+
+// ESC/POS command bytes.
+const (
+	esc = 0x1b
+	gs  = 0x1d
+	lf  = 0x0a
+)
+
+// Alignments for Encoder.Align.
+const (
+	AlignLeft   = 0
+	AlignCenter = 1
+	AlignRight  = 2
+)
+
+// Encoder builds an ESC/POS byte stream. Text is sent in the WPC1252 code
+// page, so accented Latin letters print as such and any other character as a
+// question mark.
+type Encoder struct {
+	buf bytes.Buffer
+}
+
+// NewEncoder starts a stream that resets the printer and selects WPC1252.
+func NewEncoder() *Encoder {
+	e := &Encoder{}
+	e.buf.Write([]byte{esc, '@', esc, 't', 16})
+	return e
+}
+
+func (e *Encoder) Align(alignment byte) *Encoder {
+	e.buf.Write([]byte{esc, 'a', alignment})
+	return e
+}
+
+func (e *Encoder) Bold(on bool) *Encoder {
+	e.buf.Write([]byte{esc, 'E', flag(on)})
+	return e
+}
+
+// Size sets the character size, each dimension from 1 to 8 times normal.
+func (e *Encoder) Size(width, height int) *Encoder {
+	e.buf.Write([]byte{gs, '!', byte(clamp(width)-1)<<4 | byte(clamp(height)-1)})
+	return e
+}
+
+// Text prints s, which may span several lines.
+func (e *Encoder) Text(s string) *Encoder {
+	for _, r := range s {
+		switch {
+		case r == '\n' || (r >= 0x20 && r < 0x7f):
+			e.buf.WriteByte(byte(r))
+		case r >= 0xa0 && r <= 0xff:
+			e.buf.WriteByte(byte(r))
+		default:
+			e.buf.WriteByte('?')
+		}
+	}
+	return e
+}
+
+// Line prints s and ends the line.
+func (e *Encoder) Line(s string) *Encoder {
+	e.Text(strings.TrimSuffix(s, "\n"))
+	e.buf.WriteByte(lf)
+	return e
+}
+
+// Feed advances the paper by n lines.
+func (e *Encoder) Feed(n int) *Encoder {
+	e.buf.Write([]byte{esc, 'd', byte(n)})
+	return e
+}
+
+// Cut feeds the paper up to the cutter and cuts it, leaving a small hinge.
+func (e *Encoder) Cut() *Encoder {
+	e.buf.Write([]byte{gs, 'V', 66, 3})
+	return e
+}
+
+func (e *Encoder) Bytes() []byte {
+	return e.buf.Bytes()
+}
+
+func flag(on bool) byte {
+	if on {
+		return 1
+	}
+	return 0
+}
+
+func clamp(n int) int {
+	if n < 1 {
+		return 1
+	}
+	if n > 8 {
+		return 8
+	}
+	return n
+}
+
+// Plain strips the commands out of an ESC/POS stream produced by Encoder,
+// leaving the text a printer would print. The fake printer logs it.
+func Plain(data []byte) string {
+	var b strings.Builder
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case esc:
+			switch {
+			case i+1 < len(data) && data[i+1] == '@':
+				i++
+			case i+2 < len(data):
+				i += 2
+			}
+		case gs:
+			switch {
+			case i+1 < len(data) && data[i+1] == 'V':
+				i += 3
+			case i+2 < len(data):
+				i += 2
+			}
+		default:
+			if data[i] < 0x80 {
+				b.WriteByte(data[i])
+			} else {
+				b.WriteRune(rune(data[i]))
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package printing
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// FakePrinter is a raw TCP printer for tests and local development. It
+// listens on a local port and keeps everything sent to it, one document per
+// connection, as a network printer would print it.
+type FakePrinter struct {
+	listener net.Listener
+	onPrint  func([]byte)
+
+	mu        sync.Mutex
+	documents [][]byte
+	done      sync.WaitGroup
+}
+
+// NewFakePrinter listens on a free port of the loopback interface. onPrint,
+// if set, is called with every document received.
+func NewFakePrinter(onPrint func([]byte)) (*FakePrinter, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &FakePrinter{listener: listener, onPrint: onPrint}
+	p.done.Add(1)
+	go p.serve()
+	return p, nil
+}
+
+// Addr is the host:port to send jobs to.
+func (p *FakePrinter) Addr() string {
+	return p.listener.Addr().String()
+}
+
+// Documents returns what was printed so far, in the order it arrived.
+func (p *FakePrinter) Documents() [][]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([][]byte(nil), p.documents...)
+}
+
+// Close stops listening and waits for documents being received.
+func (p *FakePrinter) Close() error {
+	err := p.listener.Close()
+	p.done.Wait()
+	return err
+}
+
+func (p *FakePrinter) serve() {
+	defer p.done.Done()
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		p.done.Add(1)
+		go p.receive(conn)
+	}
+}
+
+func (p *FakePrinter) receive(conn net.Conn) {
+	defer p.done.Done()
+	defer conn.Close()
+
+	// a client that never hangs up must not keep Close waiting forever
+	_ = conn.SetReadDeadline(time.Now().Add(writeTimeout))
+	var document bytes.Buffer
+	if _, err := io.Copy(&document, conn); err != nil || document.Len() == 0 {
+		return
+	}
+	p.mu.Lock()
+	p.documents = append(p.documents, document.Bytes())
+	p.mu.Unlock()
+	if p.onPrint != nil {
+		p.onPrint(document.Bytes())
+	}
+}
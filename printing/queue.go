@@ -0,0 +1,265 @@
+package printing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Names of the printers jobs are sent to.
+const (
+	PrinterKitchen = "kitchen"
+	PrinterReceipt = "receipt"
+)
+
+// Kinds of print job.
+const (
+	JobKitchenTicket = "KITCHEN_TICKET"
+	JobReceipt       = "RECEIPT"
+)
+
+const (
+	JobStatusQueued   = "QUEUED"
+	JobStatusPrinting = "PRINTING"
+	JobStatusPrinted  = "PRINTED"
+	JobStatusFailed   = "FAILED"
+)
+
+const (
+	// attempts is how many times a job is sent before it is given up, with
+	// a growing pause in between while the printer is unreachable.
+	attempts     = 3
+	retryBackoff = 2 * time.Second
+	dialTimeout  = 5 * time.Second
+	writeTimeout = 10 * time.Second
+	// pending is how many jobs may wait for one printer, and kept how many
+	// finished jobs are remembered for GET /printJobs.
+	pending = 64
+	kept    = 200
+)
+
+var (
+	ErrUnknownPrinter = errors.New("printing: printer is not configured")
+	ErrQueueFull      = errors.New("printing: too many jobs are waiting for the printer")
+	ErrQueueClosed    = errors.New("printing: queue is closed")
+)
+
+// Job is one document sent to a printer.
+type Job struct {
+	JobID     string     `json:"job_id"`
+	Printer   string     `json:"printer"`
+	Kind      string     `json:"kind"`
+	Reference string     `json:"reference"`
+	Size      int        `json:"size"`
+	Status    string     `json:"status"`
+	Attempts  int        `json:"attempts"`
+	Error     string     `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	PrintedAt *time.Time `json:"printed_at"`
+}
+
+// Queue sends print jobs to raw TCP printers, such as those listening on port
+// 9100. Each printer has its own worker so jobs reach it one at a time and in
+// the order they were submitted, and a printer that is down does not hold up
+// the others. Jobs live in memory: those still waiting when the process stops
+// are not printed.
+type Queue struct {
+	mu      sync.Mutex
+	workers map[string]chan *queuedJob
+	jobs    map[string]*Job
+	order   []string
+	closed  bool
+	stop    context.CancelFunc
+	stopped context.Context
+	done    sync.WaitGroup
+}
+
+type queuedJob struct {
+	job  *Job
+	data []byte
+}
+
+// NewQueue starts a worker for every printer, keyed by name, with its
+// host:port address. Printers with an empty address are left out.
+func NewQueue(printers map[string]string) *Queue {
+	ctx, stop := context.WithCancel(context.Background())
+	q := &Queue{
+		workers: map[string]chan *queuedJob{},
+		jobs:    map[string]*Job{},
+		stop:    stop,
+		stopped: ctx,
+	}
+	for name, addr := range printers {
+		if addr == "" {
+			continue
+		}
+		jobs := make(chan *queuedJob, pending)
+		q.workers[name] = jobs
+		q.done.Add(1)
+		go q.work(addr, jobs)
+	}
+	return q
+}
+
+// Submit queues data for the named printer. kind and reference, such as the
+// order or invoice printed, describe the job to whoever looks it up.
+func (q *Queue) Submit(printer, kind, reference string, data []byte) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return Job{}, ErrQueueClosed
+	}
+	jobs, ok := q.workers[printer]
+	if !ok {
+		return Job{}, ErrUnknownPrinter
+	}
+	createdAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	job := &Job{
+		JobID:     primitive.NewObjectID().Hex(),
+		Printer:   printer,
+		Kind:      kind,
+		Reference: reference,
+		Size:      len(data),
+		Status:    JobStatusQueued,
+		CreatedAt: createdAt,
+	}
+	select {
+	case jobs <- &queuedJob{job: job, data: data}:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	q.remember(job)
+	return *job, nil
+}
+
+// Job returns the job with the given ID, if it is still remembered.
+func (q *Queue) Job(jobID string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[jobID]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Jobs lists the remembered jobs, newest first.
+func (q *Queue) Jobs() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]Job, 0, len(q.order))
+	for i := len(q.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, *q.jobs[q.order[i]])
+	}
+	return jobs
+}
+
+// Close stops accepting jobs and waits for those already queued to be sent,
+// until ctx is done, at which point sending is abandoned.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		for _, jobs := range q.workers {
+			close(jobs)
+		}
+	}
+	q.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		q.done.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		q.stop()
+		<-finished
+		return fmt.Errorf("print jobs left unsent: %w", ctx.Err())
+	}
+}
+
+// remember keeps job for lookups, forgetting the oldest ones beyond kept.
+// q.mu must be held.
+func (q *Queue) remember(job *Job) {
+	q.jobs[job.JobID] = job
+	q.order = append(q.order, job.JobID)
+	for len(q.order) > kept {
+		delete(q.jobs, q.order[0])
+		q.order = q.order[1:]
+	}
+}
+
+func (q *Queue) work(addr string, jobs <-chan *queuedJob) {
+	defer q.done.Done()
+	for queued := range jobs {
+		q.print(addr, queued)
+	}
+}
+
+func (q *Queue) print(addr string, queued *queuedJob) {
+	var err error
+retry:
+	for attempt := 1; attempt <= attempts; attempt++ {
+		q.update(queued.job, func(job *Job) {
+			job.Status = JobStatusPrinting
+			job.Attempts = attempt
+		})
+		if err = Send(q.stopped, addr, queued.data); err == nil {
+			printedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			q.update(queued.job, func(job *Job) {
+				job.Status = JobStatusPrinted
+				job.Error = ""
+				job.PrintedAt = &printedAt
+			})
+			return
+		}
+		if attempt == attempts {
+			break
+		}
+		select {
+		case <-time.After(time.Duration(attempt) * retryBackoff):
+		case <-q.stopped.Done():
+			break retry
+		}
+	}
+	log.Printf("print job %s to %s failed: %v", queued.job.JobID, addr, err)
+	q.update(queued.job, func(job *Job) {
+		job.Status = JobStatusFailed
+		job.Error = err.Error()
+	})
+}
+
+func (q *Queue) update(job *Job, change func(*Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	change(job)
+}
+
+// Send writes data to the raw TCP printer at addr in one connection.
+func Send(ctx context.Context, addr string, data []byte) error {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	if _, err := conn.Write(data); err != nil {
+		return err
+	}
+	return conn.Close()
+}
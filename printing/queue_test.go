@@ -0,0 +1,129 @@
+package printing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/receipts"
+)
+
+func newTestPrinter(t *testing.T) *FakePrinter {
+	t.Helper()
+	printer, err := NewFakePrinter(nil)
+	if err != nil {
+		t.Fatalf("NewFakePrinter: %v", err)
+	}
+	t.Cleanup(func() { printer.Close() })
+	return printer
+}
+
+func TestQueuePrintsKitchenTicketAndReceipt(t *testing.T) {
+	kitchen, receipt := newTestPrinter(t), newTestPrinter(t)
+	queue := NewQueue(map[string]string{PrinterKitchen: kitchen.Addr(), PrinterReceipt: receipt.Addr()})
+
+	table := 4
+	at := time.Date(2026, 10, 18, 12, 30, 0, 0, time.UTC)
+	ticket := KitchenTicket(Ticket{
+		OrderID:     "order1",
+		TableNumber: &table,
+		Lines:       []TicketLine{{Name: "Café au lait", Units: 2, Modifiers: []string{"Oat milk"}, Notes: "no sugar"}},
+		PrintedAt:   at,
+	})
+	price := models.NewMoney(1500, "NGN")
+	bill := receipts.Receipt{
+		Header:        receipts.Header{Name: "The Grill", Address: "1 Main Street"},
+		InvoiceNumber: "LAG-2026-000001",
+		IssuedAt:      at,
+		Lines:         []receipts.Line{{Name: "Burger", Units: 2, UnitPrice: price, Amount: price.Mul(2)}},
+		Total:         price.Mul(2),
+		Paid:          models.NewMoney(0, "NGN"),
+		Refunded:      models.NewMoney(0, "NGN"),
+		Balance:       price.Mul(2),
+		Status:        models.PaymentStatusPending,
+	}
+
+	ticketJob, err := queue.Submit(PrinterKitchen, JobKitchenTicket, "order1", ticket)
+	if err != nil {
+		t.Fatalf("Submit kitchen ticket: %v", err)
+	}
+	receiptJob, err := queue.Submit(PrinterReceipt, JobReceipt, "LAG-2026-000001", Receipt(bill, receipts.NarrowWidth))
+	if err != nil {
+		t.Fatalf("Submit receipt: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := queue.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	kitchen.Close()
+	receipt.Close()
+
+	for _, submitted := range []Job{ticketJob, receiptJob} {
+		job, ok := queue.Job(submitted.JobID)
+		if !ok || job.Status != JobStatusPrinted || job.Attempts != 1 || job.PrintedAt == nil {
+			t.Errorf("job %s: got %+v, want printed at the first attempt", submitted.Kind, job)
+		}
+	}
+
+	rule := strings.Repeat("-", receipts.NarrowWidth) + "\n"
+	var want bytes.Buffer
+	want.Write([]byte{esc, '@', esc, 't', 16})
+	want.Write([]byte{esc, 'a', AlignCenter, esc, 'E', 1, gs, '!', 0x11})
+	want.WriteString("TABLE 4\n")
+	want.Write([]byte{gs, '!', 0x00, esc, 'E', 0})
+	want.WriteString("Order order1\n2026-10-18 12:30\n")
+	want.Write([]byte{esc, 'a', AlignLeft})
+	want.WriteString(rule)
+	want.Write([]byte{esc, 'E', 1, gs, '!', 0x01})
+	want.WriteString("  2 x Caf\xe9 au lait\n")
+	want.Write([]byte{esc, 'E', 0, gs, '!', 0x00})
+	want.WriteString("      + Oat milk\n      NOTE: no sugar\n")
+	want.WriteString(rule)
+	want.Write([]byte{esc, 'd', 3, gs, 'V', 66, 3})
+
+	documents := kitchen.Documents()
+	if len(documents) != 1 {
+		t.Fatalf("kitchen printer received %d documents, want 1", len(documents))
+	}
+	if !bytes.Equal(documents[0], want.Bytes()) {
+		t.Errorf("kitchen ticket:\n got %q\nwant %q", documents[0], want.Bytes())
+	}
+
+	documents = receipt.Documents()
+	if len(documents) != 1 {
+		t.Fatalf("receipt printer received %d documents, want 1", len(documents))
+	}
+	printed := documents[0]
+	start := []byte{esc, '@', esc, 't', 16, esc, 'E', 1, gs, '!', 0x01}
+	end := []byte{esc, 'd', 3, gs, 'V', 66, 3}
+	if !bytes.HasPrefix(printed, start) || !bytes.HasSuffix(printed, end) {
+		t.Errorf("receipt: got %q, want it to start with %q and end with %q", printed, start, end)
+	}
+	// the receipt prints the text layout line by line, whatever the commands
+	// around each line
+	text := Plain(printed)
+	for _, line := range strings.Split(strings.TrimSuffix(receipts.Text(bill, receipts.NarrowWidth), "\n"), "\n") {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("receipt is missing the line %q", line)
+		}
+	}
+}
+
+func TestQueueRefusesUnknownPrinterAndClosedQueue(t *testing.T) {
+	queue := NewQueue(map[string]string{PrinterKitchen: "", PrinterReceipt: "127.0.0.1:9"})
+
+	if _, err := queue.Submit(PrinterKitchen, JobKitchenTicket, "order1", []byte("x")); !errors.Is(err, ErrUnknownPrinter) {
+		t.Errorf("Submit to a printer without an address: got %v, want ErrUnknownPrinter", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	queue.Close(ctx)
+	if _, err := queue.Submit(PrinterReceipt, JobReceipt, "invoice1", []byte("x")); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Submit after Close: got %v, want ErrQueueClosed", err)
+	}
+}
@@ -0,0 +1,92 @@
+package printing
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/Micah-Shallom/modules/receipts"
+)
+
+// Ticket is what the kitchen needs to prepare an order: where it goes and how
+// many of each dish, without prices.
+type Ticket struct {
+	OrderID     string
+	TableNumber *int
+	Lines       []TicketLine
+	PrintedAt   time.Time
+}
+
+type TicketLine struct {
+	Name      string
+	Units     int
+	Modifiers []string
+	Notes     string
+}
+
+// TicketFromSummaries builds the kitchen ticket of an order from its lines as
+// assembled by OrderItemRepository.ItemsByOrder.
+func TicketFromSummaries(orderID string, summaries []models.OrderSummary, at time.Time) Ticket {
+	ticket := Ticket{OrderID: orderID, PrintedAt: at}
+	for _, summary := range summaries {
+		ticket.TableNumber = summary.TableNumber
+		for _, item := range summary.OrderItems {
+			line := TicketLine{Name: "Item", Units: item.Quantity}
+			if item.FoodName != nil {
+				line.Name = *item.FoodName
+			}
+			for _, modifier := range item.Modifiers {
+				line.Modifiers = append(line.Modifiers, modifier.OptionName)
+			}
+			if item.Notes != nil {
+				line.Notes = *item.Notes
+			}
+			ticket.Lines = append(ticket.Lines, line)
+		}
+	}
+	return ticket
+}
+
+// KitchenTicket encodes t for a kitchen printer. The table and the dishes
+// print in tall letters so they can be read from across the pass; modifiers
+// and notes go under their dish at normal size.
+func KitchenTicket(t Ticket) []byte {
+	rule := strings.Repeat("-", receipts.NarrowWidth)
+	table := "NO TABLE"
+	if t.TableNumber != nil {
+		table = fmt.Sprintf("TABLE %d", *t.TableNumber)
+	}
+
+	e := NewEncoder()
+	e.Align(AlignCenter).Bold(true).Size(2, 2).Line(table)
+	e.Size(1, 1).Bold(false).Line("Order " + t.OrderID)
+	e.Line(t.PrintedAt.Format("2006-01-02 15:04"))
+	e.Align(AlignLeft).Line(rule)
+	for _, line := range t.Lines {
+		e.Bold(true).Size(1, 2).Line(fmt.Sprintf("%3d x %s", line.Units, line.Name))
+		e.Bold(false).Size(1, 1)
+		for _, modifier := range line.Modifiers {
+			e.Line("      + " + modifier)
+		}
+		if line.Notes != "" {
+			e.Line("      NOTE: " + line.Notes)
+		}
+	}
+	e.Line(rule)
+	return e.Feed(3).Cut().Bytes()
+}
+
+// Receipt encodes the customer receipt laid out by receipts.Text in width
+// columns, with the restaurant name in bold tall letters.
+func Receipt(r receipts.Receipt, width int) []byte {
+	lines := strings.Split(strings.TrimSuffix(receipts.Text(r, width), "\n"), "\n")
+
+	e := NewEncoder()
+	e.Bold(true).Size(1, 2).Line(lines[0])
+	e.Bold(false).Size(1, 1)
+	for _, line := range lines[1:] {
+		e.Line(line)
+	}
+	return e.Feed(3).Cut().Bytes()
+}
@@ -0,0 +1,74 @@
+package receipts
+
+import (
+	"html/template"
+	"io"
+)
+
+var htmlReceipt = template.Must(template.New("receipt").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Receipt {{.Receipt.InvoiceNumber}}</title>
+<style>
+body { font-family: "Courier New", monospace; max-width: 28em; margin: 1em auto; }
+header, footer { text-align: center; }
+h1 { font-size: 1.2em; margin: 0; }
+table { width: 100%; border-collapse: collapse; }
+td { padding: 0.1em 0; vertical-align: top; }
+td.amount { text-align: right; white-space: nowrap; }
+tbody + tbody { border-top: 1px dashed; }
+.total td { font-weight: bold; }
+</style>
+</head>
+<body>
+{{with .Receipt}}<header>
+<h1>{{.Header.Name}}</h1>
+{{if .Header.Address}}<div>{{.Header.Address}}</div>{{end}}
+{{if .Header.Phone}}<div>Tel: {{.Header.Phone}}</div>{{end}}
+{{if .Header.TaxID}}<div>Tax ID: {{.Header.TaxID}}</div>{{end}}
+</header>
+<table>
+<tbody>
+<tr><td>Invoice</td><td class="amount">{{.InvoiceNumber}}</td></tr>
+<tr><td>Date</td><td class="amount">{{.IssuedAt.Format "2006-01-02 15:04"}}</td></tr>
+{{if .TableNumber}}<tr><td>Table</td><td class="amount">{{.TableNumber}}</td></tr>{{end}}
+</tbody>
+<tbody>
+{{range .Lines}}<tr><td>{{.Name}}{{range .Modifiers}}<br>&nbsp;&nbsp;+ {{.Name}}{{with .Delta}} ({{.}}){{end}}{{end}}<br>&nbsp;&nbsp;{{.Units}} x {{.UnitPrice}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}</tbody>
+<tbody>
+{{range $.Totals}}<tr{{if .Total}} class="total"{{end}}><td>{{.Label}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}</tbody>
+<tbody>
+{{range .Payments}}<tr><td>{{.Method}}{{if .Label}} ({{.Label}}){{end}}</td><td class="amount">{{.Amount}}</td></tr>
+{{if not .Refunded.IsZero}}<tr><td>&nbsp;&nbsp;refunded</td><td class="amount">-{{.Refunded}}</td></tr>{{end}}
+{{end}}<tr><td>Paid</td><td class="amount">{{.Paid}}</td></tr>
+{{if not .Refunded.IsZero}}<tr><td>Refunded</td><td class="amount">{{.Refunded}}</td></tr>{{end}}
+<tr><td>Balance</td><td class="amount">{{.Balance}}</td></tr>
+<tr><td>Status</td><td class="amount">{{.Status}}</td></tr>
+</tbody>
+</table>
+{{end}}<footer><p>Thank you!</p></footer>
+</body>
+</html>
+`))
+
+type htmlRow struct {
+	Label  string
+	Amount string
+	Total  bool
+}
+
+// HTML writes the receipt as a standalone HTML page.
+func HTML(w io.Writer, r Receipt) error {
+	rows := totalRows(r)
+	totals := make([]htmlRow, len(rows))
+	for i, row := range rows {
+		totals[i] = htmlRow{Label: row[0], Amount: row[1], Total: i == len(rows)-1}
+	}
+	return htmlReceipt.Execute(w, struct {
+		Receipt Receipt
+		Totals  []htmlRow
+	}{r, totals})
+}
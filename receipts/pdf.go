@@ -0,0 +1,78 @@
+package receipts
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PDF page layout, in points: the narrow text receipt set in 9pt Courier,
+// whose glyphs are 0.6em wide, on a single page as long as the receipt.
+const (
+	pdfFontSize = 9
+	pdfLeading  = 11
+	pdfMargin   = 14
+)
+
+// PDF writes the receipt as a one-page PDF document. It sets the narrow text
+// layout in the Courier font every PDF reader provides, so no font is
+// embedded.
+func PDF(w io.Writer, r Receipt) error {
+	lines := strings.Split(strings.TrimSuffix(Text(r, NarrowWidth), "\n"), "\n")
+	width := float64(NarrowWidth)*pdfFontSize*0.6 + 2*pdfMargin
+	height := float64(len(lines)*pdfLeading + 2*pdfMargin)
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %.1f Td\n", pdfFontSize, pdfLeading, pdfMargin, height-pdfMargin-pdfFontSize)
+	for _, line := range lines {
+		fmt.Fprintf(&content, "(%s) Tj T*\n", pdfString(line))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.1f %.1f] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>", width, height),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var doc bytes.Buffer
+	doc.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = doc.Len()
+		fmt.Fprintf(&doc, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(doc.Bytes())
+	return err
+}
+
+// pdfString escapes s for a PDF literal string in WinAnsiEncoding, which
+// matches Latin-1 for the characters it shares with it. Anything else, such
+// as the naira sign, is printed as a question mark.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
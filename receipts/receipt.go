@@ -0,0 +1,71 @@
+// Package receipts renders customer receipts for invoices as plain text for
+// thermal printers, HTML and PDF, without any external service.
+package receipts
+
+import (
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+)
+
+// Receipt is everything printed on a customer receipt, assembled from an
+// invoice, its order and its payments.
+type Receipt struct {
+	Header        Header
+	InvoiceNumber string
+	IssuedAt      time.Time
+	TableNumber   *int
+	Lines         []Line
+	// Totals is nil for orders stored before the server computed totals;
+	// only Total is printed for them.
+	Totals   *models.OrderTotals
+	Total    models.Money
+	Payments []Payment
+	Paid     models.Money
+	Refunded models.Money
+	Balance  models.Money
+	Status   string
+}
+
+// Header identifies the restaurant at the top of the receipt.
+type Header struct {
+	Name    string
+	Address string
+	Phone   string
+	TaxID   string
+}
+
+type Line struct {
+	Name      string
+	Units     int
+	UnitPrice models.Money
+	Amount    models.Money
+	// Modifiers are the options chosen with the item, already included in
+	// UnitPrice.
+	Modifiers []Modifier
+}
+
+type Modifier struct {
+	Name       string
+	PriceDelta models.Money
+}
+
+// Delta is the signed price delta of the modifier, e.g. +1.50, or empty when
+// the option is free.
+func (m Modifier) Delta() string {
+	switch {
+	case m.PriceDelta.IsZero():
+		return ""
+	case m.PriceDelta.IsNegative():
+		return m.PriceDelta.String()
+	}
+	return "+" + m.PriceDelta.String()
+}
+
+type Payment struct {
+	Method string
+	// Label names the split the payment was for, when the bill was split.
+	Label    string
+	Amount   models.Money
+	Refunded models.Money
+}
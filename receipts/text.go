@@ -0,0 +1,183 @@
+package receipts
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Widths of the thermal printers receipts are laid out for: 42 columns on
+// 80mm paper with the standard font, 80 for wide-carriage printers.
+const (
+	NarrowWidth = 42
+	WideWidth   = 80
+)
+
+// Text lays out the receipt in width columns of monospaced text.
+func Text(r Receipt, width int) string {
+	t := &textWriter{width: width}
+
+	t.center(strings.ToUpper(r.Header.Name))
+	t.center(r.Header.Address)
+	if r.Header.Phone != "" {
+		t.center("Tel: " + r.Header.Phone)
+	}
+	if r.Header.TaxID != "" {
+		t.center("Tax ID: " + r.Header.TaxID)
+	}
+	t.rule()
+	t.row("Invoice", r.InvoiceNumber)
+	t.row("Date", r.IssuedAt.Format("2006-01-02 15:04"))
+	if r.TableNumber != nil {
+		t.row("Table", fmt.Sprint(*r.TableNumber))
+	}
+	t.rule()
+
+	for _, line := range r.Lines {
+		quantity := fmt.Sprintf("%d x %s", line.Units, line.UnitPrice)
+		if single := line.Name + "  " + quantity; utf8.RuneCountInString(single)+2+utf8.RuneCountInString(line.Amount.String()) <= width {
+			t.row(single, line.Amount.String())
+		} else {
+			t.wrap(line.Name)
+			t.row("  "+quantity, line.Amount.String())
+		}
+		for _, modifier := range line.Modifiers {
+			if delta := modifier.Delta(); delta != "" {
+				t.row("  + "+modifier.Name, delta)
+			} else {
+				t.line("  + " + modifier.Name)
+			}
+		}
+	}
+	t.rule()
+
+	for _, row := range totalRows(r) {
+		t.row(row[0], row[1])
+	}
+	t.rule()
+
+	for _, payment := range r.Payments {
+		label := payment.Method
+		if payment.Label != "" {
+			label += " (" + payment.Label + ")"
+		}
+		t.row(label, payment.Amount.String())
+		if !payment.Refunded.IsZero() {
+			t.row("  refunded", "-"+payment.Refunded.String())
+		}
+	}
+	t.row("Paid", r.Paid.String())
+	if !r.Refunded.IsZero() {
+		t.row("Refunded", r.Refunded.String())
+	}
+	t.row("Balance", r.Balance.String())
+	t.row("Status", r.Status)
+	t.rule()
+	t.center("Thank you!")
+	return t.String()
+}
+
+// totalRows are the label and amount of every line of the totals block.
+func totalRows(r Receipt) [][2]string {
+	rows := [][2]string{}
+	if r.Totals != nil {
+		rows = append(rows, [2]string{"Subtotal", r.Totals.Subtotal.String()})
+		if !r.Totals.Discount.IsZero() {
+			rows = append(rows, [2]string{"Discount", "-" + r.Totals.Discount.String()})
+		}
+		if !r.Totals.ServiceCharge.IsZero() {
+			rows = append(rows, [2]string{"Service charge", r.Totals.ServiceCharge.String()})
+		}
+		for _, tax := range r.Totals.Taxes {
+			label := fmt.Sprintf("%s %s%%", tax.Name, percent(tax.Rate))
+			if tax.Inclusive {
+				label += " (incl.)"
+			}
+			rows = append(rows, [2]string{label, tax.Amount.String()})
+		}
+	}
+	rows = append(rows, [2]string{"TOTAL " + r.Total.Currency, r.Total.String()})
+	return rows
+}
+
+// percent formats a rate such as 0.075 as 7.5.
+func percent(rate float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.4f", rate*100), "0"), ".")
+}
+
+type textWriter struct {
+	strings.Builder
+	width int
+}
+
+func (t *textWriter) line(s string) {
+	t.WriteString(s)
+	t.WriteByte('\n')
+}
+
+func (t *textWriter) rule() {
+	t.line(strings.Repeat("-", t.width))
+}
+
+func (t *textWriter) center(s string) {
+	for _, part := range wrap(s, t.width) {
+		pad := (t.width - utf8.RuneCountInString(part)) / 2
+		t.line(strings.Repeat(" ", pad) + part)
+	}
+}
+
+func (t *textWriter) wrap(s string) {
+	for _, part := range wrap(s, t.width) {
+		t.line(part)
+	}
+}
+
+// row puts left and right on the same line, wrapping left onto lines of its
+// own when both do not fit.
+func (t *textWriter) row(left, right string) {
+	trimmed := strings.TrimLeft(left, " ")
+	indent := left[:len(left)-len(trimmed)]
+	room := t.width - utf8.RuneCountInString(right) - 1 - len(indent)
+	parts := wrap(trimmed, room)
+	if len(parts) == 0 {
+		parts = []string{""}
+	}
+	for _, part := range parts[:len(parts)-1] {
+		t.line(indent + part)
+	}
+	last := indent + parts[len(parts)-1]
+	t.line(last + strings.Repeat(" ", t.width-utf8.RuneCountInString(last)-utf8.RuneCountInString(right)) + right)
+}
+
+// wrap breaks s into lines of at most width runes, at spaces where it can.
+func wrap(s string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(s) {
+		for utf8.RuneCountInString(word) > width {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:width]))
+			word = string(runes[width:])
+		}
+		switch {
+		case current == "":
+			current = word
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= width:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AreaRepository interface {
+	List(ctx context.Context) ([]models.Area, error)
+	FindByID(ctx context.Context, areaID string) (models.Area, error)
+	Create(ctx context.Context, area *models.Area) error
+	Update(ctx context.Context, area *models.Area) error
+}
+
+type mongoAreaRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoAreaRepository) List(ctx context.Context) ([]models.Area, error) {
+	areas := []models.Area{}
+	err := mongoFindAll(ctx, r.collection, bson.M{}, &areas)
+	return areas, err
+}
+
+func (r *mongoAreaRepository) FindByID(ctx context.Context, areaID string) (area models.Area, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"area_id": areaID}, &area)
+	return area, err
+}
+
+func (r *mongoAreaRepository) Create(ctx context.Context, area *models.Area) error {
+	_, err := r.collection.InsertOne(ctx, area)
+	return mongoError(err)
+}
+
+func (r *mongoAreaRepository) Update(ctx context.Context, area *models.Area) error {
+	return mongoReplace(ctx, r.collection, bson.M{"area_id": area.AreaID}, area)
+}
+
+type memoryAreaRepository struct {
+	store *memoryStore
+	areas *memoryCollection
+}
+
+func (r *memoryAreaRepository) List(ctx context.Context) (areas []models.Area, err error) {
+	err = r.store.view(ctx, func() error {
+		areas, err = memoryFilter[models.Area](r.areas, nil)
+		return err
+	})
+	return areas, err
+}
+
+func (r *memoryAreaRepository) FindByID(ctx context.Context, areaID string) (area models.Area, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.areas.find(areaID, &area)
+	})
+	return area, err
+}
+
+func (r *memoryAreaRepository) Create(ctx context.Context, area *models.Area) error {
+	return r.store.update(ctx, func() error {
+		return r.areas.insert(area.AreaID, area)
+	})
+}
+
+func (r *memoryAreaRepository) Update(ctx context.Context, area *models.Area) error {
+	return r.store.update(ctx, func() error {
+		return r.areas.replace(area.AreaID, area)
+	})
+}
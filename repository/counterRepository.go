@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CounterRepository interface {
+	// Next increments the named counter, creating it at zero first, and
+	// returns its new value. Called inside a transaction, the increment is
+	// undone when the transaction fails, so the sequence has no gaps.
+	Next(ctx context.Context, name string) (int64, error)
+}
+
+type mongoCounterRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoCounterRepository) Next(ctx context.Context, name string) (int64, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var counter models.Counter
+	for attempt := 0; ; attempt++ {
+		err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": name}, bson.M{"$inc": bson.M{"seq": 1}}, opts).Decode(&counter)
+		// two upserts creating the same counter race on its _id; the loser
+		// retries against the counter the winner created
+		if mongo.IsDuplicateKeyError(err) && attempt == 0 {
+			continue
+		}
+		return counter.Seq, mongoError(err)
+	}
+}
+
+type memoryCounterRepository struct {
+	store    *memoryStore
+	counters *memoryCollection
+}
+
+func (r *memoryCounterRepository) Next(ctx context.Context, name string) (seq int64, err error) {
+	err = r.store.update(ctx, func() error {
+		counter := models.Counter{Name: name}
+		err := r.counters.find(name, &counter)
+		if errors.Is(err, ErrNotFound) {
+			if err := r.counters.insert(name, &counter); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+		counter.Seq++
+		seq = counter.Seq
+		return r.counters.replace(name, &counter)
+	})
+	return seq, err
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CreditNoteRepository interface {
+	List(ctx context.Context) ([]models.CreditNote, error)
+	ListByInvoice(ctx context.Context, invoiceID string) ([]models.CreditNote, error)
+	FindByID(ctx context.Context, creditNoteID string) (models.CreditNote, error)
+	Create(ctx context.Context, creditNote *models.CreditNote) error
+}
+
+type mongoCreditNoteRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoCreditNoteRepository) List(ctx context.Context) ([]models.CreditNote, error) {
+	creditNotes := []models.CreditNote{}
+	err := mongoFindAll(ctx, r.collection, bson.M{}, &creditNotes)
+	return creditNotes, err
+}
+
+func (r *mongoCreditNoteRepository) ListByInvoice(ctx context.Context, invoiceID string) ([]models.CreditNote, error) {
+	creditNotes := []models.CreditNote{}
+	err := mongoFindAll(ctx, r.collection, bson.M{"invoice_id": invoiceID}, &creditNotes)
+	return creditNotes, err
+}
+
+func (r *mongoCreditNoteRepository) FindByID(ctx context.Context, creditNoteID string) (creditNote models.CreditNote, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"credit_note_id": creditNoteID}, &creditNote)
+	return creditNote, err
+}
+
+func (r *mongoCreditNoteRepository) Create(ctx context.Context, creditNote *models.CreditNote) error {
+	_, err := r.collection.InsertOne(ctx, creditNote)
+	return mongoError(err)
+}
+
+type memoryCreditNoteRepository struct {
+	store       *memoryStore
+	creditNotes *memoryCollection
+}
+
+func (r *memoryCreditNoteRepository) List(ctx context.Context) (creditNotes []models.CreditNote, err error) {
+	err = r.store.view(ctx, func() error {
+		creditNotes, err = memoryFilter[models.CreditNote](r.creditNotes, nil)
+		return err
+	})
+	return creditNotes, err
+}
+
+func (r *memoryCreditNoteRepository) ListByInvoice(ctx context.Context, invoiceID string) (creditNotes []models.CreditNote, err error) {
+	err = r.store.view(ctx, func() error {
+		creditNotes, err = memoryFilter(r.creditNotes, func(creditNote models.CreditNote) bool {
+			return creditNote.InvoiceID == invoiceID
+		})
+		return err
+	})
+	return creditNotes, err
+}
+
+func (r *memoryCreditNoteRepository) FindByID(ctx context.Context, creditNoteID string) (creditNote models.CreditNote, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.creditNotes.find(creditNoteID, &creditNote)
+	})
+	return creditNote, err
+}
+
+func (r *memoryCreditNoteRepository) Create(ctx context.Context, creditNote *models.CreditNote) error {
+	return r.store.update(ctx, func() error {
+		return r.creditNotes.insert(creditNote.CreditNoteID, creditNote)
+	})
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type FoodRepository interface {
+	// List pages through the foods of the menus in menuIDs, along with the
+	// foods on no menu when withoutMenu is set, or every food when menuIDs is
+	// nil.
+	List(ctx context.Context, menuIDs []string, withoutMenu bool, startIndex, limit int) ([]models.Food, int64, error)
+	FindByID(ctx context.Context, foodID string) (models.Food, error)
+	Create(ctx context.Context, food *models.Food) error
+	Update(ctx context.Context, food *models.Food) error
+}
+
+type mongoFoodRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoFoodRepository) List(ctx context.Context, menuIDs []string, withoutMenu bool, startIndex, limit int) ([]models.Food, int64, error) {
+	foods := []models.Food{}
+	filter := bson.M{}
+	switch {
+	case menuIDs != nil && withoutMenu:
+		filter["$or"] = bson.A{bson.M{"menu_id": bson.M{"$in": menuIDs}}, bson.M{"menu_id": nil}}
+	case menuIDs != nil:
+		filter["menu_id"] = bson.M{"$in": menuIDs}
+	}
+	total, err := mongoFindPage(ctx, r.collection, filter, startIndex, limit, &foods)
+	return foods, total, err
+}
+
+func (r *mongoFoodRepository) FindByID(ctx context.Context, foodID string) (food models.Food, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"food_id": foodID}, &food)
+	return food, err
+}
+
+func (r *mongoFoodRepository) Create(ctx context.Context, food *models.Food) error {
+	_, err := r.collection.InsertOne(ctx, food)
+	return mongoError(err)
+}
+
+func (r *mongoFoodRepository) Update(ctx context.Context, food *models.Food) error {
+	return mongoReplace(ctx, r.collection, bson.M{"food_id": food.FoodID}, food)
+}
+
+type memoryFoodRepository struct {
+	store *memoryStore
+	foods *memoryCollection
+}
+
+func (r *memoryFoodRepository) List(ctx context.Context, menuIDs []string, withoutMenu bool, startIndex, limit int) (foods []models.Food, total int64, err error) {
+	err = r.store.view(ctx, func() error {
+		all, err := memoryFilter(r.foods, func(food models.Food) bool {
+			if menuIDs == nil {
+				return true
+			}
+			if food.MenuID == nil {
+				return withoutMenu
+			}
+			return slices.Contains(menuIDs, *food.MenuID)
+		})
+		total, foods = int64(len(all)), memoryPage(all, startIndex, limit)
+		return err
+	})
+	return foods, total, err
+}
+
+func (r *memoryFoodRepository) FindByID(ctx context.Context, foodID string) (food models.Food, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.foods.find(foodID, &food)
+	})
+	return food, err
+}
+
+func (r *memoryFoodRepository) Create(ctx context.Context, food *models.Food) error {
+	return r.store.update(ctx, func() error {
+		return r.foods.insert(food.FoodID, food)
+	})
+}
+
+func (r *memoryFoodRepository) Update(ctx context.Context, food *models.Food) error {
+	return r.store.update(ctx, func() error {
+		return r.foods.replace(food.FoodID, food)
+	})
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type InvoiceRepository interface {
+	List(ctx context.Context) ([]models.Invoice, error)
+	ListByOrder(ctx context.Context, orderID string) ([]models.Invoice, error)
+	FindByID(ctx context.Context, invoiceID string) (models.Invoice, error)
+	Create(ctx context.Context, invoice *models.Invoice) error
+	Update(ctx context.Context, invoice *models.Invoice) error
+}
+
+type mongoInvoiceRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoInvoiceRepository) List(ctx context.Context) ([]models.Invoice, error) {
+	invoices := []models.Invoice{}
+	err := mongoFindAll(ctx, r.collection, bson.M{}, &invoices)
+	return invoices, err
+}
+
+func (r *mongoInvoiceRepository) ListByOrder(ctx context.Context, orderID string) ([]models.Invoice, error) {
+	invoices := []models.Invoice{}
+	err := mongoFindAll(ctx, r.collection, bson.M{"order_id": orderID}, &invoices)
+	return invoices, err
+}
+
+func (r *mongoInvoiceRepository) FindByID(ctx context.Context, invoiceID string) (invoice models.Invoice, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"invoice_id": invoiceID}, &invoice)
+	return invoice, err
+}
+
+func (r *mongoInvoiceRepository) Create(ctx context.Context, invoice *models.Invoice) error {
+	_, err := r.collection.InsertOne(ctx, invoice)
+	return mongoError(err)
+}
+
+func (r *mongoInvoiceRepository) Update(ctx context.Context, invoice *models.Invoice) error {
+	return mongoReplace(ctx, r.collection, bson.M{"invoice_id": invoice.InvoiceID}, invoice)
+}
+
+type memoryInvoiceRepository struct {
+	store    *memoryStore
+	invoices *memoryCollection
+}
+
+func (r *memoryInvoiceRepository) List(ctx context.Context) (invoices []models.Invoice, err error) {
+	err = r.store.view(ctx, func() error {
+		invoices, err = memoryFilter[models.Invoice](r.invoices, nil)
+		return err
+	})
+	return invoices, err
+}
+
+func (r *memoryInvoiceRepository) ListByOrder(ctx context.Context, orderID string) (invoices []models.Invoice, err error) {
+	err = r.store.view(ctx, func() error {
+		invoices, err = memoryFilter(r.invoices, func(invoice models.Invoice) bool {
+			return invoice.OrderID == orderID
+		})
+		return err
+	})
+	return invoices, err
+}
+
+func (r *memoryInvoiceRepository) FindByID(ctx context.Context, invoiceID string) (invoice models.Invoice, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.invoices.find(invoiceID, &invoice)
+	})
+	return invoice, err
+}
+
+func (r *memoryInvoiceRepository) Create(ctx context.Context, invoice *models.Invoice) error {
+	return r.store.update(ctx, func() error {
+		return r.invoices.insert(invoice.InvoiceID, invoice)
+	})
+}
+
+func (r *memoryInvoiceRepository) Update(ctx context.Context, invoice *models.Invoice) error {
+	return r.store.update(ctx, func() error {
+		return r.invoices.replace(invoice.InvoiceID, invoice)
+	})
+}
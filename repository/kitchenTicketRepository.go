@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type KitchenTicketRepository interface {
+	// Queue lists the NEW tickets of a station, oldest first.
+	Queue(ctx context.Context, stationID string) ([]models.KitchenTicket, error)
+	// RecentlyBumped lists up to limit BUMPED tickets of a station, the last
+	// bumped first.
+	RecentlyBumped(ctx context.Context, stationID string, limit int) ([]models.KitchenTicket, error)
+	ListByOrder(ctx context.Context, orderID string) ([]models.KitchenTicket, error)
+	FindByID(ctx context.Context, ticketID string) (models.KitchenTicket, error)
+	CreateMany(ctx context.Context, tickets []models.KitchenTicket) error
+	Update(ctx context.Context, ticket *models.KitchenTicket) error
+}
+
+type mongoKitchenTicketRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoKitchenTicketRepository) Queue(ctx context.Context, stationID string) ([]models.KitchenTicket, error) {
+	tickets := []models.KitchenTicket{}
+	err := mongoFindAll(ctx, r.collection, bson.M{"station_id": stationID, "status": models.TicketStatusNew}, &tickets)
+	return tickets, err
+}
+
+func (r *mongoKitchenTicketRepository) RecentlyBumped(ctx context.Context, stationID string, limit int) ([]models.KitchenTicket, error) {
+	tickets := []models.KitchenTicket{}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "bumped_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, bson.M{"station_id": stationID, "status": models.TicketStatusBumped}, opts)
+	if err != nil {
+		return tickets, err
+	}
+	return tickets, cursor.All(ctx, &tickets)
+}
+
+func (r *mongoKitchenTicketRepository) ListByOrder(ctx context.Context, orderID string) ([]models.KitchenTicket, error) {
+	tickets := []models.KitchenTicket{}
+	err := mongoFindAll(ctx, r.collection, bson.M{"order_id": orderID}, &tickets)
+	return tickets, err
+}
+
+func (r *mongoKitchenTicketRepository) FindByID(ctx context.Context, ticketID string) (ticket models.KitchenTicket, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"ticket_id": ticketID}, &ticket)
+	return ticket, err
+}
+
+func (r *mongoKitchenTicketRepository) CreateMany(ctx context.Context, tickets []models.KitchenTicket) error {
+	if len(tickets) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(tickets))
+	for i := range tickets {
+		docs = append(docs, tickets[i])
+	}
+	_, err := r.collection.InsertMany(ctx, docs)
+	return mongoError(err)
+}
+
+func (r *mongoKitchenTicketRepository) Update(ctx context.Context, ticket *models.KitchenTicket) error {
+	return mongoReplace(ctx, r.collection, bson.M{"ticket_id": ticket.TicketID}, ticket)
+}
+
+type memoryKitchenTicketRepository struct {
+	store   *memoryStore
+	tickets *memoryCollection
+}
+
+func (r *memoryKitchenTicketRepository) Queue(ctx context.Context, stationID string) (tickets []models.KitchenTicket, err error) {
+	err = r.store.view(ctx, func() error {
+		tickets, err = memoryFilter(r.tickets, func(ticket models.KitchenTicket) bool {
+			return ticket.StationID == stationID && ticket.Status == models.TicketStatusNew
+		})
+		return err
+	})
+	return tickets, err
+}
+
+func (r *memoryKitchenTicketRepository) RecentlyBumped(ctx context.Context, stationID string, limit int) (tickets []models.KitchenTicket, err error) {
+	err = r.store.view(ctx, func() error {
+		tickets, err = memoryFilter(r.tickets, func(ticket models.KitchenTicket) bool {
+			return ticket.StationID == stationID && ticket.Status == models.TicketStatusBumped
+		})
+		return err
+	})
+	if err != nil {
+		return tickets, err
+	}
+	// reversed first so that tickets bumped in the same second keep the
+	// newest-first order of the mongo backend
+	for i, j := 0, len(tickets)-1; i < j; i, j = i+1, j-1 {
+		tickets[i], tickets[j] = tickets[j], tickets[i]
+	}
+	sort.SliceStable(tickets, func(i, j int) bool {
+		return tickets[i].BumpedAt.After(*tickets[j].BumpedAt)
+	})
+	return memoryPage(tickets, 0, limit), nil
+}
+
+func (r *memoryKitchenTicketRepository) ListByOrder(ctx context.Context, orderID string) (tickets []models.KitchenTicket, err error) {
+	err = r.store.view(ctx, func() error {
+		tickets, err = memoryFilter(r.tickets, func(ticket models.KitchenTicket) bool {
+			return ticket.OrderID == orderID
+		})
+		return err
+	})
+	return tickets, err
+}
+
+func (r *memoryKitchenTicketRepository) FindByID(ctx context.Context, ticketID string) (ticket models.KitchenTicket, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.tickets.find(ticketID, &ticket)
+	})
+	return ticket, err
+}
+
+func (r *memoryKitchenTicketRepository) CreateMany(ctx context.Context, tickets []models.KitchenTicket) error {
+	return r.store.update(ctx, func() error {
+		for i := range tickets {
+			if err := r.tickets.insert(tickets[i].TicketID, &tickets[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryKitchenTicketRepository) Update(ctx context.Context, ticket *models.KitchenTicket) error {
+	return r.store.update(ctx, func() error {
+		return r.tickets.replace(ticket.TicketID, ticket)
+	})
+}
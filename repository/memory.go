@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// memoryStore keeps every collection as marshalled BSON documents so that the
+// in-memory backend round-trips values exactly the way MongoDB would (time
+// truncation, pointer copies, omitted fields) and callers never share memory
+// with the store.
+type memoryStore struct {
+	mu          sync.RWMutex
+	collections []*memoryCollection
+}
+
+type memoryCollection struct {
+	keys []string
+	docs map[string]bson.Raw
+}
+
+func NewMemoryRepositories() *Repositories {
+	store := &memoryStore{}
+	foods := store.newCollection()
+	menus := store.newCollection()
+	orders := store.newCollection()
+	orderItems := store.newCollection()
+	invoices := store.newCollection()
+	tables := store.newCollection()
+	users := store.newCollection()
+	sessions := store.newCollection()
+	taxRules := store.newCollection()
+	payments := store.newCollection()
+	idempotencyKeys := store.newCollection()
+	creditNotes := store.newCollection()
+	counters := store.newCollection()
+	stations := store.newCollection()
+	tickets := store.newCollection()
+	reservations := store.newCollection()
+	areas := store.newCollection()
+	sections := store.newCollection()
+	shifts := store.newCollection()
+	waitlist := store.newCollection()
+
+	return &Repositories{
+		Foods:        &memoryFoodRepository{store: store, foods: foods},
+		Menus:        &memoryMenuRepository{store: store, menus: menus},
+		Orders:       &memoryOrderRepository{store: store, orders: orders},
+		OrderItems:   &memoryOrderItemRepository{store: store, orderItems: orderItems, foods: foods, orders: orders, tables: tables},
+		Invoices:     &memoryInvoiceRepository{store: store, invoices: invoices},
+		Tables:       &memoryTableRepository{store: store, tables: tables},
+		Users:        &memoryUserRepository{store: store, users: users},
+		Sessions:     &memorySessionRepository{store: store, sessions: sessions},
+		TaxRules:     &memoryTaxRuleRepository{store: store, taxRules: taxRules},
+		Payments:     &memoryPaymentRepository{store: store, payments: payments, keys: idempotencyKeys},
+		CreditNotes:  &memoryCreditNoteRepository{store: store, creditNotes: creditNotes},
+		Counters:     &memoryCounterRepository{store: store, counters: counters},
+		Stations:     &memoryStationRepository{store: store, stations: stations},
+		Tickets:      &memoryKitchenTicketRepository{store: store, tickets: tickets},
+		Reservations: &memoryReservationRepository{store: store, reservations: reservations},
+		Areas:        &memoryAreaRepository{store: store, areas: areas},
+		Sections:     &memorySectionRepository{store: store, sections: sections},
+		Shifts:       &memoryShiftRepository{store: store, shifts: shifts},
+		Waitlist:     &memoryWaitlistRepository{store: store, entries: waitlist},
+
+		Transactions: store,
+	}
+}
+
+func (s *memoryStore) newCollection() *memoryCollection {
+	collection := &memoryCollection{docs: map[string]bson.Raw{}}
+	s.collections = append(s.collections, collection)
+	return collection
+}
+
+// memoryTxKey marks a context running inside a transaction of the store it
+// holds. The transaction already owns the write lock, so repository calls made
+// with that context must not take it again.
+type memoryTxKey struct{}
+
+func (s *memoryStore) inTransaction(ctx context.Context) bool {
+	store, _ := ctx.Value(memoryTxKey{}).(*memoryStore)
+	return store == s
+}
+
+// WithTransaction holds the write lock for the whole of fn, so no other
+// request observes its partial writes, and restores every collection when fn
+// fails. Nested calls join the outer transaction.
+func (s *memoryStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.inTransaction(ctx) {
+		return fn(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.snapshot()
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, s)); err != nil {
+		s.restore(snapshot)
+		return err
+	}
+	return nil
+}
+
+// snapshot copies the document index of every collection. Documents are
+// immutable marshalled bytes that writes replace rather than modify, so
+// sharing them with the snapshot is safe.
+func (s *memoryStore) snapshot() []memoryCollection {
+	snapshot := make([]memoryCollection, len(s.collections))
+	for i, c := range s.collections {
+		docs := make(map[string]bson.Raw, len(c.docs))
+		for key, raw := range c.docs {
+			docs[key] = raw
+		}
+		snapshot[i] = memoryCollection{keys: append([]string(nil), c.keys...), docs: docs}
+	}
+	return snapshot
+}
+
+func (s *memoryStore) restore(snapshot []memoryCollection) {
+	for i, c := range s.collections {
+		c.keys, c.docs = snapshot[i].keys, snapshot[i].docs
+	}
+}
+
+func (s *memoryStore) view(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.inTransaction(ctx) {
+		return fn()
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn()
+}
+
+func (s *memoryStore) update(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.inTransaction(ctx) {
+		return fn()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn()
+}
+
+func (c *memoryCollection) insert(key string, doc interface{}) error {
+	if _, ok := c.docs[key]; ok {
+		return ErrDuplicate
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	c.keys = append(c.keys, key)
+	c.docs[key] = raw
+	return nil
+}
+
+func (c *memoryCollection) replace(key string, doc interface{}) error {
+	if _, ok := c.docs[key]; !ok {
+		return ErrNotFound
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	c.docs[key] = raw
+	return nil
+}
+
+func (c *memoryCollection) find(key string, out interface{}) error {
+	raw, ok := c.docs[key]
+	if !ok {
+		return ErrNotFound
+	}
+	return bson.Unmarshal(raw, out)
+}
+
+// memoryFilter decodes every document of the collection in insertion order and
+// keeps the ones accepted by match. A nil match keeps everything.
+func memoryFilter[T any](c *memoryCollection, match func(T) bool) ([]T, error) {
+	items := []T{}
+	for _, key := range c.keys {
+		var item T
+		if err := bson.Unmarshal(c.docs[key], &item); err != nil {
+			return nil, err
+		}
+		if match == nil || match(item) {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func memoryPage[T any](items []T, startIndex, limit int) []T {
+	if startIndex < 0 {
+		startIndex = 0
+	}
+	if startIndex >= len(items) {
+		return []T{}
+	}
+	end := len(items)
+	if limit > 0 && startIndex+limit < end {
+		end = startIndex + limit
+	}
+	return items[startIndex:end]
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+)
+
+func newTestFood(foodID, name string) *models.Food {
+	return &models.Food{FoodID: foodID, Name: &name}
+}
+
+func TestMemoryFindAndReplace(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+
+	food := newTestFood("burger", "Burger")
+	food.CreatedAt = time.Date(2026, 10, 18, 12, 30, 0, 123456789, time.UTC)
+	if err := repos.Foods.Create(ctx, food); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repos.Foods.Create(ctx, food); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("second Create: got %v, want ErrDuplicate", err)
+	}
+
+	found, err := repos.Foods.FindByID(ctx, "burger")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if *found.Name != "Burger" {
+		t.Errorf("name: got %q, want Burger", *found.Name)
+	}
+	// documents go through BSON, which keeps times to the millisecond
+	if want := food.CreatedAt.Truncate(time.Millisecond); !found.CreatedAt.Equal(want) {
+		t.Errorf("created_at: got %v, want %v", found.CreatedAt, want)
+	}
+
+	// the store keeps its own copy of what it was given and hands out copies
+	*food.Name = "Changed by the caller"
+	*found.Name = "Changed by the reader"
+	again, err := repos.Foods.FindByID(ctx, "burger")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if *again.Name != "Burger" {
+		t.Errorf("name after changing copies: got %q, want Burger", *again.Name)
+	}
+
+	*again.Name = "Cheeseburger"
+	if err := repos.Foods.Update(ctx, &again); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	replaced, err := repos.Foods.FindByID(ctx, "burger")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if *replaced.Name != "Cheeseburger" {
+		t.Errorf("name after Update: got %q, want Cheeseburger", *replaced.Name)
+	}
+
+	if _, err := repos.Foods.FindByID(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindByID of a missing food: got %v, want ErrNotFound", err)
+	}
+	if err := repos.Foods.Update(ctx, newTestFood("missing", "Missing")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update of a missing food: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryPagination(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	for i := 0; i < 5; i++ {
+		if err := repos.Foods.Create(ctx, newTestFood(fmt.Sprint("food", i), fmt.Sprint("Food ", i))); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	tests := []struct {
+		startIndex, limit int
+		want              []string
+	}{
+		{0, 2, []string{"food0", "food1"}},
+		{2, 2, []string{"food2", "food3"}},
+		{4, 2, []string{"food4"}},
+		{5, 2, []string{}},
+		{-1, 1, []string{"food0"}},
+	}
+	for _, tt := range tests {
+		foods, total, err := repos.Foods.List(ctx, nil, false, tt.startIndex, tt.limit)
+		if err != nil {
+			t.Fatalf("List(%d, %d): %v", tt.startIndex, tt.limit, err)
+		}
+		if total != 5 {
+			t.Errorf("List(%d, %d): total %d, want 5", tt.startIndex, tt.limit, total)
+		}
+		got := []string{}
+		for _, food := range foods {
+			got = append(got, food.FoodID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("List(%d, %d): got %v, want %v", tt.startIndex, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryTransactionRollback(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	if err := repos.Foods.Create(ctx, newTestFood("burger", "Burger")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	failed := errors.New("failed")
+	err := repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := repos.Foods.Update(ctx, newTestFood("burger", "Cheeseburger")); err != nil {
+			return err
+		}
+		if err := repos.Foods.Create(ctx, newTestFood("fries", "Fries")); err != nil {
+			return err
+		}
+		// a nested transaction joins the outer one and is undone with it
+		if err := repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+			return repos.Menus.Create(ctx, &models.Menu{MenuID: "lunch", Name: "Lunch"})
+		}); err != nil {
+			return err
+		}
+		// the transaction sees its own writes
+		if _, err := repos.Foods.FindByID(ctx, "fries"); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("WithTransaction: got %v, want the error of fn", err)
+	}
+
+	burger, err := repos.Foods.FindByID(ctx, "burger")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if *burger.Name != "Burger" {
+		t.Errorf("name after rollback: got %q, want Burger", *burger.Name)
+	}
+	if _, err := repos.Foods.FindByID(ctx, "fries"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("food created in a failed transaction: got %v, want ErrNotFound", err)
+	}
+	if _, err := repos.Menus.FindByID(ctx, "lunch"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("menu created in a failed nested transaction: got %v, want ErrNotFound", err)
+	}
+	if foods, total, _ := repos.Foods.List(ctx, nil, false, 0, 10); total != 1 || len(foods) != 1 {
+		t.Errorf("foods after rollback: got %d, want 1", total)
+	}
+
+	err = repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		return repos.Foods.Create(ctx, newTestFood("fries", "Fries"))
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction: %v", err)
+	}
+	if _, err := repos.Foods.FindByID(ctx, "fries"); err != nil {
+		t.Errorf("food created in a committed transaction: %v", err)
+	}
+}
+
+func TestMemoryCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	repos := NewMemoryRepositories()
+
+	if _, err := repos.Foods.FindByID(ctx, "burger"); !errors.Is(err, context.Canceled) {
+		t.Errorf("FindByID: got %v, want context.Canceled", err)
+	}
+	if err := repos.Foods.Create(ctx, newTestFood("burger", "Burger")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Create: got %v, want context.Canceled", err)
+	}
+	called := false
+	err := repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || called {
+		t.Errorf("WithTransaction: got %v and called %v, want context.Canceled without calling fn", err, called)
+	}
+}
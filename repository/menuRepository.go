@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MenuRepository interface {
+	List(ctx context.Context) ([]models.Menu, error)
+	FindByID(ctx context.Context, menuID string) (models.Menu, error)
+	Create(ctx context.Context, menu *models.Menu) error
+	Update(ctx context.Context, menu *models.Menu) error
+}
+
+type mongoMenuRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoMenuRepository) List(ctx context.Context) ([]models.Menu, error) {
+	menus := []models.Menu{}
+	err := mongoFindAll(ctx, r.collection, bson.M{}, &menus)
+	return menus, err
+}
+
+func (r *mongoMenuRepository) FindByID(ctx context.Context, menuID string) (menu models.Menu, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"menu_id": menuID}, &menu)
+	return menu, err
+}
+
+func (r *mongoMenuRepository) Create(ctx context.Context, menu *models.Menu) error {
+	_, err := r.collection.InsertOne(ctx, menu)
+	return mongoError(err)
+}
+
+func (r *mongoMenuRepository) Update(ctx context.Context, menu *models.Menu) error {
+	return mongoReplace(ctx, r.collection, bson.M{"menu_id": menu.MenuID}, menu)
+}
+
+type memoryMenuRepository struct {
+	store *memoryStore
+	menus *memoryCollection
+}
+
+func (r *memoryMenuRepository) List(ctx context.Context) (menus []models.Menu, err error) {
+	err = r.store.view(ctx, func() error {
+		menus, err = memoryFilter[models.Menu](r.menus, nil)
+		return err
+	})
+	return menus, err
+}
+
+func (r *memoryMenuRepository) FindByID(ctx context.Context, menuID string) (menu models.Menu, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.menus.find(menuID, &menu)
+	})
+	return menu, err
+}
+
+func (r *memoryMenuRepository) Create(ctx context.Context, menu *models.Menu) error {
+	return r.store.update(ctx, func() error {
+		return r.menus.insert(menu.MenuID, menu)
+	})
+}
+
+func (r *memoryMenuRepository) Update(ctx context.Context, menu *models.Menu) error {
+	return r.store.update(ctx, func() error {
+		return r.menus.replace(menu.MenuID, menu)
+	})
+}
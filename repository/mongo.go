@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func NewMongoRepositories(db *mongo.Database) *Repositories {
+	return &Repositories{
+		Foods:        &mongoFoodRepository{collection: db.Collection("food")},
+		Menus:        &mongoMenuRepository{collection: db.Collection("menu")},
+		Orders:       &mongoOrderRepository{collection: db.Collection("order")},
+		OrderItems:   &mongoOrderItemRepository{collection: db.Collection("orderItem")},
+		Invoices:     &mongoInvoiceRepository{collection: db.Collection("invoice")},
+		Tables:       &mongoTableRepository{collection: db.Collection("table")},
+		Users:        &mongoUserRepository{collection: db.Collection("user")},
+		Sessions:     &mongoSessionRepository{collection: db.Collection("session")},
+		TaxRules:     &mongoTaxRuleRepository{collection: db.Collection("taxRule")},
+		Payments:     &mongoPaymentRepository{collection: db.Collection("payment"), keys: db.Collection("idempotencyKey")},
+		CreditNotes:  &mongoCreditNoteRepository{collection: db.Collection("creditNote")},
+		Counters:     &mongoCounterRepository{collection: db.Collection("counter")},
+		Stations:     &mongoStationRepository{collection: db.Collection("station")},
+		Tickets:      &mongoKitchenTicketRepository{collection: db.Collection("kitchenTicket")},
+		Reservations: &mongoReservationRepository{collection: db.Collection("reservation")},
+		Areas:        &mongoAreaRepository{collection: db.Collection("area")},
+		Sections:     &mongoSectionRepository{collection: db.Collection("section")},
+		Shifts:       &mongoShiftRepository{collection: db.Collection("shift")},
+		Waitlist:     &mongoWaitlistRepository{collection: db.Collection("waitlistEntry")},
+
+		Transactions: &mongoTransactor{client: db.Client()},
+	}
+}
+
+// mongoTransactor runs multi-document transactions, which MongoDB only
+// supports on replica sets and sharded clusters.
+type mongoTransactor struct {
+	client *mongo.Client
+}
+
+func (t *mongoTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+func mongoError(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func mongoFindOne(ctx context.Context, collection *mongo.Collection, filter interface{}, out interface{}) error {
+	return mongoError(collection.FindOne(ctx, filter).Decode(out))
+}
+
+func mongoFindAll(ctx context.Context, collection *mongo.Collection, filter interface{}, out interface{}) error {
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, out)
+}
+
+func mongoFindPage(ctx context.Context, collection *mongo.Collection, filter interface{}, startIndex, limit int, out interface{}) (int64, error) {
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetSkip(int64(startIndex)).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+	return total, cursor.All(ctx, out)
+}
+
+func mongoReplace(ctx context.Context, collection *mongo.Collection, filter interface{}, doc interface{}) error {
+	result, err := collection.ReplaceOne(ctx, filter, doc)
+	if err != nil {
+		return mongoError(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type OrderItemRepository interface {
+	List(ctx context.Context) ([]models.OrderItem, error)
+	ListByOrder(ctx context.Context, orderID string) ([]models.OrderItem, error)
+	// ListReadyBetween lists the items that became ready in [from, to).
+	ListReadyBetween(ctx context.Context, from, to time.Time) ([]models.OrderItem, error)
+	FindByID(ctx context.Context, orderItemID string) (models.OrderItem, error)
+	CreateMany(ctx context.Context, orderItems []models.OrderItem) error
+	Update(ctx context.Context, orderItem *models.OrderItem) error
+	// ItemsByOrder joins the items of an order with their food, order and
+	// table and groups them into a single invoice-ready summary.
+	ItemsByOrder(ctx context.Context, orderID string) ([]models.OrderSummary, error)
+}
+
+type mongoOrderItemRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoOrderItemRepository) List(ctx context.Context) ([]models.OrderItem, error) {
+	orderItems := []models.OrderItem{}
+	err := mongoFindAll(ctx, r.collection, bson.M{}, &orderItems)
+	return orderItems, err
+}
+
+func (r *mongoOrderItemRepository) ListByOrder(ctx context.Context, orderID string) ([]models.OrderItem, error) {
+	orderItems := []models.OrderItem{}
+	err := mongoFindAll(ctx, r.collection, bson.M{"order_id": orderID}, &orderItems)
+	return orderItems, err
+}
+
+func (r *mongoOrderItemRepository) FindByID(ctx context.Context, orderItemID string) (orderItem models.OrderItem, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"order_item_id": orderItemID}, &orderItem)
+	return orderItem, err
+}
+
+func (r *mongoOrderItemRepository) ListReadyBetween(ctx context.Context, from, to time.Time) ([]models.OrderItem, error) {
+	orderItems := []models.OrderItem{}
+	err := mongoFindAll(ctx, r.collection, bson.M{"ready_at": bson.M{"$gte": from, "$lt": to}}, &orderItems)
+	return orderItems, err
+}
+
+func (r *mongoOrderItemRepository) CreateMany(ctx context.Context, orderItems []models.OrderItem) error {
+	if len(orderItems) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(orderItems))
+	for i := range orderItems {
+		docs = append(docs, orderItems[i])
+	}
+	_, err := r.collection.InsertMany(ctx, docs)
+	return mongoError(err)
+}
+
+func (r *mongoOrderItemRepository) Update(ctx context.Context, orderItem *models.OrderItem) error {
+	return mongoReplace(ctx, r.collection, bson.M{"order_item_id": orderItem.OrderItemID}, orderItem)
+}
+
+func (r *mongoOrderItemRepository) ItemsByOrder(ctx context.Context, orderID string) ([]models.OrderSummary, error) {
+	matchStage := bson.D{{Key: "$match", Value: bson.D{{Key: "order_id", Value: orderID}}}}
+	lookupStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "food"}, {Key: "localField", Value: "food_id"}, {Key: "foreignField", Value: "food_id"}, {Key: "as", Value: "food"}}}}
+	unwindStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$food"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
+
+	lookupOrderStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "order"}, {Key: "localField", Value: "order_id"}, {Key: "foreignField", Value: "order_id"}, {Key: "as", Value: "order"}}}}
+	unwindOrderStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$order"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
+
+	lookupTableStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "table"}, {Key: "localField", Value: "order.table_id"}, {Key: "foreignField", Value: "table_id"}, {Key: "as", Value: "table"}}}}
+	unwindTableStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$table"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
+
+	// tables merged into the table of the order for a large party
+	lookupMergedStage := bson.D{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: "table"},
+		{Key: "let", Value: bson.D{{Key: "table_id", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$table.table_id", ""}}}}}},
+		{Key: "pipeline", Value: bson.A{bson.D{{Key: "$match", Value: bson.D{{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$merged_into", "$$table_id"}}}}}}}}},
+		{Key: "as", Value: "merged"},
+	}}}
+
+	//this stage determines what goes to the frontend
+	projectStage := bson.D{
+		{
+			Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "amount", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$line_total", "$food.price"}}}},
+				{Key: "food_name", Value: "$food.name"},
+				{Key: "food_image", Value: "$food.food_image"},
+				{Key: "table_number", Value: "$table.table_number"},
+				{Key: "table_id", Value: "$table.table_id"},
+				{Key: "order_id", Value: "$order.order_id"},
+				{Key: "price", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$unit_price", "$food.price"}}}},
+				{Key: "quantity", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$units", 1}}}},
+				{Key: "modifiers", Value: "$modifiers"},
+				{Key: "notes", Value: "$notes"},
+				{Key: "merged_numbers", Value: "$merged.table_number"},
+			},
+		},
+	}
+
+	groupStage := bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: bson.D{{Key: "order_id", Value: "$order_id"}, {Key: "table_id", Value: "$table_id"}, {Key: "table_number", Value: "$table_number"}}},
+		{Key: "total_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		{Key: "merged_numbers", Value: bson.D{{Key: "$first", Value: "$merged_numbers"}}},
+		{Key: "order_items", Value: bson.D{{Key: "$push", Value: "$$ROOT"}}},
+	}}}
+
+	projectStage2 := bson.D{
+		{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "total_count", Value: 1},
+			{Key: "table_number", Value: "$_id.table_number"},
+			{Key: "table_numbers", Value: bson.D{{Key: "$filter", Value: bson.D{
+				{Key: "input", Value: bson.D{{Key: "$concatArrays", Value: bson.A{bson.A{"$_id.table_number"}, bson.D{{Key: "$ifNull", Value: bson.A{"$merged_numbers", bson.A{}}}}}}}},
+				{Key: "cond", Value: bson.D{{Key: "$ne", Value: bson.A{"$$this", nil}}}},
+			}}}},
+			{Key: "order_items", Value: 1},
+		}}}
+
+	result, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		matchStage,
+		lookupStage,
+		unwindStage,
+		lookupOrderStage,
+		unwindOrderStage,
+		lookupTableStage,
+		unwindTableStage,
+		lookupMergedStage,
+		projectStage,
+		groupStage,
+		projectStage2})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := []models.OrderSummary{}
+	if err = result.All(ctx, &summaries); err != nil {
+		return nil, err
+	}
+	for i := range summaries {
+		sumPaymentDue(&summaries[i])
+	}
+	return summaries, nil
+}
+
+// sumPaymentDue adds up the line amounts in Go rather than in the pipeline,
+// which cannot sum Money documents and prices stored as plain numbers alike.
+func sumPaymentDue(summary *models.OrderSummary) {
+	summary.PaymentDue = models.Money{}
+	for _, line := range summary.OrderItems {
+		if line.Amount != nil {
+			summary.PaymentDue = summary.PaymentDue.Add(*line.Amount)
+		}
+	}
+}
+
+type memoryOrderItemRepository struct {
+	store      *memoryStore
+	orderItems *memoryCollection
+	foods      *memoryCollection
+	orders     *memoryCollection
+	tables     *memoryCollection
+}
+
+func (r *memoryOrderItemRepository) List(ctx context.Context) (orderItems []models.OrderItem, err error) {
+	err = r.store.view(ctx, func() error {
+		orderItems, err = memoryFilter[models.OrderItem](r.orderItems, nil)
+		return err
+	})
+	return orderItems, err
+}
+
+func (r *memoryOrderItemRepository) ListByOrder(ctx context.Context, orderID string) (orderItems []models.OrderItem, err error) {
+	err = r.store.view(ctx, func() error {
+		orderItems, err = r.byOrder(orderID)
+		return err
+	})
+	return orderItems, err
+}
+
+func (r *memoryOrderItemRepository) ListReadyBetween(ctx context.Context, from, to time.Time) (orderItems []models.OrderItem, err error) {
+	err = r.store.view(ctx, func() error {
+		orderItems, err = memoryFilter(r.orderItems, func(orderItem models.OrderItem) bool {
+			return orderItem.ReadyAt != nil && !orderItem.ReadyAt.Before(from) && orderItem.ReadyAt.Before(to)
+		})
+		return err
+	})
+	return orderItems, err
+}
+
+func (r *memoryOrderItemRepository) FindByID(ctx context.Context, orderItemID string) (orderItem models.OrderItem, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.orderItems.find(orderItemID, &orderItem)
+	})
+	return orderItem, err
+}
+
+func (r *memoryOrderItemRepository) CreateMany(ctx context.Context, orderItems []models.OrderItem) error {
+	return r.store.update(ctx, func() error {
+		for i := range orderItems {
+			if _, ok := r.orderItems.docs[orderItems[i].OrderItemID]; ok {
+				return ErrDuplicate
+			}
+		}
+		for i := range orderItems {
+			if err := r.orderItems.insert(orderItems[i].OrderItemID, &orderItems[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryOrderItemRepository) Update(ctx context.Context, orderItem *models.OrderItem) error {
+	return r.store.update(ctx, func() error {
+		return r.orderItems.replace(orderItem.OrderItemID, orderItem)
+	})
+}
+
+// ItemsByOrder mirrors the lookup/group pipeline of the Mongo implementation
+// and produces documents of the same shape.
+func (r *memoryOrderItemRepository) ItemsByOrder(ctx context.Context, orderID string) (result []models.OrderSummary, err error) {
+	err = r.store.view(ctx, func() error {
+		orderItems, err := r.byOrder(orderID)
+		if err != nil || len(orderItems) == 0 {
+			result = []models.OrderSummary{}
+			return err
+		}
+
+		var order models.Order
+		var table models.Table
+		hasOrder := r.orders.find(orderID, &order) == nil
+		hasTable := hasOrder && order.TableID != nil && r.tables.find(*order.TableID, &table) == nil
+
+		summary := models.OrderSummary{TotalCount: len(orderItems), OrderItems: []models.OrderSummaryLine{}}
+		for _, orderItem := range orderItems {
+			line := models.OrderSummaryLine{Quantity: 1, Price: orderItem.UnitPrice, Amount: orderItem.LineTotal, Modifiers: orderItem.Modifiers, Notes: orderItem.Notes}
+			if orderItem.Units != nil {
+				line.Quantity = *orderItem.Units
+			}
+			var food models.Food
+			if orderItem.FoodID != nil && r.foods.find(*orderItem.FoodID, &food) == nil {
+				if line.Amount == nil {
+					line.Amount = food.Price
+				}
+				if line.Price == nil {
+					line.Price = food.Price
+				}
+				line.FoodName = food.Name
+				line.FoodImage = food.FoodImage
+			}
+			if hasOrder {
+				line.OrderID = order.OrderID
+			}
+			if hasTable {
+				line.TableID = table.TableID
+				line.TableNumber = table.TableNumber
+			}
+			summary.OrderItems = append(summary.OrderItems, line)
+		}
+		summary.TableNumbers = []int{}
+		if hasTable {
+			summary.TableNumber = table.TableNumber
+			if table.TableNumber != nil {
+				summary.TableNumbers = append(summary.TableNumbers, *table.TableNumber)
+			}
+			merged, err := memoryFilter(r.tables, func(other models.Table) bool {
+				return other.MergedInto != nil && *other.MergedInto == table.TableID && other.TableNumber != nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, other := range merged {
+				summary.TableNumbers = append(summary.TableNumbers, *other.TableNumber)
+			}
+		}
+		sumPaymentDue(&summary)
+		result = []models.OrderSummary{summary}
+		return nil
+	})
+	return result, err
+}
+
+func (r *memoryOrderItemRepository) byOrder(orderID string) ([]models.OrderItem, error) {
+	return memoryFilter(r.orderItems, func(orderItem models.OrderItem) bool {
+		return orderItem.OrderID == orderID
+	})
+}
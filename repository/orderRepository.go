@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OrderRepository interface {
+	List(ctx context.Context) ([]models.Order, error)
+	FindByID(ctx context.Context, orderID string) (models.Order, error)
+	Create(ctx context.Context, order *models.Order) error
+	Update(ctx context.Context, order *models.Order) error
+	// Transition moves the order to transition.To and appends transition to
+	// its history, but only while the order is still in status from. It
+	// returns ErrConflict when the order has moved on in the meantime.
+	Transition(ctx context.Context, orderID, from string, transition models.OrderTransition) (models.Order, error)
+}
+
+type mongoOrderRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoOrderRepository) List(ctx context.Context) ([]models.Order, error) {
+	orders := []models.Order{}
+	err := mongoFindAll(ctx, r.collection, bson.M{}, &orders)
+	return orders, err
+}
+
+func (r *mongoOrderRepository) FindByID(ctx context.Context, orderID string) (order models.Order, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"order_id": orderID}, &order)
+	return order, err
+}
+
+func (r *mongoOrderRepository) Create(ctx context.Context, order *models.Order) error {
+	_, err := r.collection.InsertOne(ctx, order)
+	return mongoError(err)
+}
+
+func (r *mongoOrderRepository) Update(ctx context.Context, order *models.Order) error {
+	return mongoReplace(ctx, r.collection, bson.M{"order_id": order.OrderID}, order)
+}
+
+func (r *mongoOrderRepository) Transition(ctx context.Context, orderID, from string, transition models.OrderTransition) (order models.Order, err error) {
+	status := interface{}(from)
+	if from == models.OrderStatusPlaced {
+		// also matches orders stored before statuses existed
+		status = bson.M{"$in": bson.A{from, nil, ""}}
+	}
+	err = r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"order_id": orderID, "status": status},
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "status", Value: transition.To},
+				{Key: "updated_at", Value: transition.ChangedAt},
+			}},
+			{Key: "$push", Value: bson.D{
+				{Key: "status_history", Value: transition},
+			}},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, err := r.FindByID(ctx, orderID); err != nil {
+			return order, err
+		}
+		return order, ErrConflict
+	}
+	return order, err
+}
+
+type memoryOrderRepository struct {
+	store  *memoryStore
+	orders *memoryCollection
+}
+
+func (r *memoryOrderRepository) List(ctx context.Context) (orders []models.Order, err error) {
+	err = r.store.view(ctx, func() error {
+		orders, err = memoryFilter[models.Order](r.orders, nil)
+		return err
+	})
+	return orders, err
+}
+
+func (r *memoryOrderRepository) FindByID(ctx context.Context, orderID string) (order models.Order, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.orders.find(orderID, &order)
+	})
+	return order, err
+}
+
+func (r *memoryOrderRepository) Create(ctx context.Context, order *models.Order) error {
+	return r.store.update(ctx, func() error {
+		return r.orders.insert(order.OrderID, order)
+	})
+}
+
+func (r *memoryOrderRepository) Update(ctx context.Context, order *models.Order) error {
+	return r.store.update(ctx, func() error {
+		return r.orders.replace(order.OrderID, order)
+	})
+}
+
+func (r *memoryOrderRepository) Transition(ctx context.Context, orderID, from string, transition models.OrderTransition) (order models.Order, err error) {
+	err = r.store.update(ctx, func() error {
+		if err := r.orders.find(orderID, &order); err != nil {
+			return err
+		}
+		if order.CurrentStatus() != from {
+			return ErrConflict
+		}
+		order.Status = transition.To
+		order.StatusHistory = append(order.StatusHistory, transition)
+		order.UpdatedAt = transition.ChangedAt
+		return r.orders.replace(orderID, &order)
+	})
+	return order, err
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type PaymentRepository interface {
+	FindByID(ctx context.Context, paymentID string) (models.Payment, error)
+	ListByInvoice(ctx context.Context, invoiceID string) ([]models.Payment, error)
+	Create(ctx context.Context, payment *models.Payment) error
+	Update(ctx context.Context, payment *models.Payment) error
+	// ClaimKey records an idempotency key, or returns ErrDuplicate when the
+	// key was already claimed.
+	ClaimKey(ctx context.Context, key *models.IdempotencyKey) error
+	FindKey(ctx context.Context, key string) (models.IdempotencyKey, error)
+}
+
+type mongoPaymentRepository struct {
+	collection *mongo.Collection
+	keys       *mongo.Collection
+}
+
+func (r *mongoPaymentRepository) FindByID(ctx context.Context, paymentID string) (payment models.Payment, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"payment_id": paymentID}, &payment)
+	return payment, err
+}
+
+func (r *mongoPaymentRepository) ListByInvoice(ctx context.Context, invoiceID string) ([]models.Payment, error) {
+	payments := []models.Payment{}
+	err := mongoFindAll(ctx, r.collection, bson.M{"invoice_id": invoiceID}, &payments)
+	return payments, err
+}
+
+func (r *mongoPaymentRepository) Create(ctx context.Context, payment *models.Payment) error {
+	_, err := r.collection.InsertOne(ctx, payment)
+	return mongoError(err)
+}
+
+func (r *mongoPaymentRepository) Update(ctx context.Context, payment *models.Payment) error {
+	return mongoReplace(ctx, r.collection, bson.M{"payment_id": payment.PaymentID}, payment)
+}
+
+func (r *mongoPaymentRepository) ClaimKey(ctx context.Context, key *models.IdempotencyKey) error {
+	_, err := r.keys.InsertOne(ctx, key)
+	return mongoError(err)
+}
+
+func (r *mongoPaymentRepository) FindKey(ctx context.Context, key string) (idempotencyKey models.IdempotencyKey, err error) {
+	err = mongoFindOne(ctx, r.keys, bson.M{"_id": key}, &idempotencyKey)
+	return idempotencyKey, err
+}
+
+type memoryPaymentRepository struct {
+	store    *memoryStore
+	payments *memoryCollection
+	keys     *memoryCollection
+}
+
+func (r *memoryPaymentRepository) FindByID(ctx context.Context, paymentID string) (payment models.Payment, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.payments.find(paymentID, &payment)
+	})
+	return payment, err
+}
+
+func (r *memoryPaymentRepository) ListByInvoice(ctx context.Context, invoiceID string) (payments []models.Payment, err error) {
+	err = r.store.view(ctx, func() error {
+		payments, err = memoryFilter(r.payments, func(payment models.Payment) bool {
+			return payment.InvoiceID == invoiceID
+		})
+		return err
+	})
+	return payments, err
+}
+
+func (r *memoryPaymentRepository) Create(ctx context.Context, payment *models.Payment) error {
+	return r.store.update(ctx, func() error {
+		return r.payments.insert(payment.PaymentID, payment)
+	})
+}
+
+func (r *memoryPaymentRepository) Update(ctx context.Context, payment *models.Payment) error {
+	return r.store.update(ctx, func() error {
+		return r.payments.replace(payment.PaymentID, payment)
+	})
+}
+
+func (r *memoryPaymentRepository) ClaimKey(ctx context.Context, key *models.IdempotencyKey) error {
+	return r.store.update(ctx, func() error {
+		return r.keys.insert(key.Key, key)
+	})
+}
+
+func (r *memoryPaymentRepository) FindKey(ctx context.Context, key string) (idempotencyKey models.IdempotencyKey, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.keys.find(key, &idempotencyKey)
+	})
+	return idempotencyKey, err
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrDuplicate = errors.New("record already exists")
+	ErrConflict  = errors.New("record was modified concurrently")
+)
+
+// Repositories bundles one repository per aggregate so that controllers can be
+// wired against either backend without knowing which one is in use.
+type Repositories struct {
+	Foods        FoodRepository
+	Menus        MenuRepository
+	Orders       OrderRepository
+	OrderItems   OrderItemRepository
+	Invoices     InvoiceRepository
+	Tables       TableRepository
+	Users        UserRepository
+	Sessions     SessionRepository
+	TaxRules     TaxRuleRepository
+	Payments     PaymentRepository
+	CreditNotes  CreditNoteRepository
+	Counters     CounterRepository
+	Stations     StationRepository
+	Tickets      KitchenTicketRepository
+	Reservations ReservationRepository
+	Areas        AreaRepository
+	Sections     SectionRepository
+	Shifts       ShiftRepository
+	Waitlist     WaitlistRepository
+
+	Transactions Transactor
+}
+
+// Transactor runs fn as one atomic unit: either every write fn makes through
+// the repositories with the ctx it is given is kept, or none is. fn may be
+// retried on transient conflicts and must not have side effects outside the
+// repositories.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ReservationRepository interface {
+	// ListBetween returns the reservations of any status whose time overlaps
+	// [from, to).
+	ListBetween(ctx context.Context, from, to time.Time) ([]models.Reservation, error)
+	FindByID(ctx context.Context, reservationID string) (models.Reservation, error)
+	Create(ctx context.Context, reservation *models.Reservation) error
+	Update(ctx context.Context, reservation *models.Reservation) error
+}
+
+type mongoReservationRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoReservationRepository) ListBetween(ctx context.Context, from, to time.Time) ([]models.Reservation, error) {
+	reservations := []models.Reservation{}
+	err := mongoFindAll(ctx, r.collection, bson.M{"starts_at": bson.M{"$lt": to}, "ends_at": bson.M{"$gt": from}}, &reservations)
+	return reservations, err
+}
+
+func (r *mongoReservationRepository) FindByID(ctx context.Context, reservationID string) (reservation models.Reservation, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"reservation_id": reservationID}, &reservation)
+	return reservation, err
+}
+
+func (r *mongoReservationRepository) Create(ctx context.Context, reservation *models.Reservation) error {
+	_, err := r.collection.InsertOne(ctx, reservation)
+	return mongoError(err)
+}
+
+func (r *mongoReservationRepository) Update(ctx context.Context, reservation *models.Reservation) error {
+	return mongoReplace(ctx, r.collection, bson.M{"reservation_id": reservation.ReservationID}, reservation)
+}
+
+type memoryReservationRepository struct {
+	store        *memoryStore
+	reservations *memoryCollection
+}
+
+func (r *memoryReservationRepository) ListBetween(ctx context.Context, from, to time.Time) (reservations []models.Reservation, err error) {
+	err = r.store.view(ctx, func() error {
+		reservations, err = memoryFilter(r.reservations, func(reservation models.Reservation) bool {
+			return reservation.StartsAt.Before(to) && reservation.EndsAt.After(from)
+		})
+		return err
+	})
+	return reservations, err
+}
+
+func (r *memoryReservationRepository) FindByID(ctx context.Context, reservationID string) (reservation models.Reservation, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.reservations.find(reservationID, &reservation)
+	})
+	return reservation, err
+}
+
+func (r *memoryReservationRepository) Create(ctx context.Context, reservation *models.Reservation) error {
+	return r.store.update(ctx, func() error {
+		return r.reservations.insert(reservation.ReservationID, reservation)
+	})
+}
+
+func (r *memoryReservationRepository) Update(ctx context.Context, reservation *models.Reservation) error {
+	return r.store.update(ctx, func() error {
+		return r.reservations.replace(reservation.ReservationID, reservation)
+	})
+}
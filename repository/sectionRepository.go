@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SectionRepository interface {
+	List(ctx context.Context) ([]models.Section, error)
+	FindByID(ctx context.Context, sectionID string) (models.Section, error)
+	Create(ctx context.Context, section *models.Section) error
+	Update(ctx context.Context, section *models.Section) error
+}
+
+type mongoSectionRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoSectionRepository) List(ctx context.Context) ([]models.Section, error) {
+	sections := []models.Section{}
+	err := mongoFindAll(ctx, r.collection, bson.M{}, &sections)
+	return sections, err
+}
+
+func (r *mongoSectionRepository) FindByID(ctx context.Context, sectionID string) (section models.Section, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"section_id": sectionID}, &section)
+	return section, err
+}
+
+func (r *mongoSectionRepository) Create(ctx context.Context, section *models.Section) error {
+	_, err := r.collection.InsertOne(ctx, section)
+	return mongoError(err)
+}
+
+func (r *mongoSectionRepository) Update(ctx context.Context, section *models.Section) error {
+	return mongoReplace(ctx, r.collection, bson.M{"section_id": section.SectionID}, section)
+}
+
+type memorySectionRepository struct {
+	store    *memoryStore
+	sections *memoryCollection
+}
+
+func (r *memorySectionRepository) List(ctx context.Context) (sections []models.Section, err error) {
+	err = r.store.view(ctx, func() error {
+		sections, err = memoryFilter[models.Section](r.sections, nil)
+		return err
+	})
+	return sections, err
+}
+
+func (r *memorySectionRepository) FindByID(ctx context.Context, sectionID string) (section models.Section, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.sections.find(sectionID, &section)
+	})
+	return section, err
+}
+
+func (r *memorySectionRepository) Create(ctx context.Context, section *models.Section) error {
+	return r.store.update(ctx, func() error {
+		return r.sections.insert(section.SectionID, section)
+	})
+}
+
+func (r *memorySectionRepository) Update(ctx context.Context, section *models.Section) error {
+	return r.store.update(ctx, func() error {
+		return r.sections.replace(section.SectionID, section)
+	})
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindByID(ctx context.Context, sessionID string) (models.Session, error)
+	// Rotate replaces the current refresh token of an active session, but only
+	// if currentTokenID is still the current one. It returns ErrNotFound when
+	// the session is revoked or the token was already rotated.
+	Rotate(ctx context.Context, sessionID, currentTokenID, nextTokenID string, expiresAt time.Time) error
+	Revoke(ctx context.Context, sessionID, reason string) error
+}
+
+type mongoSessionRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	_, err := r.collection.InsertOne(ctx, session)
+	return mongoError(err)
+}
+
+func (r *mongoSessionRepository) FindByID(ctx context.Context, sessionID string) (session models.Session, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"session_id": sessionID}, &session)
+	return session, err
+}
+
+func (r *mongoSessionRepository) Rotate(ctx context.Context, sessionID, currentTokenID, nextTokenID string, expiresAt time.Time) error {
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"session_id": sessionID, "current_token_id": currentTokenID, "revoked": false},
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "current_token_id", Value: nextTokenID},
+				{Key: "expires_at", Value: expiresAt},
+				{Key: "updated_at", Value: updatedAt},
+			}},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoSessionRepository) Revoke(ctx context.Context, sessionID, reason string) error {
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"session_id": sessionID, "revoked": false},
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "revoked", Value: true},
+				{Key: "revoked_at", Value: now},
+				{Key: "revoked_reason", Value: reason},
+				{Key: "updated_at", Value: now},
+			}},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memorySessionRepository struct {
+	store    *memoryStore
+	sessions *memoryCollection
+}
+
+func (r *memorySessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return r.store.update(ctx, func() error {
+		return r.sessions.insert(session.SessionID, session)
+	})
+}
+
+func (r *memorySessionRepository) FindByID(ctx context.Context, sessionID string) (session models.Session, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.sessions.find(sessionID, &session)
+	})
+	return session, err
+}
+
+func (r *memorySessionRepository) Rotate(ctx context.Context, sessionID, currentTokenID, nextTokenID string, expiresAt time.Time) error {
+	return r.store.update(ctx, func() error {
+		var session models.Session
+		if err := r.sessions.find(sessionID, &session); err != nil {
+			return err
+		}
+		if session.Revoked || session.CurrentTokenID != currentTokenID {
+			return ErrNotFound
+		}
+		session.CurrentTokenID = nextTokenID
+		session.ExpiresAt = expiresAt
+		session.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		return r.sessions.replace(sessionID, &session)
+	})
+}
+
+func (r *memorySessionRepository) Revoke(ctx context.Context, sessionID, reason string) error {
+	return r.store.update(ctx, func() error {
+		var session models.Session
+		if err := r.sessions.find(sessionID, &session); err != nil {
+			return err
+		}
+		if session.Revoked {
+			return ErrNotFound
+		}
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		session.Revoked = true
+		session.RevokedAt = &now
+		session.RevokedReason = reason
+		session.UpdatedAt = now
+		return r.sessions.replace(sessionID, &session)
+	})
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ShiftRepository interface {
+	// ListBetween returns the shifts, cancelled ones included, that overlap
+	// [from, to).
+	ListBetween(ctx context.Context, from, to time.Time) ([]models.Shift, error)
+	FindByID(ctx context.Context, shiftID string) (models.Shift, error)
+	Create(ctx context.Context, shift *models.Shift) error
+	Update(ctx context.Context, shift *models.Shift) error
+}
+
+type mongoShiftRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoShiftRepository) ListBetween(ctx context.Context, from, to time.Time) ([]models.Shift, error) {
+	shifts := []models.Shift{}
+	err := mongoFindAll(ctx, r.collection, bson.M{"starts_at": bson.M{"$lt": to}, "ends_at": bson.M{"$gt": from}}, &shifts)
+	return shifts, err
+}
+
+func (r *mongoShiftRepository) FindByID(ctx context.Context, shiftID string) (shift models.Shift, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"shift_id": shiftID}, &shift)
+	return shift, err
+}
+
+func (r *mongoShiftRepository) Create(ctx context.Context, shift *models.Shift) error {
+	_, err := r.collection.InsertOne(ctx, shift)
+	return mongoError(err)
+}
+
+func (r *mongoShiftRepository) Update(ctx context.Context, shift *models.Shift) error {
+	return mongoReplace(ctx, r.collection, bson.M{"shift_id": shift.ShiftID}, shift)
+}
+
+type memoryShiftRepository struct {
+	store  *memoryStore
+	shifts *memoryCollection
+}
+
+func (r *memoryShiftRepository) ListBetween(ctx context.Context, from, to time.Time) (shifts []models.Shift, err error) {
+	err = r.store.view(ctx, func() error {
+		shifts, err = memoryFilter(r.shifts, func(shift models.Shift) bool {
+			return shift.StartsAt.Before(to) && shift.EndsAt.After(from)
+		})
+		return err
+	})
+	return shifts, err
+}
+
+func (r *memoryShiftRepository) FindByID(ctx context.Context, shiftID string) (shift models.Shift, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.shifts.find(shiftID, &shift)
+	})
+	return shift, err
+}
+
+func (r *memoryShiftRepository) Create(ctx context.Context, shift *models.Shift) error {
+	return r.store.update(ctx, func() error {
+		return r.shifts.insert(shift.ShiftID, shift)
+	})
+}
+
+func (r *memoryShiftRepository) Update(ctx context.Context, shift *models.Shift) error {
+	return r.store.update(ctx, func() error {
+		return r.shifts.replace(shift.ShiftID, shift)
+	})
+}
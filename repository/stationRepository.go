@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type StationRepository interface {
+	List(ctx context.Context) ([]models.Station, error)
+	FindByID(ctx context.Context, stationID string) (models.Station, error)
+	Create(ctx context.Context, station *models.Station) error
+	Update(ctx context.Context, station *models.Station) error
+}
+
+type mongoStationRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoStationRepository) List(ctx context.Context) ([]models.Station, error) {
+	stations := []models.Station{}
+	err := mongoFindAll(ctx, r.collection, bson.M{}, &stations)
+	return stations, err
+}
+
+func (r *mongoStationRepository) FindByID(ctx context.Context, stationID string) (station models.Station, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"station_id": stationID}, &station)
+	return station, err
+}
+
+func (r *mongoStationRepository) Create(ctx context.Context, station *models.Station) error {
+	_, err := r.collection.InsertOne(ctx, station)
+	return mongoError(err)
+}
+
+func (r *mongoStationRepository) Update(ctx context.Context, station *models.Station) error {
+	return mongoReplace(ctx, r.collection, bson.M{"station_id": station.StationID}, station)
+}
+
+type memoryStationRepository struct {
+	store    *memoryStore
+	stations *memoryCollection
+}
+
+func (r *memoryStationRepository) List(ctx context.Context) (stations []models.Station, err error) {
+	err = r.store.view(ctx, func() error {
+		stations, err = memoryFilter[models.Station](r.stations, nil)
+		return err
+	})
+	return stations, err
+}
+
+func (r *memoryStationRepository) FindByID(ctx context.Context, stationID string) (station models.Station, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.stations.find(stationID, &station)
+	})
+	return station, err
+}
+
+func (r *memoryStationRepository) Create(ctx context.Context, station *models.Station) error {
+	return r.store.update(ctx, func() error {
+		return r.stations.insert(station.StationID, station)
+	})
+}
+
+func (r *memoryStationRepository) Update(ctx context.Context, station *models.Station) error {
+	return r.store.update(ctx, func() error {
+		return r.stations.replace(station.StationID, station)
+	})
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TableRepository interface {
+	List(ctx context.Context) ([]models.Table, error)
+	FindByID(ctx context.Context, tableID string) (models.Table, error)
+	Create(ctx context.Context, table *models.Table) error
+	Update(ctx context.Context, table *models.Table) error
+}
+
+type mongoTableRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoTableRepository) List(ctx context.Context) ([]models.Table, error) {
+	tables := []models.Table{}
+	err := mongoFindAll(ctx, r.collection, bson.M{}, &tables)
+	return tables, err
+}
+
+func (r *mongoTableRepository) FindByID(ctx context.Context, tableID string) (table models.Table, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"table_id": tableID}, &table)
+	return table, err
+}
+
+func (r *mongoTableRepository) Create(ctx context.Context, table *models.Table) error {
+	_, err := r.collection.InsertOne(ctx, table)
+	return mongoError(err)
+}
+
+func (r *mongoTableRepository) Update(ctx context.Context, table *models.Table) error {
+	return mongoReplace(ctx, r.collection, bson.M{"table_id": table.TableID}, table)
+}
+
+type memoryTableRepository struct {
+	store  *memoryStore
+	tables *memoryCollection
+}
+
+func (r *memoryTableRepository) List(ctx context.Context) (tables []models.Table, err error) {
+	err = r.store.view(ctx, func() error {
+		tables, err = memoryFilter[models.Table](r.tables, nil)
+		return err
+	})
+	return tables, err
+}
+
+func (r *memoryTableRepository) FindByID(ctx context.Context, tableID string) (table models.Table, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.tables.find(tableID, &table)
+	})
+	return table, err
+}
+
+func (r *memoryTableRepository) Create(ctx context.Context, table *models.Table) error {
+	return r.store.update(ctx, func() error {
+		return r.tables.insert(table.TableID, table)
+	})
+}
+
+func (r *memoryTableRepository) Update(ctx context.Context, table *models.Table) error {
+	return r.store.update(ctx, func() error {
+		return r.tables.replace(table.TableID, table)
+	})
+}
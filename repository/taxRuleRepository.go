@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TaxRuleRepository interface {
+	List(ctx context.Context) ([]models.TaxRule, error)
+	FindByID(ctx context.Context, taxRuleID string) (models.TaxRule, error)
+	Create(ctx context.Context, taxRule *models.TaxRule) error
+	Update(ctx context.Context, taxRule *models.TaxRule) error
+}
+
+type mongoTaxRuleRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoTaxRuleRepository) List(ctx context.Context) ([]models.TaxRule, error) {
+	taxRules := []models.TaxRule{}
+	err := mongoFindAll(ctx, r.collection, bson.M{}, &taxRules)
+	return taxRules, err
+}
+
+func (r *mongoTaxRuleRepository) FindByID(ctx context.Context, taxRuleID string) (taxRule models.TaxRule, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"tax_rule_id": taxRuleID}, &taxRule)
+	return taxRule, err
+}
+
+func (r *mongoTaxRuleRepository) Create(ctx context.Context, taxRule *models.TaxRule) error {
+	_, err := r.collection.InsertOne(ctx, taxRule)
+	return mongoError(err)
+}
+
+func (r *mongoTaxRuleRepository) Update(ctx context.Context, taxRule *models.TaxRule) error {
+	return mongoReplace(ctx, r.collection, bson.M{"tax_rule_id": taxRule.TaxRuleID}, taxRule)
+}
+
+type memoryTaxRuleRepository struct {
+	store    *memoryStore
+	taxRules *memoryCollection
+}
+
+func (r *memoryTaxRuleRepository) List(ctx context.Context) (taxRules []models.TaxRule, err error) {
+	err = r.store.view(ctx, func() error {
+		taxRules, err = memoryFilter[models.TaxRule](r.taxRules, nil)
+		return err
+	})
+	return taxRules, err
+}
+
+func (r *memoryTaxRuleRepository) FindByID(ctx context.Context, taxRuleID string) (taxRule models.TaxRule, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.taxRules.find(taxRuleID, &taxRule)
+	})
+	return taxRule, err
+}
+
+func (r *memoryTaxRuleRepository) Create(ctx context.Context, taxRule *models.TaxRule) error {
+	return r.store.update(ctx, func() error {
+		return r.taxRules.insert(taxRule.TaxRuleID, taxRule)
+	})
+}
+
+func (r *memoryTaxRuleRepository) Update(ctx context.Context, taxRule *models.TaxRule) error {
+	return r.store.update(ctx, func() error {
+		return r.taxRules.replace(taxRule.TaxRuleID, taxRule)
+	})
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type UserRepository interface {
+	List(ctx context.Context, startIndex, limit int) ([]models.User, int64, error)
+	FindByID(ctx context.Context, userID string) (models.User, error)
+	FindByEmail(ctx context.Context, email string) (models.User, error)
+	ExistsByEmailOrPhone(ctx context.Context, email, phone string) (bool, error)
+	Create(ctx context.Context, user *models.User) error
+	UpdateTokens(ctx context.Context, userID, token, refreshToken string) error
+}
+
+type mongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUserRepository) List(ctx context.Context, startIndex, limit int) ([]models.User, int64, error) {
+	users := []models.User{}
+	total, err := mongoFindPage(ctx, r.collection, bson.D{}, startIndex, limit, &users)
+	return users, total, err
+}
+
+func (r *mongoUserRepository) FindByID(ctx context.Context, userID string) (user models.User, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"userid": userID}, &user)
+	return user, err
+}
+
+func (r *mongoUserRepository) FindByEmail(ctx context.Context, email string) (user models.User, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"email": email}, &user)
+	return user, err
+}
+
+func (r *mongoUserRepository) ExistsByEmailOrPhone(ctx context.Context, email, phone string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"$or": bson.A{
+		bson.M{"email": email},
+		bson.M{"phone": phone},
+	}})
+	return count > 0, err
+}
+
+func (r *mongoUserRepository) Create(ctx context.Context, user *models.User) error {
+	_, err := r.collection.InsertOne(ctx, user)
+	return mongoError(err)
+}
+
+func (r *mongoUserRepository) UpdateTokens(ctx context.Context, userID, token, refreshToken string) error {
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"userid": userID},
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "token", Value: token},
+				{Key: "refresh_token", Value: refreshToken},
+				{Key: "updated_at", Value: updatedAt},
+			}},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryUserRepository struct {
+	store *memoryStore
+	users *memoryCollection
+}
+
+func (r *memoryUserRepository) List(ctx context.Context, startIndex, limit int) (users []models.User, total int64, err error) {
+	err = r.store.view(ctx, func() error {
+		all, err := memoryFilter[models.User](r.users, nil)
+		total, users = int64(len(all)), memoryPage(all, startIndex, limit)
+		return err
+	})
+	return users, total, err
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, userID string) (user models.User, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.users.find(userID, &user)
+	})
+	return user, err
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (user models.User, err error) {
+	err = r.store.view(ctx, func() error {
+		users, err := memoryFilter(r.users, func(u models.User) bool {
+			return u.Email != nil && *u.Email == email
+		})
+		if err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return ErrNotFound
+		}
+		user = users[0]
+		return nil
+	})
+	return user, err
+}
+
+func (r *memoryUserRepository) ExistsByEmailOrPhone(ctx context.Context, email, phone string) (exists bool, err error) {
+	err = r.store.view(ctx, func() error {
+		users, err := memoryFilter(r.users, func(u models.User) bool {
+			return (u.Email != nil && *u.Email == email) || (u.Phone != nil && *u.Phone == phone)
+		})
+		exists = len(users) > 0
+		return err
+	})
+	return exists, err
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	return r.store.update(ctx, func() error {
+		return r.users.insert(user.UserID, user)
+	})
+}
+
+func (r *memoryUserRepository) UpdateTokens(ctx context.Context, userID, token, refreshToken string) error {
+	return r.store.update(ctx, func() error {
+		var user models.User
+		if err := r.users.find(userID, &user); err != nil {
+			return err
+		}
+		user.Token = &token
+		user.RefreshToken = &refreshToken
+		user.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		return r.users.replace(userID, &user)
+	})
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Micah-Shallom/modules/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type WaitlistRepository interface {
+	// ListWaiting returns the entries still WAITING or NOTIFIED.
+	ListWaiting(ctx context.Context) ([]models.WaitlistEntry, error)
+	FindByID(ctx context.Context, entryID string) (models.WaitlistEntry, error)
+	Create(ctx context.Context, entry *models.WaitlistEntry) error
+	Update(ctx context.Context, entry *models.WaitlistEntry) error
+}
+
+type mongoWaitlistRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoWaitlistRepository) ListWaiting(ctx context.Context) ([]models.WaitlistEntry, error) {
+	entries := []models.WaitlistEntry{}
+	err := mongoFindAll(ctx, r.collection, bson.M{"status": bson.M{"$in": bson.A{models.WaitlistStatusWaiting, models.WaitlistStatusNotified}}}, &entries)
+	return entries, err
+}
+
+func (r *mongoWaitlistRepository) FindByID(ctx context.Context, entryID string) (entry models.WaitlistEntry, err error) {
+	err = mongoFindOne(ctx, r.collection, bson.M{"entry_id": entryID}, &entry)
+	return entry, err
+}
+
+func (r *mongoWaitlistRepository) Create(ctx context.Context, entry *models.WaitlistEntry) error {
+	_, err := r.collection.InsertOne(ctx, entry)
+	return mongoError(err)
+}
+
+func (r *mongoWaitlistRepository) Update(ctx context.Context, entry *models.WaitlistEntry) error {
+	return mongoReplace(ctx, r.collection, bson.M{"entry_id": entry.EntryID}, entry)
+}
+
+type memoryWaitlistRepository struct {
+	store   *memoryStore
+	entries *memoryCollection
+}
+
+func (r *memoryWaitlistRepository) ListWaiting(ctx context.Context) (entries []models.WaitlistEntry, err error) {
+	err = r.store.view(ctx, func() error {
+		entries, err = memoryFilter(r.entries, func(entry models.WaitlistEntry) bool {
+			return entry.Waiting()
+		})
+		return err
+	})
+	return entries, err
+}
+
+func (r *memoryWaitlistRepository) FindByID(ctx context.Context, entryID string) (entry models.WaitlistEntry, err error) {
+	err = r.store.view(ctx, func() error {
+		return r.entries.find(entryID, &entry)
+	})
+	return entry, err
+}
+
+func (r *memoryWaitlistRepository) Create(ctx context.Context, entry *models.WaitlistEntry) error {
+	return r.store.update(ctx, func() error {
+		return r.entries.insert(entry.EntryID, entry)
+	})
+}
+
+func (r *memoryWaitlistRepository) Update(ctx context.Context, entry *models.WaitlistEntry) error {
+	return r.store.update(ctx, func() error {
+		return r.entries.replace(entry.EntryID, entry)
+	})
+}
@@ -2,10 +2,15 @@ package routes
 
 import (
 	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
 	"github.com/gin-gonic/gin"
 )
 
-func AuthRoutes(incomingRoutes *gin.Engine){
-	incomingRoutes.POST("/signup/", controllers.SignUp())
-	incomingRoutes.POST("/login/", controllers.Login())
-}
\ No newline at end of file
+func AuthRoutes(incomingRoutes gin.IRoutes, protectedRoutes gin.IRoutes, ac *controllers.AuthController) {
+	incomingRoutes.POST("/signup/", ac.SignUp())
+	incomingRoutes.POST("/login/", ac.Login())
+	incomingRoutes.POST("/auth/refresh", ac.Refresh())
+	incomingRoutes.GET("/.well-known/jwks.json", ac.JWKS())
+	protectedRoutes.POST("/auth/logout", middleware.Authorize(models.AllRoles...), ac.Logout())
+}
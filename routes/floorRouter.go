@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/gin-gonic/gin"
+)
+
+func FloorRoutes(incomingRoutes gin.IRoutes, fc *controllers.FloorController) {
+	incomingRoutes.GET("/areas", middleware.Authorize(models.StaffRoles...), fc.GetAreas())
+	incomingRoutes.GET("/areas/:area_id", middleware.Authorize(models.StaffRoles...), fc.GetArea())
+	incomingRoutes.POST("/areas", middleware.Authorize(models.RoleManager), fc.CreateArea())
+	incomingRoutes.PATCH("/areas/:area_id", middleware.Authorize(models.RoleManager), fc.UpdateArea())
+	incomingRoutes.GET("/sections", middleware.Authorize(models.StaffRoles...), fc.GetSections())
+	incomingRoutes.GET("/sections/:section_id", middleware.Authorize(models.StaffRoles...), fc.GetSection())
+	incomingRoutes.POST("/sections", middleware.Authorize(models.RoleManager), fc.CreateSection())
+	incomingRoutes.PATCH("/sections/:section_id", middleware.Authorize(models.RoleManager), fc.UpdateSection())
+	incomingRoutes.GET("/shifts", middleware.Authorize(models.StaffRoles...), fc.GetShifts())
+	incomingRoutes.POST("/shifts", middleware.Authorize(models.RoleManager), fc.CreateShift())
+	incomingRoutes.POST("/shifts/:shift_id/cancel", middleware.Authorize(models.RoleManager), fc.CancelShift())
+}
@@ -2,12 +2,14 @@ package routes
 
 import (
 	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
 	"github.com/gin-gonic/gin"
 )
 
-func FoodRoutes(incomingRoutes *gin.Engine){
-	incomingRoutes.GET("/foods", controllers.GetFoods())
-	incomingRoutes.GET("/foods/:food_id", controllers.GetFood())
-	incomingRoutes.POST("/foods", controllers.CreateFood())
-	incomingRoutes.PATCH("/foods/:food_id", controllers.UpdateFood())
-}
\ No newline at end of file
+func FoodRoutes(incomingRoutes gin.IRoutes, fc *controllers.FoodController) {
+	incomingRoutes.GET("/foods", middleware.Authorize(models.AllRoles...), fc.GetFoods())
+	incomingRoutes.GET("/foods/:food_id", middleware.Authorize(models.AllRoles...), fc.GetFood())
+	incomingRoutes.POST("/foods", middleware.Authorize(models.RoleAdmin), fc.CreateFood())
+	incomingRoutes.PATCH("/foods/:food_id", middleware.Authorize(models.RoleAdmin), fc.UpdateFood())
+}
@@ -2,12 +2,26 @@ package routes
 
 import (
 	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
 	"github.com/gin-gonic/gin"
-) 
+)
 
-func InvoiceRoutes(incomingRoutes *gin.Engine){
-	incomingRoutes.GET("/invoices", controllers.GetInvoices())
-	incomingRoutes.GET("/invoices/:invoice_id", controllers.GetInvoice())
-	incomingRoutes.POST("/invoices",controllers.CreateInvoice())
-	incomingRoutes.PATCH("/invoices/:invoice_id", controllers.UpdateInvoice())
-}
\ No newline at end of file
+func InvoiceRoutes(incomingRoutes gin.IRoutes, ic *controllers.InvoiceController) {
+	incomingRoutes.GET("/invoices", middleware.Authorize(models.RoleCashier, models.RoleManager), ic.GetInvoices())
+	incomingRoutes.GET("/invoices/:invoice_id", middleware.Authorize(models.RoleWaiter, models.RoleCashier, models.RoleManager), ic.GetInvoice())
+	incomingRoutes.GET("/invoices/:invoice_id/receipt", middleware.Authorize(models.RoleWaiter, models.RoleCashier, models.RoleManager), ic.GetReceipt())
+	incomingRoutes.POST("/invoices/:invoice_id/receipt/print", middleware.Authorize(models.RoleWaiter, models.RoleCashier, models.RoleManager), ic.PrintReceipt())
+	incomingRoutes.POST("/invoices", middleware.Authorize(models.RoleWaiter, models.RoleCashier, models.RoleManager), ic.CreateInvoice())
+	incomingRoutes.PATCH("/invoices/:invoice_id", middleware.Authorize(models.RoleCashier, models.RoleManager), ic.UpdateInvoice())
+	incomingRoutes.POST("/invoices/:invoice_id/split", middleware.Authorize(models.RoleWaiter, models.RoleCashier, models.RoleManager), ic.SplitInvoice())
+	incomingRoutes.GET("/invoices/:invoice_id/payments", middleware.Authorize(models.RoleCashier, models.RoleManager), ic.GetInvoicePayments())
+	incomingRoutes.POST("/invoices/:invoice_id/splits/:split_id/payments", middleware.Authorize(models.RoleCashier, models.RoleManager), ic.AddPayment())
+	incomingRoutes.GET("/payments/:payment_id", middleware.Authorize(models.RoleCashier, models.RoleManager), ic.GetPayment())
+	incomingRoutes.POST("/payments/:payment_id/capture", middleware.Authorize(models.RoleCashier, models.RoleManager), ic.CapturePayment())
+	incomingRoutes.POST("/payments/:payment_id/refunds", middleware.Authorize(models.RoleManager), ic.RefundPayment())
+	incomingRoutes.POST("/invoices/:invoice_id/void", middleware.Authorize(models.RoleManager), ic.VoidInvoice())
+	incomingRoutes.GET("/invoices/:invoice_id/creditNotes", middleware.Authorize(models.RoleCashier, models.RoleManager), ic.GetInvoiceCreditNotes())
+	incomingRoutes.GET("/creditNotes", middleware.Authorize(models.RoleCashier, models.RoleManager), ic.GetCreditNotes())
+	incomingRoutes.GET("/creditNotes/:credit_note_id", middleware.Authorize(models.RoleCashier, models.RoleManager), ic.GetCreditNote())
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/gin-gonic/gin"
+)
+
+// StationStreamPath is the route of the Server-Sent Events stream of a
+// station, which stays open for as long as a display listens.
+const StationStreamPath = "/stations/:station_id/stream"
+
+func KitchenRoutes(incomingRoutes gin.IRoutes, kc *controllers.KitchenController) {
+	incomingRoutes.GET("/stations", middleware.Authorize(models.StaffRoles...), kc.GetStations())
+	incomingRoutes.GET("/stations/:station_id", middleware.Authorize(models.StaffRoles...), kc.GetStation())
+	incomingRoutes.POST("/stations", middleware.Authorize(models.RoleManager), kc.CreateStation())
+	incomingRoutes.PATCH("/stations/:station_id", middleware.Authorize(models.RoleManager), kc.UpdateStation())
+	incomingRoutes.GET("/stations/:station_id/tickets", middleware.Authorize(models.StaffRoles...), kc.GetStationTickets())
+	incomingRoutes.GET(StationStreamPath, middleware.Authorize(models.StaffRoles...), kc.StreamStation())
+	incomingRoutes.POST("/kitchenTickets/:ticket_id/bump", middleware.Authorize(models.RoleChef, models.RoleManager), kc.BumpTicket())
+	incomingRoutes.POST("/kitchenTickets/:ticket_id/recall", middleware.Authorize(models.RoleChef, models.RoleManager), kc.RecallTicket())
+}
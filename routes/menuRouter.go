@@ -2,12 +2,15 @@ package routes
 
 import (
 	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
 	"github.com/gin-gonic/gin"
 )
 
-func MenuRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/menus", controllers.GetMenus())
-	incomingRoutes.GET("/menus/:menu_id", controllers.GetMenu())
-	incomingRoutes.POST("/menus", controllers.CreateMenu())
-	incomingRoutes.PATCH("/menus/:menu_id", controllers.UpdateMenu())
-}
\ No newline at end of file
+func MenuRoutes(incomingRoutes gin.IRoutes, mc *controllers.MenuController) {
+	incomingRoutes.GET("/menus", middleware.Authorize(models.AllRoles...), mc.GetMenus())
+	incomingRoutes.GET("/menus/active", middleware.Authorize(models.AllRoles...), mc.GetActiveMenus())
+	incomingRoutes.GET("/menus/:menu_id", middleware.Authorize(models.AllRoles...), mc.GetMenu())
+	incomingRoutes.POST("/menus", middleware.Authorize(models.RoleAdmin), mc.CreateMenu())
+	incomingRoutes.PATCH("/menus/:menu_id", middleware.Authorize(models.RoleAdmin), mc.UpdateMenu())
+}
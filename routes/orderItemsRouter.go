@@ -2,13 +2,17 @@ package routes
 
 import (
 	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
 	"github.com/gin-gonic/gin"
 )
 
-func OrderItemsRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/orderItems", controllers.GetOrderItems())
-	incomingRoutes.GET("/orderItems/:orderItem_id", controllers.GetOrderItem())
-	incomingRoutes.GET("/orderItems-order/:order_id", controllers.GetOrderItemsByOrder())
-	incomingRoutes.POST("/orderItems", controllers.CreateOrderItem())
-	incomingRoutes.PATCH("/orderItems/:orderItem_id", controllers.UpdateOrderItem())
-}
\ No newline at end of file
+func OrderItemsRoutes(incomingRoutes gin.IRoutes, oic *controllers.OrderItemController) {
+	incomingRoutes.GET("/orderItems", middleware.Authorize(models.StaffRoles...), oic.GetOrderItems())
+	incomingRoutes.GET("/orderItems/:orderItem_id", middleware.Authorize(models.StaffRoles...), oic.GetOrderItem())
+	incomingRoutes.GET("/orderItems-order/:order_id", middleware.Authorize(models.StaffRoles...), oic.GetOrderItemsByOrder())
+	incomingRoutes.POST("/orderItems", middleware.Authorize(models.RoleWaiter, models.RoleManager), oic.CreateOrderItem())
+	incomingRoutes.PATCH("/orderItems/:orderItem_id", middleware.Authorize(models.RoleWaiter, models.RoleManager), oic.UpdateOrderItem())
+	incomingRoutes.POST("/orderItems/:orderItem_id/prep", middleware.Authorize(models.StaffRoles...), oic.AdvanceOrderItemPrep())
+	incomingRoutes.GET("/prepTimes", middleware.Authorize(models.RoleChef, models.RoleManager), oic.GetPrepTimes())
+}
@@ -1,14 +1,18 @@
 package routes
 
-
 import (
 	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
 	"github.com/gin-gonic/gin"
 )
 
-func OrderRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/orders", controllers.GetOrders())
-	incomingRoutes.GET("/orders/:order_id", controllers.GetOrder())
-	incomingRoutes.POST("/orders", controllers.CreateOrder())
-	incomingRoutes.PATCH("/orders/:order_id", controllers.UpdateOrder())
-}
\ No newline at end of file
+func OrderRoutes(incomingRoutes gin.IRoutes, oc *controllers.OrderController) {
+	incomingRoutes.GET("/orders", middleware.Authorize(models.StaffRoles...), oc.GetOrders())
+	incomingRoutes.GET("/orders/:order_id", middleware.Authorize(models.StaffRoles...), oc.GetOrder())
+	incomingRoutes.POST("/orders", middleware.Authorize(models.RoleWaiter, models.RoleManager), oc.CreateOrder())
+	incomingRoutes.PATCH("/orders/:order_id", middleware.Authorize(models.RoleWaiter, models.RoleManager), oc.UpdateOrder())
+	incomingRoutes.GET("/orders/:order_id/transitions", middleware.Authorize(models.StaffRoles...), oc.GetOrderTransitions())
+	incomingRoutes.POST("/orders/:order_id/transitions", middleware.Authorize(models.StaffRoles...), oc.TransitionOrder())
+	incomingRoutes.POST("/orders/:order_id/prep", middleware.Authorize(models.StaffRoles...), oc.AdvanceOrderPrep())
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/gin-gonic/gin"
+)
+
+func PrintRoutes(incomingRoutes gin.IRoutes, pc *controllers.PrintController) {
+	incomingRoutes.POST("/orders/:order_id/kitchenTicket", middleware.Authorize(models.RoleWaiter, models.RoleChef, models.RoleManager), pc.PrintKitchenTicket())
+	incomingRoutes.GET("/printJobs", middleware.Authorize(models.StaffRoles...), pc.GetPrintJobs())
+	incomingRoutes.GET("/printJobs/:job_id", middleware.Authorize(models.StaffRoles...), pc.GetPrintJob())
+}
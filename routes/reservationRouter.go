@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/gin-gonic/gin"
+)
+
+func ReservationRoutes(incomingRoutes gin.IRoutes, rc *controllers.ReservationController) {
+	hosts := []string{models.RoleWaiter, models.RoleCashier, models.RoleManager}
+	incomingRoutes.GET("/tables/availability", middleware.Authorize(models.StaffRoles...), rc.GetAvailability())
+	incomingRoutes.GET("/reservations", middleware.Authorize(models.StaffRoles...), rc.GetReservations())
+	incomingRoutes.GET("/reservations/:reservation_id", middleware.Authorize(models.StaffRoles...), rc.GetReservation())
+	incomingRoutes.POST("/reservations", middleware.Authorize(hosts...), rc.CreateReservation())
+	incomingRoutes.PATCH("/reservations/:reservation_id", middleware.Authorize(hosts...), rc.UpdateReservation())
+	incomingRoutes.POST("/reservations/:reservation_id/cancel", middleware.Authorize(hosts...), rc.CancelReservation())
+	incomingRoutes.POST("/reservations/:reservation_id/seat", middleware.Authorize(hosts...), rc.SeatReservation())
+	incomingRoutes.POST("/reservations/:reservation_id/complete", middleware.Authorize(hosts...), rc.CompleteReservation())
+	incomingRoutes.POST("/reservations/:reservation_id/noShow", middleware.Authorize(hosts...), rc.MarkNoShow())
+}
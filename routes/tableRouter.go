@@ -1,14 +1,19 @@
 package routes
 
-
 import (
 	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
 	"github.com/gin-gonic/gin"
 )
 
-func TableRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/tables", controllers.GetTables())
-	incomingRoutes.GET("/tables/:table_id", controllers.GetTable())
-	incomingRoutes.POST("/tables", controllers.CreateTable())
-	incomingRoutes.PATCH("/tables/:table_id", controllers.UpdateTable())
-}
\ No newline at end of file
+func TableRoutes(incomingRoutes gin.IRoutes, tc *controllers.TableController) {
+	incomingRoutes.GET("/tables", middleware.Authorize(models.StaffRoles...), tc.GetTables())
+	incomingRoutes.GET("/tables/floorPlan", middleware.Authorize(models.StaffRoles...), tc.GetFloorPlan())
+	incomingRoutes.GET("/tables/:table_id", middleware.Authorize(models.StaffRoles...), tc.GetTable())
+	incomingRoutes.POST("/tables", middleware.Authorize(models.RoleManager), tc.CreateTable())
+	incomingRoutes.PATCH("/tables/:table_id", middleware.Authorize(models.RoleManager), tc.UpdateTable())
+	incomingRoutes.POST("/tables/:table_id/status", middleware.Authorize(models.RoleWaiter, models.RoleCashier, models.RoleManager), tc.MoveTable())
+	incomingRoutes.POST("/tables/:table_id/merge", middleware.Authorize(models.RoleWaiter, models.RoleCashier, models.RoleManager), tc.MergeTables())
+	incomingRoutes.POST("/tables/:table_id/split", middleware.Authorize(models.RoleWaiter, models.RoleCashier, models.RoleManager), tc.SplitTables())
+}
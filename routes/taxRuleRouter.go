@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TaxRuleRoutes(incomingRoutes gin.IRoutes, tc *controllers.TaxRuleController) {
+	incomingRoutes.GET("/taxRules", middleware.Authorize(models.StaffRoles...), tc.GetTaxRules())
+	incomingRoutes.GET("/taxRules/:tax_rule_id", middleware.Authorize(models.StaffRoles...), tc.GetTaxRule())
+	incomingRoutes.POST("/taxRules", middleware.Authorize(models.RoleManager), tc.CreateTaxRule())
+	incomingRoutes.PATCH("/taxRules/:tax_rule_id", middleware.Authorize(models.RoleManager), tc.UpdateTaxRule())
+}
@@ -3,11 +3,12 @@ package routes
 import (
 	"github.com/Micah-Shallom/modules/controllers"
 	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
 	"github.com/gin-gonic/gin"
 )
 
-func UserRoutes(incomingRoutes *gin.Engine) {
-	middleware.Authenticate()
-	incomingRoutes.GET("/users/", controllers.GetUsers())
-	incomingRoutes.GET("/users/:userid", controllers.GetUser())
-}
\ No newline at end of file
+func UserRoutes(incomingRoutes gin.IRoutes, uc *controllers.UserController) {
+	incomingRoutes.GET("/users/", middleware.Authorize(models.RoleManager), uc.GetUsers())
+	incomingRoutes.POST("/users/", middleware.Authorize(models.RoleAdmin), uc.CreateUser())
+	incomingRoutes.GET("/users/:userid", middleware.Authorize(models.AllRoles...), uc.GetUser())
+}
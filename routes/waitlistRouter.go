@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/Micah-Shallom/modules/controllers"
+	"github.com/Micah-Shallom/modules/middleware"
+	"github.com/Micah-Shallom/modules/models"
+	"github.com/gin-gonic/gin"
+)
+
+func WaitlistRoutes(incomingRoutes gin.IRoutes, wc *controllers.WaitlistController) {
+	hosts := []string{models.RoleWaiter, models.RoleCashier, models.RoleManager}
+	incomingRoutes.GET("/waitlist", middleware.Authorize(models.StaffRoles...), wc.GetWaitlist())
+	incomingRoutes.GET("/waitlist/estimate", middleware.Authorize(models.StaffRoles...), wc.GetWaitEstimate())
+	incomingRoutes.GET("/waitlist/:entry_id", middleware.Authorize(models.StaffRoles...), wc.GetWaitlistEntry())
+	incomingRoutes.POST("/waitlist", middleware.Authorize(hosts...), wc.AddToWaitlist())
+	incomingRoutes.PATCH("/waitlist/:entry_id", middleware.Authorize(hosts...), wc.UpdateWaitlistEntry())
+	incomingRoutes.POST("/waitlist/:entry_id/notify", middleware.Authorize(hosts...), wc.NotifyWaitlistEntry())
+	incomingRoutes.POST("/waitlist/:entry_id/seat", middleware.Authorize(hosts...), wc.SeatWaitlistEntry())
+	incomingRoutes.POST("/waitlist/:entry_id/cancel", middleware.Authorize(hosts...), wc.CancelWaitlistEntry())
+}